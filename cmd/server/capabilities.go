@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/pdf_extractor/forensics"
+	"net/http"
+)
+
+// Estados del análisis forense en las capacidades
+const (
+	forensicsEnabled  = "enabled"
+	forensicsDegraded = "degraded" // Sin UniPDF no se hacen las comprobaciones de la capa de texto
+	forensicsDisabled = "disabled"
+)
+
+// capabilities describe lo que puede hacer el servidor con la configuración con la que arrancó
+type capabilities struct {
+	Strategies       []string `json:"strategies"`                  // Estrategias de extracción, en el orden en que se prueban
+	UniPDF           bool     `json:"unipdf"`                      // Hay licencia de UniPDF
+	Forensics        string   `json:"forensics"`                   // enabled, degraded o disabled
+	ForensicsSkipped []string `json:"forensics_skipped,omitempty"` // Comprobaciones que no se harán
+}
+
+// newCapabilities describe las capacidades de la configuración aplicada
+func (s *server) newCapabilities(uniPDF bool) capabilities {
+	caps := capabilities{
+		Strategies: s.extractor.Strategies(),
+		UniPDF:     uniPDF,
+		Forensics:  forensicsEnabled,
+	}
+	switch {
+	case !s.forensics.Enabled:
+		caps.Forensics = forensicsDisabled
+	case !uniPDF:
+		caps.Forensics = forensicsDegraded
+		caps.ForensicsSkipped = forensics.TextChecks
+	}
+	return caps
+}
+
+// capabilitiesHandler devuelve las capacidades del servidor, para que los clientes y los
+// despliegues sepan qué estrategias de extracción están disponibles
+func (s *server) capabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.capabilities); err != nil {
+		logger.FromContext(r.Context()).Error("Error al escribir respuesta: %v", err)
+	}
+}
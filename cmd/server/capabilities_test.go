@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"go_ocr/config"
+	"go_ocr/internal/services/pdf_extractor"
+	"go_ocr/internal/services/pdf_extractor/forensics"
+	"testing"
+)
+
+func TestNewCapabilitiesForensics(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		uniPDF     bool
+		status     string
+		skipped    int
+		strategies string
+	}{
+		{"con licencia", true, true, forensicsEnabled, 0, "[pdftotext unipdf ocr]"},
+		{"sin licencia", true, false, forensicsDegraded, len(forensics.TextChecks), "[pdftotext ocr]"},
+		{"desactivado", false, false, forensicsDisabled, 0, "[pdftotext ocr]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &server{
+				extractor: pdf_extractor.New(nil, nil, tt.uniPDF),
+				forensics: config.Forensics{Enabled: tt.enabled},
+			}
+			caps := s.newCapabilities(tt.uniPDF)
+			if caps.Forensics != tt.status || len(caps.ForensicsSkipped) != tt.skipped {
+				t.Errorf("forensics %q, omitidas %v; se esperaba %q con %d omitidas",
+					caps.Forensics, caps.ForensicsSkipped, tt.status, tt.skipped)
+			}
+			if got := fmt.Sprint(caps.Strategies); got != tt.strategies {
+				t.Errorf("estrategias %s, se esperaban %s", got, tt.strategies)
+			}
+		})
+	}
+}
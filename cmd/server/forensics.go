@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"go_ocr/internal/services/ai"
+	"go_ocr/internal/services/cache"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/metrics"
+	"go_ocr/internal/services/pdf_extractor"
+	"go_ocr/internal/services/pdf_extractor/forensics"
+	"time"
+)
+
+// analyzeDocument busca indicios de manipulación en el documento y en los datos extraídos.
+// El análisis del archivo se guarda en caché; los totales dependen de los datos y se
+// comprueban siempre. Devuelve nil si el análisis está desactivado o falla: no bloquea la
+// respuesta.
+func (s *server) analyzeDocument(ctx context.Context, doc *pdf_extractor.Document, docHash string, noCache bool, opts pdf_extractor.Options, data *ai.PayrollData) *forensics.Report {
+	reqLog := logger.FromContext(ctx)
+	if !s.forensics.Enabled {
+		return nil
+	}
+
+	key := cache.ForensicsKey(docHash, opts.Key(), s.forensics.OCRPages)
+	report := s.lookupForensics(ctx, key, noCache)
+	if report != nil {
+		reqLog.Info("Análisis forense obtenido de caché")
+	} else {
+		forensicsCtx, cancel := context.WithTimeout(ctx, s.timeouts.Extract)
+		defer cancel()
+		startTime := time.Now()
+		var err error
+		report, err = forensics.Analyze(forensicsCtx, doc.Path, forensics.Options{
+			Password: doc.Password,
+			Plain:    doc.Plain,
+			Engine:   s.ocr,
+			OCR:      opts.OCR,
+			OCRPages: s.forensics.OCRPages,
+		})
+		if err != nil {
+			err = stageError(forensicsCtx, "análisis forense", err)
+		}
+		metrics.ObserveStage("forensics", startTime, err)
+		if err != nil {
+			reqLog.Warning("Error en el análisis forense: %v", err)
+			return nil
+		}
+		s.storeForensics(ctx, key, report)
+	}
+	report.CheckTotals(data.GrossAmount, data.Deductions, data.NetAmount)
+
+	if report.Level != forensics.LevelLow {
+		reqLog.Warning("Riesgo de manipulación %s (%d): %d indicios",
+			report.Level, report.Score, len(report.Reasons))
+	}
+	return report
+}
+
+// lookupForensics busca el análisis forense del documento en la caché
+func (s *server) lookupForensics(ctx context.Context, key string, noCache bool) *forensics.Report {
+	reqLog := logger.FromContext(ctx)
+	if s.cache == nil || noCache {
+		return nil
+	}
+	raw, ok := s.cache.Get(key)
+	if !ok {
+		return nil
+	}
+	var report forensics.Report
+	if err := json.Unmarshal(raw, &report); err != nil {
+		reqLog.Warning("Entrada de caché forense corrupta: %v", err)
+		return nil
+	}
+	return &report
+}
+
+// storeForensics guarda el análisis forense del documento. Un análisis con comprobaciones
+// omitidas no se guarda: el motivo puede ser transitorio (p. ej. un fallo del OCR).
+func (s *server) storeForensics(ctx context.Context, key string, report *forensics.Report) {
+	reqLog := logger.FromContext(ctx)
+	if s.cache == nil || len(report.Skipped) > 0 {
+		return
+	}
+	raw, err := json.Marshal(report)
+	if err != nil {
+		reqLog.Warning("Error al serializar análisis forense para caché: %v", err)
+		return
+	}
+	if err := s.cache.Set(key, raw); err != nil {
+		reqLog.Warning("Error al guardar análisis forense en caché: %v", err)
+	}
+}
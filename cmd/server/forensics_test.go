@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"go_ocr/config"
+	"go_ocr/internal/services/ai"
+	"go_ocr/internal/services/cache"
+	"go_ocr/internal/services/pdf_extractor"
+	"go_ocr/internal/services/pdf_extractor/forensics"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeDocumentUsesCache(t *testing.T) {
+	s := &server{
+		cache:     cache.NewMemoryStore(10),
+		forensics: config.Forensics{Enabled: true},
+		timeouts:  config.Timeouts{Extract: time.Minute},
+	}
+
+	// Una imagen no tiene estructura PDF que analizar: el informe solo depende de los totales
+	path := filepath.Join(t.TempDir(), "nomina.png")
+	if err := os.WriteFile(path, []byte("\x89PNG\r\n\x1a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Totales que no cuadran: el indicio se añade en cada petición, no se acumula en la caché
+	data := &ai.PayrollData{GrossAmount: 2000, Deductions: 300, NetAmount: 1800}
+	opts := pdf_extractor.DefaultOptions
+	doc := &pdf_extractor.Document{Path: path, Plain: path}
+
+	first := s.analyzeDocument(context.Background(), doc, "hash", false, opts, data)
+	if first == nil || len(first.Reasons) != 1 || first.Reasons[0].Check != forensics.CheckTotals {
+		t.Fatalf("primer análisis inesperado: %+v", first)
+	}
+
+	// Sin el archivo el análisis fallaría: el segundo resultado tiene que venir de la caché
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	second := s.analyzeDocument(context.Background(), doc, "hash", false, opts, data)
+	if second == nil || len(second.Reasons) != 1 || second.Score != first.Score {
+		t.Fatalf("análisis de caché inesperado: %+v", second)
+	}
+
+	if bypass := s.analyzeDocument(context.Background(), doc, "hash", true, opts, data); bypass != nil {
+		t.Errorf("sin caché se esperaba un fallo al no existir el archivo: %+v", bypass)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/metrics"
+	"go_ocr/internal/services/pdf_extractor/doctype"
+	"go_ocr/internal/services/pdf_extractor/downloader"
+	"go_ocr/internal/services/pdf_extractor/inspect"
+	"net/http"
+	"time"
+)
+
+// inspectHandler describe la estructura de un PDF sin OCR ni llamadas al modelo.
+// Acepta los mismos parámetros url, password y dni que /convert.
+func (s *server) inspectHandler(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	reqLog := logger.FromContext(r.Context())
+
+	// La consulta puede llevar url, password y dni: solo se registra la ruta
+	reqLog.Info("New inspect request received - Método: %s - Ruta: %s",
+		r.Method, r.URL.Path)
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeouts.Request)
+	defer cancel()
+
+	if r.Method != http.MethodPost {
+		errMsg := "Method not allowed"
+		reqLog.Warning("%s", errMsg)
+		http.Error(w, errMsg, http.StatusMethodNotAllowed)
+		return
+	}
+
+	url := r.FormValue("url")
+	if url == "" {
+		errMsg := "Se requiere el parámetro 'url'"
+		reqLog.Warning("%s", errMsg)
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	downloadCtx, cancelDownload := context.WithTimeout(ctx, s.timeouts.Download)
+	filePath, err := downloader.DownloadPDF(downloadCtx, url, s.maxDownload)
+	if err != nil {
+		err = stageError(downloadCtx, "descargar documento", err)
+	}
+	cancelDownload()
+	if err != nil {
+		errMsg := fmt.Sprintf("Error al descargar documento: %v", err)
+		reqLog.Error("%s", errMsg)
+		http.Error(w, errMsg, errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	defer downloader.CleanupFile(ctx, filePath)
+
+	docType, err := doctype.Detect(filePath)
+	if err != nil {
+		errMsg := fmt.Sprintf("Error al detectar formato: %v", err)
+		reqLog.Error("%s", errMsg)
+		http.Error(w, errMsg, http.StatusInternalServerError)
+		return
+	}
+	if docType != doctype.PDF {
+		errMsg := fmt.Sprintf("Solo se pueden inspeccionar PDF, el documento es %s", docType)
+		reqLog.Warning("%s", errMsg)
+		http.Error(w, errMsg, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	passwords := s.passwordCandidates(r.FormValue("password"), r.FormValue("dni"))
+	inspectStart := time.Now()
+	report, err := inspect.Inspect(ctx, filePath, passwords)
+	if err != nil {
+		err = stageError(ctx, "inspeccionar documento", err)
+	}
+	metrics.ObserveStage("inspect", inspectStart, err)
+	if err != nil {
+		errMsg := fmt.Sprintf("Error al inspeccionar documento: %v", err)
+		reqLog.Error("%s", errMsg)
+		http.Error(w, errMsg, errorStatus(err, http.StatusUnprocessableEntity))
+		return
+	}
+
+	responseJSON, err := json.Marshal(report)
+	if err != nil {
+		errMsg := fmt.Sprintf("Error al convertir a JSON: %v", err)
+		reqLog.Error("%s", errMsg)
+		http.Error(w, errMsg, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(responseJSON); err != nil {
+		reqLog.Error("Error al escribir respuesta: %v", err)
+	} else {
+		reqLog.With("duration_ms", time.Since(startTime).Milliseconds()).Info("Inspección enviada exitosamente. Tiempo total: %v",
+			time.Since(startTime))
+	}
+}
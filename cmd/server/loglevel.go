@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"go_ocr/config"
+	"go_ocr/internal/services/logger"
+	"net/http"
+)
+
+// sensitiveHeaders son las cabeceras que no se registran nunca
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+// newLogConfig construye la configuración del registro. Sin nivel se usa debug con
+// ENV=development e info en otro caso. El nivel trace, que registra el texto de los
+// documentos y los prompts, solo se puede activar con log.allow_trace y nunca en producción
+// (ENV=production), donde los datos personales se enmascaran siempre; fuera de producción
+// log.redact=false desactiva el enmascarado.
+func newLogConfig(cfg config.Config) logger.Config {
+	production := cfg.App.Env == "production"
+	logConfig := logger.Config{
+		Format:           cfg.Log.Format,
+		Level:            cfg.Log.Level,
+		AllowTrace:       cfg.Log.AllowTrace && !production,
+		DisableRedaction: !cfg.Log.Redact && !production,
+		File: logger.FileConfig{
+			Path:        cfg.Log.File.Path,
+			MaxSize:     cfg.Log.File.MaxSizeMB << 20,
+			RotateEvery: cfg.Log.File.RotateEvery,
+			MaxBackups:  cfg.Log.File.MaxBackups,
+			MaxAge:      cfg.Log.File.MaxAge,
+			Compress:    cfg.Log.File.Compress,
+		},
+	}
+	if logConfig.Level == "" {
+		logConfig.Level = "info"
+		if cfg.App.Env == "development" {
+			logConfig.Level = "debug"
+		}
+	}
+	return logConfig
+}
+
+// redactHeaders devuelve una copia de las cabeceras sin los valores de las sensibles
+func redactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "[REDACTED]")
+		}
+	}
+	return redacted
+}
+
+// logLevelResponse es la respuesta de /log/level
+type logLevelResponse struct {
+	Level string `json:"level"`
+}
+
+// logLevelHandler consulta (GET) o cambia (PUT/POST con el parámetro level) el nivel mínimo
+// del registro sin reiniciar. El cambio exige la cabecera "Authorization: Bearer <token>"
+// con el token de administración; sin token configurado no se puede cambiar.
+func (s *server) logLevelHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		if !s.requireAdmin(w, r, "Cambio de nivel de log") {
+			return
+		}
+		previous := logger.GetLevel()
+		if err := logger.SetLevel(r.FormValue("level")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.With("previous", previous, "level", logger.GetLevel()).
+			Warning("Nivel de log cambiado de %s a %s", previous, logger.GetLevel())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(logLevelResponse{Level: logger.GetLevel()}); err != nil {
+		log.Error("Error al escribir respuesta: %v", err)
+	}
+}
+
+// requireAdmin comprueba el token de administración y, si no es válido, responde 403 si no
+// hay token configurado (los endpoints de administración están desactivados) o 401 si no
+// coincide. action describe la operación en el registro.
+func (s *server) requireAdmin(w http.ResponseWriter, r *http.Request, action string) bool {
+	reqLog := logger.FromContext(r.Context())
+	if s.adminToken == "" {
+		reqLog.Warning("%s rechazado desde %s: no hay token de administración configurado", action, r.RemoteAddr)
+		http.Error(w, "Forbidden: LOG_ADMIN_TOKEN no configurado", http.StatusForbidden)
+		return false
+	}
+	expected := fmt.Sprintf("Bearer %s", s.adminToken)
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) != 1 {
+		reqLog.Warning("%s no autorizado desde %s", action, r.RemoteAddr)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
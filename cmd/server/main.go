@@ -1,120 +1,317 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/joho/godotenv"
 	uniPdfLicense "github.com/unidoc/unipdf/v3/common/license"
+	"go_ocr/config"
 	"go_ocr/internal/services/ai"
+	"go_ocr/internal/services/cache"
 	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/metrics"
 	"go_ocr/internal/services/pdf_extractor"
+	"go_ocr/internal/services/pdf_extractor/archive"
+	"go_ocr/internal/services/pdf_extractor/doctype"
 	"go_ocr/internal/services/pdf_extractor/downloader"
+	"go_ocr/internal/services/pdf_extractor/ocr"
+	"go_ocr/internal/services/pdf_extractor/ocr/preprocess"
+	"go_ocr/internal/services/pdf_extractor/signature"
+	"go_ocr/internal/services/runner"
+	"go_ocr/internal/services/tracing"
+	"io/fs"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 )
 
-var (
-	log = logger.NewLogger(false)
-)
+var log = logger.NewLogger()
+
+// server reúne la configuración y los servicios con los que se atienden las peticiones,
+// construidos al arrancar
+type server struct {
+	timeouts       config.Timeouts       // Plazos de la petición completa y de cada etapa
+	maxDownload    int64                 // Tamaño máximo de un documento descargado, en bytes
+	extractOptions pdf_extractor.Options // Opciones de extracción globales
+	passwords      []string              // Contraseñas que se prueban con todos los PDF cifrados, después de las de la petición
+	extractor      *pdf_extractor.Service
+	ai             *ai.Client
+	ocr            *ocr.Engine
+	trustStore     *signature.TrustStore   // Raíces con las que se validan las firmas
+	forensics      config.Forensics        // Activa el análisis de manipulación y fija cuántas páginas se contrastan con OCR
+	cache          cache.Store             // nil si la caché está desactivada
+	adminToken     string                  // Token que exigen los endpoints de administración; vacío los desactiva
+	memorySpans    *tracing.MemoryExporter // Exportador en memoria que consulta /debug/traces, si se configura
+	capabilities   capabilities
+}
 
 func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("No se encontró archivo .env")
+	// Si el proceso es el ayudante que aplica los límites a un comando externo, no vuelve
+	runner.Init()
+
+	// El archivo .env es opcional: en contenedores las variables llegan del entorno
+	envFile := true
+	if err := godotenv.Load(); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Fatal("Error al leer archivo .env: %v", err)
+		}
+		envFile = false
 	}
 
-	licenseKey := os.Getenv("UNIPDF_LICENSE_KEY")
-	err = uniPdfLicense.SetMeteredKey(licenseKey)
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatal("Error al configurar licencia de UniPDF: %v", err)
+		log.Fatal("Error en la configuración: %v", err)
 	}
 
 	// Configurar logger
+	logConfig := newLogConfig(cfg)
+	if err := logger.Configure(logConfig); err != nil {
+		log.Fatal("Error en la configuración del log: %v", err)
+	}
+	defer logger.Close()
+	if logConfig.File.Path != "" {
+		// logrotate puede mover el archivo y pedir que se reabra con SIGHUP
+		logger.ReopenOnSIGHUP()
+		log.Info("Log en archivo %s: rotación a %d MB o cada %v, %d copias, antigüedad máxima %v, compresión %t",
+			logConfig.File.Path, logConfig.File.MaxSize>>20, logConfig.File.RotateEvery,
+			logConfig.File.MaxBackups, logConfig.File.MaxAge, logConfig.File.Compress)
+	}
+	srv := &server{adminToken: cfg.Log.AdminToken}
 	log.Info("Starting OCR Server")
-	log.Debug("Environment: %s", os.Getenv("ENV"))
+	log.Debug("Environment: %s", cfg.App.Env)
+	if !envFile {
+		log.Info("Sin archivo .env, se usan las variables del entorno")
+	}
+
+	// Sin licencia, UniPDF no extrae texto: se usan solo pdftotext y OCR
+	uniPDF := cfg.UniPDF.LicenseKey != ""
+	if uniPDF {
+		if err := uniPdfLicense.SetMeteredKey(cfg.UniPDF.LicenseKey); err != nil {
+			log.Fatal("Error al configurar licencia de UniPDF: %v", err)
+		}
+	} else {
+		log.Warning("Sin licencia de UniPDF (UNIPDF_LICENSE_KEY): estrategia unipdf desactivada; " +
+			"el análisis forense de fuentes y de la capa de texto y el texto por página de /inspect no estarán disponibles")
+	}
+
+	// Configurar envío de trazas
+	tracingConfig, err := newTracingConfig(cfg.Tracing)
+	if err == nil {
+		err = tracing.Configure(tracingConfig)
+	}
+	if err != nil {
+		log.Fatal("Error en la configuración de trazas: %v", err)
+	}
+	if tracingConfig.Exporter != nil {
+		log.Info("Trazas activadas con exportador %s", cfg.Tracing.Exporter)
+	}
+	srv.memorySpans, _ = tracingConfig.Exporter.(*tracing.MemoryExporter)
+
+	// Configurar API del modelo
+	srv.ai = ai.New(ai.Config{
+		APIKey:      cfg.AI.APIKey,
+		BaseURL:     cfg.AI.BaseURL,
+		Model:       cfg.AI.Model,
+		MaxAttempts: cfg.AI.MaxAttempts,
+		RetryDelay:  cfg.AI.RetryDelay,
+	})
+	log.Info("Extracción de datos con el modelo %s de %s", cfg.AI.Model, cfg.AI.BaseURL)
+
+	// Configurar aislamiento de poppler y tesseract
+	sandboxConfig := newSandboxConfig(cfg.Sandbox)
+	commands, err := runner.New(sandboxConfig)
+	if err != nil {
+		log.Warning("Sandbox de comandos externos incompleto: %v", err)
+	}
+	log.Info("Sandbox de comandos: CPU %v, memoria %d MB, salida %d MB, lanzador %v",
+		sandboxConfig.CPUTime, sandboxConfig.Memory>>20, sandboxConfig.MaxOutput>>20, sandboxConfig.Launcher)
+
+	// Configurar paralelismo y preprocesado del OCR
+	preprocessOptions, err := preprocess.ParseOptions(cfg.OCR.Preprocess)
+	if err != nil {
+		log.Fatal("Error en la configuración del preprocesado de OCR: %v", err)
+	}
+	srv.ocr = ocr.New(commands, ocr.Config{
+		Workers:      cfg.OCR.Workers,
+		MaxProcesses: cfg.OCR.MaxProcesses,
+		Preprocess:   preprocessOptions,
+	})
+	log.Info("OCR configurado con %d páginas en paralelo y %d procesos simultáneos", srv.ocr.Workers(), srv.ocr.MaxProcesses())
+	log.Info("Preprocesado de imagen para OCR: %+v", preprocessOptions)
+
+	srv.extractor = pdf_extractor.New(commands, srv.ocr, uniPDF)
+	srv.extractOptions, err = newExtractOptions(cfg)
+	if err != nil {
+		log.Fatal("Error en la configuración de OCR: %v", err)
+	}
+	srv.passwords = cfg.Extract.Passwords
+
+	log.Info("Opciones de OCR: idiomas %s, %d DPI, PSM %d, OEM %d, detección de idioma %t",
+		srv.extractOptions.OCR.Languages, srv.extractOptions.OCR.DPI, srv.extractOptions.OCR.PSM,
+		srv.extractOptions.OCR.OEM, srv.extractOptions.OCR.AutoDetect)
+
+	srv.timeouts = cfg.Timeouts
+	log.Info("Plazos: petición %v, descarga %v, extracción %v, IA %v",
+		srv.timeouts.Request, srv.timeouts.Download, srv.timeouts.Extract, srv.timeouts.AI)
+
+	srv.maxDownload = cfg.Download.MaxSizeMB << 20
+	log.Info("Tamaño máximo de descarga: %d MB", cfg.Download.MaxSizeMB)
+
+	// Configurar almacén de confianza de firmas digitales
+	srv.trustStore, err = signature.LoadTrustStore(logger.NewContext(context.Background(), log), cfg.Signature.TrustStore)
+	if err != nil {
+		log.Fatal("Error al cargar almacén de confianza: %v", err)
+	}
+
+	// Configurar análisis de manipulación
+	srv.forensics = cfg.Forensics
+	log.Info("Análisis forense: activado %t, páginas contrastadas con OCR %d", srv.forensics.Enabled, srv.forensics.OCRPages)
+
+	// Configurar caché de resultados
+	srv.cache, err = newCacheStore(cfg.Cache)
+	if err != nil {
+		log.Fatal("Error al configurar caché: %v", err)
+	}
+
+	srv.capabilities = srv.newCapabilities(uniPDF)
+	log.Info("Capacidades: estrategias %v, UniPDF %t, análisis forense %s",
+		srv.capabilities.Strategies, srv.capabilities.UniPDF, srv.capabilities.Forensics)
 
 	// Configurar handler
-	http.HandleFunc("/convert", convertHandler)
+	http.HandleFunc("/convert", withRequestID(instrument("convert", srv.convertHandler)))
+	http.HandleFunc("/inspect", withRequestID(instrument("inspect", srv.inspectHandler)))
+	http.HandleFunc("/log/level", withRequestID(srv.logLevelHandler))
+	http.HandleFunc("/capabilities", withRequestID(srv.capabilitiesHandler))
+	http.Handle("/metrics", metrics.Handler())
+	if srv.memorySpans != nil {
+		http.HandleFunc("/debug/traces", srv.tracesHandler)
+	}
 
 	// Configurar servidor
-	port := ":" + os.Getenv("APP_PORT")
-	server := &http.Server{
-		Addr:         port,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+	port := ":" + strconv.Itoa(cfg.App.Port)
+	httpServer := &http.Server{
+		Addr:        port,
+		ReadTimeout: cfg.App.ReadTimeout,
+		// La respuesta se escribe al terminar el procesamiento, acotado por el plazo de la petición
+		WriteTimeout: srv.timeouts.Request + 5*time.Second,
 	}
 
 	log.Info("Servidor escuchando en http://localhost%s", port)
-	log.Fatal(server.ListenAndServe().Error())
+	log.Fatal(httpServer.ListenAndServe().Error())
 }
 
-func convertHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) convertHandler(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
-	requestID := time.Now().UnixNano()
+	reqLog := logger.FromContext(r.Context())
+
+	// La consulta puede llevar url, password y dni: solo se registra la ruta
+	reqLog.Info("New request received - Método: %s - Ruta: %s",
+		r.Method, r.URL.Path)
+	reqLog.Debug("Headers: %v", redactHeaders(r.Header))
 
-	log.Info("[Request:%d] New request received - Método: %s - URL: %s",
-		requestID, r.Method, r.URL.String())
-	log.Debug("[Request:%d] Headers: %v", requestID, r.Header)
+	// El contexto se cancela si el cliente se desconecta o vence el plazo de la petición
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeouts.Request)
+	defer cancel()
 
 	// Validar método HTTP
 	if r.Method != http.MethodPost {
 		errMsg := "Method not allowed"
-		log.Warning("[Request:%d] %s", requestID, errMsg)
+		reqLog.Warning("%s", errMsg)
 		http.Error(w, errMsg, http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Obtener URL del PDF
+	// Obtener URL del documento (PDF o imagen)
 	url := r.FormValue("url")
 	if url == "" {
 		errMsg := "Se requiere el parámetro 'url'"
-		log.Warning("[Request:%d] %s", requestID, errMsg)
+		reqLog.Warning("%s", errMsg)
 		http.Error(w, errMsg, http.StatusBadRequest)
 		return
 	}
 
-	log.Info("[Request:%d] Procesando PDF desde URL: %s", requestID, url)
+	noCache, _ := strconv.ParseBool(r.FormValue("no_cache"))
 
-	// Descargar el PDF
-	filePath, err := downloader.DownloadPDF(url)
+	opts, err := s.requestExtractOptions(r)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error al descargar PDF: %v", err)
-		log.Error("[Request:%d] %s", requestID, errMsg)
-		http.Error(w, errMsg, http.StatusInternalServerError)
+		errMsg := fmt.Sprintf("Opciones de OCR inválidas: %v", err)
+		reqLog.Warning("%s", errMsg)
+		http.Error(w, errMsg, http.StatusBadRequest)
 		return
 	}
-	log.Info("[Request:%d] PDF descargado en: %s", requestID, filePath)
-	defer func() {
-		downloader.CleanupFile(filePath)
-	}()
 
-	// Extraer texto
-	text, err := pdf_extractor.ExtractTextFromPDF(filePath)
+	reqLog.Info("Procesando documento desde %s", logger.RedactURL(url))
+
+	// Descargar el documento
+	downloadCtx, cancelDownload := context.WithTimeout(ctx, s.timeouts.Download)
+	filePath, err := downloader.DownloadPDF(downloadCtx, url, s.maxDownload)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error al extraer texto: %v", err)
-		log.Error("[Request:%d] %s", requestID, errMsg)
-		http.Error(w, errMsg, http.StatusInternalServerError)
+		err = stageError(downloadCtx, "descargar documento", err)
+	}
+	cancelDownload()
+	if err != nil {
+		errMsg := fmt.Sprintf("Error al descargar documento: %v", err)
+		reqLog.Error("%s", errMsg)
+		http.Error(w, errMsg, errorStatus(err, http.StatusInternalServerError))
 		return
 	}
+	reqLog.Info("Documento descargado en: %s", filePath)
+	defer func() {
+		downloader.CleanupFile(ctx, filePath)
+	}()
 
-	// Extraer datos estructurados
-	payrollData, err := ai.ExtractPayrollData(text)
+	docType, err := doctype.Detect(filePath)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error al extraer datos: %v", err)
-		log.Error("[Request:%d] %s", requestID, errMsg)
+		errMsg := fmt.Sprintf("Error al detectar formato: %v", err)
+		reqLog.Error("%s", errMsg)
 		http.Error(w, errMsg, http.StatusInternalServerError)
 		return
 	}
 
-	log.Info("[Request:%d] Datos extraídos exitosamente", requestID)
-	log.Debug("[Request:%d] Datos extraídos: %+v", requestID, payrollData)
+	var response interface{}
+	if docType.IsArchive() {
+		// Procesar cada documento contenido en el ZIP/EML
+		results, err := s.processArchive(ctx, filePath, noCache, opts)
+		if err != nil {
+			errMsg := fmt.Sprintf("Error al desempaquetar archivo: %v", err)
+			reqLog.Error("%s", errMsg)
+			status := http.StatusInternalServerError
+			if errors.Is(err, archive.ErrLimitExceeded) {
+				status = http.StatusUnprocessableEntity
+			}
+			http.Error(w, errMsg, errorStatus(err, status))
+			return
+		}
+		response = archiveResponse{Results: results}
+	} else {
+		result, err := s.processDocument(ctx, filePath, noCache, opts)
+		if err != nil {
+			errMsg := fmt.Sprintf("Error al procesar documento: %v", err)
+			if errors.Is(err, pdf_extractor.ErrPasswordRequired) {
+				errMsg = "Se requiere contraseña (password required): el PDF está cifrado y no se pudo abrir con 'password' ni 'dni'"
+			}
+			reqLog.Error("%s", errMsg)
+			http.Error(w, errMsg, errorStatus(err, http.StatusInternalServerError))
+			return
+		}
+
+		reqLog.Info("Datos extraídos exitosamente")
+		reqLog.Trace("Datos extraídos: %+v", result.PayrollData)
+		response = result
+	}
 
 	// Convertir a JSON
-	responseJSON, err := json.Marshal(payrollData)
+	responseJSON, err := json.Marshal(response)
 	if err != nil {
 		errMsg := fmt.Sprintf("Error al convertir a JSON: %v", err)
-		log.Error("[Request:%d] %s", requestID, errMsg)
+		reqLog.Error("%s", errMsg)
 		http.Error(w, errMsg, http.StatusInternalServerError)
 		return
 	}
@@ -123,9 +320,9 @@ func convertHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write(responseJSON); err != nil {
-		log.Error("[Request:%d] Error al escribir respuesta: %v", requestID, err)
+		reqLog.Error("Error al escribir respuesta: %v", err)
 	} else {
-		log.Info("[Request:%d] Respuesta enviada exitosamente. Tiempo total: %v",
-			requestID, time.Since(startTime))
+		reqLog.With("duration_ms", time.Since(startTime).Milliseconds()).Info("Respuesta enviada exitosamente. Tiempo total: %v",
+			time.Since(startTime))
 	}
 }
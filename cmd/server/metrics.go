@@ -0,0 +1,45 @@
+package main
+
+import (
+	"go_ocr/internal/services/metrics"
+	"net/http"
+	"time"
+)
+
+var (
+	httpRequests = metrics.NewCounter("go_ocr_http_requests_total",
+		"Peticiones atendidas por handler y resultado.", "handler", "outcome")
+	httpDuration = metrics.NewHistogram("go_ocr_http_request_duration_seconds",
+		"Duración de las peticiones por handler y resultado.", metrics.DurationBuckets, "handler", "outcome")
+	httpInFlight = metrics.NewGauge("go_ocr_http_requests_in_flight",
+		"Peticiones en curso por handler.", "handler")
+)
+
+// instrument mide las peticiones del handler: en curso, total y duración por resultado
+func instrument(handler string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		httpInFlight.Inc(handler)
+		defer httpInFlight.Dec(handler)
+
+		startTime := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+
+		outcome := statusOutcome(recorder.status)
+		httpRequests.Inc(handler, outcome)
+		httpDuration.Observe(time.Since(startTime).Seconds(), handler, outcome)
+	}
+}
+
+// statusOutcome clasifica el código de estado: success, client_error, timeout (504) o server_error
+func statusOutcome(status int) string {
+	switch {
+	case status < http.StatusBadRequest:
+		return "success"
+	case status == http.StatusGatewayTimeout:
+		return "timeout"
+	case status < http.StatusInternalServerError:
+		return "client_error"
+	}
+	return "server_error"
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"go_ocr/config"
+	"go_ocr/internal/services/pdf_extractor"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// newExtractOptions construye las opciones de extracción globales
+func newExtractOptions(cfg config.Config) (pdf_extractor.Options, error) {
+	opts := pdf_extractor.DefaultOptions
+	opts.Layout = cfg.Extract.Layout
+	opts.OCR.Languages = cfg.OCR.Languages
+	opts.OCR.DPI = cfg.OCR.DPI
+	opts.OCR.PSM = cfg.OCR.PSM
+	opts.OCR.OEM = cfg.OCR.OEM
+	opts.OCR.TessdataDir = cfg.OCR.TessdataDir
+	opts.OCR.Whitelist = cfg.OCR.Whitelist
+	opts.OCR.AutoDetect = cfg.OCR.AutoLanguage
+	opts.OCR.Candidates = cfg.OCR.DetectLanguages
+	opts.OCR.MaxPixels = cfg.OCR.MaxPixels
+
+	return opts, opts.Validate()
+}
+
+// requestExtractOptions combina las opciones globales con las indicadas en la petición
+// (layout, ocr_lang, ocr_dpi, ocr_psm, ocr_oem, ocr_whitelist, ocr_auto_lang).
+// El directorio de modelos y el máximo de píxeles solo se configuran globalmente.
+func (s *server) requestExtractOptions(r *http.Request) (pdf_extractor.Options, error) {
+	opts := s.extractOptions
+	values := map[string]string{
+		"languages": r.FormValue("ocr_lang"),
+		"dpi":       r.FormValue("ocr_dpi"),
+		"psm":       r.FormValue("ocr_psm"),
+		"oem":       r.FormValue("ocr_oem"),
+		"whitelist": r.FormValue("ocr_whitelist"),
+		"auto":      r.FormValue("ocr_auto_lang"),
+	}
+	if err := applyOCROptions(&opts, values); err != nil {
+		return opts, err
+	}
+	if err := applyLayout(&opts, r.FormValue("layout")); err != nil {
+		return opts, err
+	}
+	opts.Passwords = s.passwordCandidates(r.FormValue("password"), r.FormValue("dni"))
+
+	return opts, opts.Validate()
+}
+
+// passwordCandidates construye las contraseñas a probar con un PDF cifrado: la indicada en
+// la petición, las variantes habituales del DNI/NIE del empleado (muchos proveedores lo usan
+// como contraseña, con o sin letra) y las configuradas para todos los documentos
+func (s *server) passwordCandidates(password, dni string) []string {
+	var candidates []string
+	seen := make(map[string]bool)
+	add := func(candidate string) {
+		if candidate != "" && !seen[candidate] {
+			seen[candidate] = true
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	add(password)
+	if dni = strings.TrimSpace(dni); dni != "" {
+		add(dni)
+		add(strings.ToUpper(dni))
+		add(strings.ToLower(dni))
+		add(strings.TrimRightFunc(strings.ToUpper(dni), unicode.IsLetter))
+	}
+	for _, candidate := range s.passwords {
+		add(candidate)
+	}
+	return candidates
+}
+
+// applyLayout activa o desactiva la extracción por columnas si value no está vacío
+func applyLayout(opts *pdf_extractor.Options, value string) error {
+	if value == "" {
+		return nil
+	}
+	layout, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("valor de layout inválido: %q", value)
+	}
+	opts.Layout = layout
+	return nil
+}
+
+// applyOCROptions sobrescribe en opts las opciones de OCR con valor no vacío
+func applyOCROptions(opts *pdf_extractor.Options, values map[string]string) error {
+	if v := values["languages"]; v != "" {
+		opts.OCR.Languages = v
+		// Unos idiomas explícitos desactivan la detección salvo que se pida de nuevo
+		opts.OCR.AutoDetect = false
+	}
+
+	ints := []struct {
+		name   string
+		target *int
+	}{
+		{"dpi", &opts.OCR.DPI},
+		{"psm", &opts.OCR.PSM},
+		{"oem", &opts.OCR.OEM},
+	}
+	for _, field := range ints {
+		v := values[field.name]
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("valor de %s inválido: %q", field.name, v)
+		}
+		*field.target = n
+	}
+
+	if v := values["whitelist"]; v != "" {
+		opts.OCR.Whitelist = v
+	}
+	if v := values["auto"]; v != "" {
+		auto, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("valor de detección de idioma inválido: %q", v)
+		}
+		opts.OCR.AutoDetect = auto
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"go_ocr/config"
+	"go_ocr/internal/services/pdf_extractor"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRequestExtractOptions(t *testing.T) {
+	global := pdf_extractor.DefaultOptions
+	global.OCR.AutoDetect = true
+	s := &server{extractOptions: global}
+
+	tests := []struct {
+		name   string
+		values url.Values
+		valid  bool
+		check  func(pdf_extractor.Options) bool
+	}{
+		{"sin opciones", url.Values{}, true, func(o pdf_extractor.Options) bool {
+			return o.OCR.Languages == global.OCR.Languages && o.OCR.AutoDetect
+		}},
+		{"opciones válidas", url.Values{"ocr_dpi": {"200"}, "ocr_psm": {"6"}, "ocr_oem": {"1"}, "layout": {"false"}}, true,
+			func(o pdf_extractor.Options) bool {
+				return o.OCR.DPI == 200 && o.OCR.PSM == 6 && o.OCR.OEM == 1 && !o.Layout
+			}},
+		{"idiomas explícitos sin detección", url.Values{"ocr_lang": {"cat+eng"}}, true,
+			func(o pdf_extractor.Options) bool { return o.OCR.Languages == "cat+eng" && !o.OCR.AutoDetect }},
+		{"idiomas explícitos con detección", url.Values{"ocr_lang": {"cat"}, "ocr_auto_lang": {"true"}}, true,
+			func(o pdf_extractor.Options) bool { return o.OCR.Languages == "cat" && o.OCR.AutoDetect }},
+
+		{"DPI fuera de rango", url.Values{"ocr_dpi": {"1200"}}, false, nil},
+		{"DPI negativo", url.Values{"ocr_dpi": {"-300"}}, false, nil},
+		{"DPI no numérico", url.Values{"ocr_dpi": {"300dpi"}}, false, nil},
+		{"PSM fuera de rango", url.Values{"ocr_psm": {"14"}}, false, nil},
+		{"OEM fuera de rango", url.Values{"ocr_oem": {"4"}}, false, nil},
+		{"idioma con opciones", url.Values{"ocr_lang": {"spa --tessdata-dir /tmp"}}, false, nil},
+		{"idioma con -c", url.Values{"ocr_lang": {"spa -c tessedit_write_images=1"}}, false, nil},
+		{"idioma con ruta", url.Values{"ocr_lang": {"../../etc/spa"}}, false, nil},
+		{"layout inválido", url.Values{"layout": {"quizá"}}, false, nil},
+		{"detección inválida", url.Values{"ocr_auto_lang": {"sí"}}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/convert?"+tt.values.Encode(), nil)
+			opts, err := s.requestExtractOptions(r)
+			if (err == nil) != tt.valid {
+				t.Fatalf("error %v, se esperaba válido: %t", err, tt.valid)
+			}
+			if tt.check != nil && !tt.check(opts) {
+				t.Errorf("opciones inesperadas: %v", opts)
+			}
+		})
+	}
+
+	// Las opciones de una petición no modifican las globales
+	if s.extractOptions.OCR.Languages != global.OCR.Languages || s.extractOptions.OCR.DPI != global.OCR.DPI {
+		t.Errorf("las opciones globales cambiaron: %v", s.extractOptions)
+	}
+}
+
+func TestNewExtractOptionsRejectsInvalidConfig(t *testing.T) {
+	cfg := config.Default()
+	cfg.OCR.Languages = "spa -l eng"
+	if _, err := newExtractOptions(cfg); err == nil {
+		t.Error("se esperaba un error con idiomas que añaden argumentos")
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_ocr/config"
+	"go_ocr/internal/services/ai"
+	"go_ocr/internal/services/cache"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/metrics"
+	"go_ocr/internal/services/pdf_extractor"
+	"go_ocr/internal/services/pdf_extractor/archive"
+	"go_ocr/internal/services/pdf_extractor/downloader"
+	"go_ocr/internal/services/pdf_extractor/forensics"
+	"go_ocr/internal/services/pdf_extractor/signature"
+	"net/http"
+	"os"
+	"time"
+)
+
+// documentResult son los datos extraídos de un documento junto con el estado de la caché,
+// la estrategia de extracción usada, la verificación de sus firmas si está firmado y el
+// riesgo de manipulación
+type documentResult struct {
+	*ai.PayrollData
+	Cache      string                `json:"cache"`
+	Extraction *pdf_extractor.Result `json:"extraction,omitempty"`
+	Signatures *signature.Report     `json:"signatures,omitempty"`
+	Forensics  *forensics.Report     `json:"forensics,omitempty"`
+}
+
+// archiveResponse agrupa los resultados de cada documento de un ZIP/EML
+type archiveResponse struct {
+	Results []attachmentResult `json:"results"`
+}
+
+// attachmentResult es el resultado de un documento contenido en un archivo
+type attachmentResult struct {
+	Filename string          `json:"filename"`
+	Data     *documentResult `json:"data,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// cachedExtraction es la entrada de caché del texto extraído de un documento
+type cachedExtraction struct {
+	Text string `json:"text"`
+	*pdf_extractor.Result
+}
+
+// processDocument extrae los datos de un PDF o imagen, usando la caché si está disponible
+func (s *server) processDocument(ctx context.Context, filePath string, noCache bool, opts pdf_extractor.Options) (*documentResult, error) {
+	reqLog := logger.FromContext(ctx)
+	// Calcular hash del documento para la caché
+	docHash, err := cache.HashFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error al calcular hash del documento: %v", err)
+	}
+	reqLog.Debug("Hash del documento: %s", docHash)
+
+	// Un PDF cifrado solo se sirve de la caché a quien aporta una contraseña válida. Se
+	// descifra una sola vez y la extracción lee la copia descifrada.
+	doc, err := s.extractor.Open(ctx, filePath, opts.Passwords)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir documento: %w", err)
+	}
+	defer func() {
+		if err := doc.Close(); err != nil {
+			reqLog.Warning("%v", err)
+		}
+	}()
+
+	result, err := s.extractDocument(ctx, doc.Plain, docHash, noCache, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Las firmas dependen del almacén de confianza y de la fecha, no se guardan en caché
+	result.Signatures = s.verifySignatures(ctx, doc.Path, doc.Password)
+	result.Forensics = s.analyzeDocument(ctx, doc, docHash, noCache, opts, result.PayrollData)
+	return result, nil
+}
+
+// extractDocument obtiene el texto y los datos estructurados del documento, de la caché o
+// extrayéndolos
+func (s *server) extractDocument(ctx context.Context, filePath, docHash string, noCache bool, opts pdf_extractor.Options) (*documentResult, error) {
+	reqLog := logger.FromContext(ctx)
+	variant := opts.Key()
+	payrollData, extraction := s.lookupCache(ctx, docHash, variant, noCache)
+	if payrollData != nil {
+		reqLog.Info("Resultado obtenido de caché")
+		return &documentResult{PayrollData: payrollData, Cache: "hit", Extraction: extraction}, nil
+	}
+
+	// Extraer texto
+	var err error
+	if extraction == nil {
+		extractCtx, cancel := context.WithTimeout(ctx, s.timeouts.Extract)
+		extraction, err = s.extractor.ExtractTextFromPDF(extractCtx, filePath, opts)
+		if err != nil {
+			err = stageError(extractCtx, "extraer texto", err)
+		}
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		reqLog.Info("Texto obtenido de caché")
+	}
+	reqLog.With("stage", "extract", "strategy", extraction.Strategy, "pages", extraction.Pages).
+		Info("Texto extraído con %s (puntuación %.2f)", extraction.Strategy, extraction.Score)
+	if extraction.NeedsReview {
+		reqLog.Warning("Confianza de OCR baja (%.1f), el documento requiere revisión manual",
+			extraction.OCR.Confidence)
+	}
+
+	// Extraer datos estructurados
+	aiStart := time.Now()
+	aiCtx, cancel := context.WithTimeout(ctx, s.timeouts.AI)
+	defer cancel()
+	payrollData, err = s.ai.ExtractPayrollData(aiCtx, extraction.Text)
+	if err != nil {
+		err = stageError(aiCtx, "extraer datos", err)
+	}
+	metrics.ObserveStage("ai", aiStart, err)
+	if err != nil {
+		return nil, err
+	}
+	reqLog.With("stage", "ai", "duration_ms", time.Since(aiStart).Milliseconds()).Info("Datos estructurados extraídos")
+
+	s.storeCache(ctx, docHash, variant, extraction, payrollData)
+
+	result := &documentResult{PayrollData: payrollData, Cache: "miss", Extraction: extraction}
+	if noCache {
+		result.Cache = "bypass"
+	}
+	return result, nil
+}
+
+// processArchive desempaqueta un ZIP/EML y procesa cada documento por separado.
+// El fallo de un documento no impide procesar el resto.
+func (s *server) processArchive(ctx context.Context, archivePath string, noCache bool, opts pdf_extractor.Options) ([]attachmentResult, error) {
+	reqLog := logger.FromContext(ctx)
+	tempDir, err := os.MkdirTemp("", "archive_")
+	if err != nil {
+		return nil, fmt.Errorf("error al crear directorio temporal: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	entries, err := archive.Unpack(ctx, archivePath, tempDir, archive.DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
+	reqLog.Info("Archivo con %d documentos", len(entries))
+
+	results := make([]attachmentResult, 0, len(entries))
+	for _, entry := range entries {
+		// Si la petición se cancela o vence no tiene sentido seguir con el resto
+		if err := ctx.Err(); err != nil {
+			return nil, stageError(ctx, "procesar adjuntos", err)
+		}
+		reqLog.Info("Procesando adjunto: %s", entry.Name)
+
+		// Las líneas registradas al procesar el adjunto llevan su nombre
+		entryCtx := logger.NewContext(ctx, reqLog.With("attachment", entry.Name))
+		result := attachmentResult{Filename: entry.Name}
+		data, err := s.processDocument(entryCtx, entry.Path, noCache, opts)
+		if err != nil {
+			reqLog.Error("Error en adjunto %s: %v", entry.Name, err)
+			result.Error = err.Error()
+		} else {
+			result.Data = data
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// errorStatus devuelve el código HTTP de un error de procesamiento: 504 si venció un
+// plazo, 422 si el PDF necesita una contraseña válida o el documento supera el tamaño
+// máximo de descarga y status en otro caso
+func errorStatus(err error, status int) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, pdf_extractor.ErrPasswordRequired), errors.Is(err, downloader.ErrTooLarge):
+		return http.StatusUnprocessableEntity
+	}
+	return status
+}
+
+// newCacheStore crea la caché configurada: memory, disk o none
+func newCacheStore(cfg config.Cache) (cache.Store, error) {
+	switch cfg.Driver {
+	case "none":
+		log.Info("Caché de resultados desactivada")
+		return nil, nil
+	case "disk":
+		log.Info("Usando caché en disco: %s", cfg.Dir)
+		return cache.NewDiskStore(cfg.Dir)
+	default:
+		log.Info("Usando caché en memoria con %d entradas", cfg.Size)
+		return cache.NewMemoryStore(cfg.Size), nil
+	}
+}
+
+// lookupCache busca los datos y el texto ya extraído del documento.
+// Los datos solo se devuelven junto con la extracción de la que proceden.
+func (s *server) lookupCache(ctx context.Context, docHash, variant string, noCache bool) (*ai.PayrollData, *pdf_extractor.Result) {
+	reqLog := logger.FromContext(ctx)
+	if s.cache == nil || noCache {
+		return nil, nil
+	}
+
+	raw, ok := s.cache.Get(cache.TextKey(docHash, variant))
+	if !ok {
+		return nil, nil
+	}
+	var cached cachedExtraction
+	if err := json.Unmarshal(raw, &cached); err != nil || cached.Result == nil {
+		reqLog.Warning("Entrada de caché de texto corrupta para %s", docHash)
+		return nil, nil
+	}
+	extraction := cached.Result
+	extraction.Text = cached.Text
+
+	if raw, ok := s.cache.Get(cache.DataKey(docHash, variant, ai.PromptVersion, s.ai.Model())); ok {
+		var data ai.PayrollData
+		if err := json.Unmarshal(raw, &data); err == nil {
+			return &data, extraction
+		}
+		reqLog.Warning("Entrada de caché de datos corrupta para %s", docHash)
+	}
+
+	return nil, extraction
+}
+
+// storeCache guarda el texto y los datos extraídos del documento
+func (s *server) storeCache(ctx context.Context, docHash, variant string, extraction *pdf_extractor.Result, data *ai.PayrollData) {
+	reqLog := logger.FromContext(ctx)
+	if s.cache == nil {
+		return
+	}
+
+	raw, err := json.Marshal(cachedExtraction{Text: extraction.Text, Result: extraction})
+	if err != nil {
+		reqLog.Warning("Error al serializar texto para caché: %v", err)
+		return
+	}
+	if err := s.cache.Set(cache.TextKey(docHash, variant), raw); err != nil {
+		reqLog.Warning("Error al guardar texto en caché: %v", err)
+	}
+
+	raw, err = json.Marshal(data)
+	if err != nil {
+		reqLog.Warning("Error al serializar datos para caché: %v", err)
+		return
+	}
+	if err := s.cache.Set(cache.DataKey(docHash, variant, ai.PromptVersion, s.ai.Model()), raw); err != nil {
+		reqLog.Warning("Error al guardar datos en caché: %v", err)
+	}
+}
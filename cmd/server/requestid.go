@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/tracing"
+	"net/http"
+	"regexp"
+)
+
+// requestIDHeader es la cabecera con la que el cliente o un proxy identifica la petición
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID acota los identificadores aceptados de la cabecera: se copian al registro
+// y a la respuesta, así que no pueden llevar espacios ni saltos de línea
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// withRequestID asigna a la petición el identificador de X-Request-ID, o uno nuevo si no
+// viene o no es válido, y lo devuelve en la respuesta. Abre el span raíz de la petición,
+// que continúa la traza de la cabecera traceparent si la trae, y deja en el contexto un
+// Logger que añade el identificador de la petición y el de la traza a cada línea.
+func withRequestID(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		ctx := tracing.ContextWithTraceparent(r.Context(), r.Header.Get("Traceparent"))
+		ctx, span := tracing.Start(ctx, r.Method+" "+r.URL.Path,
+			tracing.String("http.method", r.Method), tracing.String("http.route", r.URL.Path),
+			tracing.String("request_id", id))
+		span.SetKind(tracing.KindServer)
+		defer span.End()
+
+		ctx = logger.NewContext(ctx, log.With("request_id", id, "trace_id", span.TraceID().String()))
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(tracing.Int("http.status_code", recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.RecordError(httpError(recorder.status))
+		}
+	}
+}
+
+// statusRecorder guarda el código de estado escrito por el handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader guarda el código antes de escribirlo
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// httpError es el error con el que se marca el span de una respuesta 5xx
+type httpError int
+
+func (e httpError) Error() string {
+	return http.StatusText(int(e))
+}
+
+// newRequestID genera un identificador aleatorio de 128 bits en hexadecimal
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		log.Warning("Error al generar identificador de petición: %v", err)
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/tracing"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+// useTracing envía los spans a un exportador en memoria y el registro, en JSON, a un buffer
+// durante el test
+func useTracing(t *testing.T) (*tracing.MemoryExporter, *bytes.Buffer) {
+	t.Helper()
+	exporter := tracing.NewMemoryExporter(100)
+	if err := tracing.Configure(tracing.Config{Exporter: exporter}); err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	if err := logger.Configure(logger.Config{Format: logger.FormatJSON, Output: &output}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		tracing.Configure(tracing.Config{})
+		logger.Configure(logger.Config{})
+	})
+	return exporter, &output
+}
+
+// attribute devuelve el valor del atributo key de span, o nil
+func attribute(span tracing.SpanData, key string) interface{} {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return nil
+}
+
+func TestWithRequestIDContinuesTrace(t *testing.T) {
+	exporter, output := useTracing(t)
+	const (
+		traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+		remoteSpanID = "00f067aa0ba902b7"
+	)
+
+	// El handler abre un span hijo, registra una línea y falla con 500
+	handler := withRequestID(func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "extract", tracing.String("strategy", "pdftotext"))
+		span.End()
+		logger.FromContext(r.Context()).Info("Procesando documento")
+		http.Error(w, "fallo", http.StatusInternalServerError)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/convert", nil)
+	req.Header.Set(requestIDHeader, "peticion-42")
+	req.Header.Set("Traceparent", "00-"+traceID+"-"+remoteSpanID+"-01")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if got := rec.Header().Get(requestIDHeader); got != "peticion-42" {
+		t.Errorf("X-Request-ID %q, se esperaba peticion-42", got)
+	}
+	if err := tracing.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := tracing.ParseTraceID(traceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans := exporter.Trace(id)
+	if len(spans) != 2 {
+		t.Fatalf("%d spans en la traza, se esperaban 2: %+v", len(spans), spans)
+	}
+	// El hijo termina antes que la raíz
+	child, root := spans[0], spans[1]
+
+	if root.Name != "POST /convert" || root.Kind != tracing.KindServer || root.ParentID.String() != remoteSpanID {
+		t.Errorf("span raíz %q de tipo %d con padre %s, se esperaba POST /convert de servidor con padre %s",
+			root.Name, root.Kind, root.ParentID, remoteSpanID)
+	}
+	attrs := map[string]interface{}{
+		"http.method":      "POST",
+		"http.route":       "/convert",
+		"request_id":       "peticion-42",
+		"http.status_code": int64(http.StatusInternalServerError),
+	}
+	for key, want := range attrs {
+		if got := attribute(root, key); got != want {
+			t.Errorf("atributo %s = %v, se esperaba %v", key, got, want)
+		}
+	}
+	if root.Status != tracing.StatusError {
+		t.Errorf("estado del span raíz %d, se esperaba error por la respuesta 500", root.Status)
+	}
+
+	if child.Name != "extract" || child.ParentID != root.SpanID || attribute(child, "strategy") != "pdftotext" {
+		t.Errorf("span hijo inesperado: %+v (raíz %s)", child, root.SpanID)
+	}
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &line); err != nil {
+		t.Fatalf("línea de log %q: %v", output, err)
+	}
+	if line["request_id"] != "peticion-42" || line["trace_id"] != traceID {
+		t.Errorf("campos de log request_id %v y trace_id %v, se esperaban peticion-42 y %s",
+			line["request_id"], line["trace_id"], traceID)
+	}
+}
+
+func TestWithRequestIDStartsNewTrace(t *testing.T) {
+	exporter, output := useTracing(t)
+
+	handler := withRequestID(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).Info("Consulta de capacidades")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/capabilities", nil)
+	// Un identificador con espacios no se copia al registro: se genera otro
+	req.Header.Set(requestIDHeader, "no válido\nsegunda línea")
+	req.Header.Set("Traceparent", "00-cabecera-mal-formada-01")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	id := rec.Header().Get(requestIDHeader)
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(id) {
+		t.Errorf("X-Request-ID %q, se esperaba uno nuevo de 32 caracteres hexadecimales", id)
+	}
+	if err := tracing.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("%d spans, se esperaba 1", len(spans))
+	}
+	root := spans[0]
+	if !root.TraceID.IsValid() || root.ParentID.IsValid() {
+		t.Errorf("span raíz con traza %s y padre %s, se esperaba una traza nueva sin padre", root.TraceID, root.ParentID)
+	}
+	if attribute(root, "request_id") != id || attribute(root, "http.status_code") != int64(http.StatusOK) {
+		t.Errorf("atributos inesperados: %+v", root.Attributes)
+	}
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &line); err != nil {
+		t.Fatalf("línea de log %q: %v", output, err)
+	}
+	if line["request_id"] != id || line["trace_id"] != root.TraceID.String() {
+		t.Errorf("campos de log request_id %v y trace_id %v, se esperaban %s y %s",
+			line["request_id"], line["trace_id"], id, root.TraceID)
+	}
+}
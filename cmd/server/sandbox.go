@@ -0,0 +1,21 @@
+package main
+
+import (
+	"go_ocr/config"
+	"go_ocr/internal/services/runner"
+	"strings"
+)
+
+// newSandboxConfig construye el aislamiento de los comandos externos. El lanzador es un
+// comando con sus opciones separados por espacios, p. ej.
+// "bwrap --ro-bind / / --dev /dev --bind /tmp /tmp --unshare-all".
+func newSandboxConfig(cfg config.Sandbox) runner.Config {
+	sandboxConfig := runner.DefaultConfig
+	sandboxConfig.CPUTime = cfg.CPUTime
+	sandboxConfig.Memory = cfg.MemoryMB << 20
+	sandboxConfig.MaxOutput = cfg.MaxOutputMB << 20
+	sandboxConfig.Env = cfg.Env
+	sandboxConfig.TempDir = cfg.TempDir
+	sandboxConfig.Launcher = strings.Fields(cfg.Launcher)
+	return sandboxConfig
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/metrics"
+	"go_ocr/internal/services/pdf_extractor/doctype"
+	"go_ocr/internal/services/pdf_extractor/signature"
+	"time"
+)
+
+// verifySignatures valida las firmas digitales de un PDF. Devuelve nil si el documento no
+// es un PDF, no está firmado o no se pudo verificar: la verificación no bloquea la extracción.
+func (s *server) verifySignatures(ctx context.Context, filePath, password string) *signature.Report {
+	reqLog := logger.FromContext(ctx)
+	if docType, err := doctype.Detect(filePath); err != nil || docType != doctype.PDF {
+		return nil
+	}
+
+	startTime := time.Now()
+	report, err := signature.Verify(ctx, filePath, password, s.trustStore)
+	metrics.ObserveStage("signatures", startTime, err)
+	if err != nil {
+		reqLog.Warning("Error al verificar firmas: %v", err)
+		return nil
+	}
+	if !report.Signed {
+		return nil
+	}
+	for _, sig := range report.Signatures {
+		// Sin almacén de confianza el estado desconocido es el esperado y no se avisa
+		if sig.Status != signature.StatusValid && sig.Status != signature.StatusUnknown {
+			reqLog.Warning("Firma %q de %s no válida (%s): %v",
+				sig.Field, sig.Signer, sig.Status, sig.Errors)
+		}
+	}
+	return report
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// stageError describe el fallo de una etapa. Si se debe a que venció su plazo o se
+// canceló la petición, envuelve el error del contexto para poder distinguirlo; si no,
+// envuelve err.
+func stageError(ctx context.Context, stage string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("error al %s: %w", stage, ctxErr)
+	}
+	return fmt.Errorf("error al %s: %w", stage, err)
+}
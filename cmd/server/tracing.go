@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"go_ocr/config"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/tracing"
+	"net/http"
+)
+
+// newTracingConfig construye el envío de trazas con el exportador configurado: none, otlp
+// (colector OTLP/HTTP) o memory, que guarda los últimos spans para consultarlos en
+// /debug/traces
+func newTracingConfig(cfg config.Tracing) (tracing.Config, error) {
+	tracingConfig := tracing.DefaultConfig
+	switch cfg.Exporter {
+	case "otlp":
+		otlp, err := tracing.NewOTLPExporter(cfg.Endpoint, cfg.Headers, cfg.ServiceName)
+		if err != nil {
+			return tracingConfig, err
+		}
+		tracingConfig.Exporter = otlp
+	case "memory":
+		tracingConfig.Exporter = tracing.NewMemoryExporter(cfg.MemorySpans)
+	}
+	return tracingConfig, nil
+}
+
+// tracesHandler devuelve los spans guardados por el exportador en memoria, todos o los de
+// la traza del parámetro trace_id. Exige el token de administración.
+func (s *server) tracesHandler(w http.ResponseWriter, r *http.Request) {
+	reqLog := logger.FromContext(r.Context())
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAdmin(w, r, "Consulta de trazas") {
+		return
+	}
+
+	spans := s.memorySpans.Spans()
+	if id := r.FormValue("trace_id"); id != "" {
+		traceID, err := tracing.ParseTraceID(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		spans = s.memorySpans.Trace(traceID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(spans); err != nil {
+		reqLog.Error("Error al escribir respuesta: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileEnv es la variable de entorno con la ruta del archivo de configuración, si no se
+// indica con la opción -config
+const fileEnv = "CONFIG_FILE"
+
+// field es un valor configurable de Config
+type field struct {
+	key   string // Ruta de claves YAML separada por puntos; también es el nombre de la opción
+	env   string
+	value reflect.Value
+}
+
+// Load construye la configuración a partir de Default, el archivo YAML de la opción -config
+// o de CONFIG_FILE (opcional), las variables de entorno y las opciones de args, cada fuente
+// con prioridad sobre las anteriores, y la valida. Las variables de entorno vacías no
+// cambian el valor. Con -h devuelve flag.ErrHelp tras mostrar las opciones.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+	fields := fieldsOf(&cfg)
+
+	// Las opciones se leen primero para conocer -config, pero se aplican al final
+	flags := flag.NewFlagSet("go_ocr", flag.ContinueOnError)
+	path := flags.String("config", os.Getenv(fileEnv), "archivo de configuración YAML (equivale a "+fileEnv+")")
+	values := make(map[string]string)
+	for _, f := range fields {
+		flags.Var(&flagValue{raw: values, key: f.key, isBool: f.value.Kind() == reflect.Bool},
+			f.key, "equivale a "+f.env)
+	}
+	if err := flags.Parse(args); err != nil {
+		return cfg, err
+	}
+	if flags.NArg() > 0 {
+		return cfg, fmt.Errorf("argumentos no reconocidos: %v", flags.Args())
+	}
+
+	if *path != "" {
+		if err := loadFile(&cfg, *path); err != nil {
+			return cfg, err
+		}
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if raw := os.Getenv(f.env); raw != "" {
+			if err := setValue(f.value, raw); err != nil {
+				errs = append(errs, fmt.Errorf("valor de %s inválido: %q", f.env, raw))
+			}
+		}
+	}
+	for _, f := range fields {
+		if raw, ok := values[f.key]; ok {
+			if err := setValue(f.value, raw); err != nil {
+				errs = append(errs, fmt.Errorf("valor de -%s inválido: %q", f.key, raw))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// loadFile sobrescribe cfg con los valores del archivo YAML. Una clave desconocida es un
+// error para que las erratas no pasen desapercibidas.
+func loadFile(cfg *Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error al abrir archivo de configuración: %v", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("error al leer archivo de configuración %s: %v", path, err)
+	}
+	return nil
+}
+
+// flagValue guarda el texto de una opción para aplicarlo después del archivo y el entorno
+type flagValue struct {
+	raw    map[string]string
+	key    string
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil || v.raw == nil {
+		return ""
+	}
+	return v.raw[v.key]
+}
+
+func (v *flagValue) Set(raw string) error {
+	v.raw[v.key] = raw
+	return nil
+}
+
+// IsBoolFlag permite escribir -opción en lugar de -opción=true en las opciones booleanas
+func (v *flagValue) IsBoolFlag() bool { return v.isBool }
+
+// fieldsOf recorre los campos con variable de entorno de cfg y sus secciones
+func fieldsOf(cfg *Config) []field {
+	var fields []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			key := prefix + t.Field(i).Tag.Get("yaml")
+			if env := t.Field(i).Tag.Get("env"); env != "" {
+				fields = append(fields, field{key: key, env: env, value: v.Field(i)})
+			} else if v.Field(i).Kind() == reflect.Struct {
+				walk(v.Field(i), key+".")
+			}
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return fields
+}
+
+// describe devuelve la clave con su variable de entorno para los mensajes de error
+func describe(key string) string {
+	for _, f := range fieldsOf(&Config{}) {
+		if f.key == key {
+			return fmt.Sprintf("%s (%s)", key, f.env)
+		}
+	}
+	return key
+}
+
+// setValue interpreta raw según el tipo del campo. Las listas se separan por comas y los
+// mapas son pares nombre=valor separados por comas.
+func setValue(v reflect.Value, raw string) error {
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int || v.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		v.Set(reflect.ValueOf(strings.Split(raw, ",")))
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String && v.Type().Elem().Kind() == reflect.String:
+		pairs := make(map[string]string)
+		for _, pair := range strings.Split(raw, ",") {
+			name, value, ok := strings.Cut(pair, "=")
+			name = strings.TrimSpace(name)
+			if !ok || name == "" {
+				return fmt.Errorf("par nombre=valor inválido: %q", pair)
+			}
+			pairs[name] = strings.TrimSpace(value)
+		}
+		v.Set(reflect.ValueOf(pairs))
+	default:
+		return fmt.Errorf("tipo de configuración no soportado: %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv vacía durante el test todas las variables de entorno de la configuración, que
+// Load ignora si están vacías
+func clearEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv(fileEnv, "")
+	for _, f := range fieldsOf(&Config{}) {
+		t.Setenv(f.env, "")
+	}
+}
+
+// writeConfig guarda content en un archivo YAML temporal y devuelve su ruta
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeConfig(t, "ocr:\n  dpi: 200\n  psm: 4\n")
+	tests := []struct {
+		name     string
+		file     bool
+		env      string
+		args     []string
+		dpi, psm int
+	}{
+		{"por defecto", false, "", nil, Default().OCR.DPI, Default().OCR.PSM},
+		{"archivo", true, "", nil, 200, 4},
+		{"entorno sobre archivo", true, "250", nil, 250, 4},
+		{"opción sobre entorno", true, "250", []string{"-ocr.dpi=350"}, 350, 4},
+		{"opción sin archivo ni entorno", false, "", []string{"-ocr.dpi", "350"}, 350, Default().OCR.PSM},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			if tt.file {
+				t.Setenv(fileEnv, file)
+			}
+			t.Setenv("OCR_DPI", tt.env)
+
+			cfg, err := Load(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.OCR.DPI != tt.dpi || cfg.OCR.PSM != tt.psm {
+				t.Errorf("DPI %d y PSM %d, se esperaban %d y %d", cfg.OCR.DPI, cfg.OCR.PSM, tt.dpi, tt.psm)
+			}
+		})
+	}
+}
+
+func TestLoadConfigOption(t *testing.T) {
+	clearEnv(t)
+	t.Setenv(fileEnv, writeConfig(t, "app:\n  port: 9000\n"))
+
+	// -config tiene prioridad sobre CONFIG_FILE
+	cfg, err := Load([]string{"-config", writeConfig(t, "app:\n  port: 9100\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.App.Port != 9100 {
+		t.Errorf("puerto %d, se esperaba 9100", cfg.App.Port)
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		key     string
+	}{
+		{"clave de sección", "ocr:\n  dpy: 300\n", "dpy"},
+		{"sección", "ocrr:\n  dpi: 300\n", "ocrr"},
+		{"clave anidada", "log:\n  file:\n    max_size: 10\n", "max_size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			_, err := Load([]string{"-config", writeConfig(t, tt.content)})
+			if err == nil || !strings.Contains(err.Error(), tt.key) {
+				t.Errorf("error %v, se esperaba uno que mencionara %q", err, tt.key)
+			}
+		})
+	}
+}
+
+func TestLoadExampleFile(t *testing.T) {
+	clearEnv(t)
+	if _, err := Load([]string{"-config", "config.example.yaml"}); err != nil {
+		t.Errorf("config.example.yaml no es válido: %v", err)
+	}
+}
+
+func TestLoadParsesValues(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("REQUEST_TIMEOUT", "90s")
+	t.Setenv("DEEPSEEK_RETRY_DELAY", "250ms")
+	t.Setenv("OCR_DETECT_LANGUAGES", "spa,cat,eus")
+	t.Setenv("PDF_PASSWORDS", "uno,dos")
+	t.Setenv("TRACING_HEADERS", "authorization=Bearer abc, x-tenant = nominas")
+	t.Setenv("DOWNLOAD_MAX_SIZE_MB", "20")
+
+	cfg, err := Load([]string{"-log.allow_trace", "-timeouts.ai=3m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Timeouts.Request != 90*time.Second || cfg.Timeouts.AI != 3*time.Minute || cfg.AI.RetryDelay != 250*time.Millisecond {
+		t.Errorf("plazos %v, %v y %v; se esperaban 1m30s, 3m0s y 250ms",
+			cfg.Timeouts.Request, cfg.Timeouts.AI, cfg.AI.RetryDelay)
+	}
+	if want := []string{"spa", "cat", "eus"}; !reflect.DeepEqual(cfg.OCR.DetectLanguages, want) {
+		t.Errorf("idiomas %q, se esperaba %q", cfg.OCR.DetectLanguages, want)
+	}
+	if want := []string{"uno", "dos"}; !reflect.DeepEqual(cfg.Extract.Passwords, want) {
+		t.Errorf("contraseñas %q, se esperaba %q", cfg.Extract.Passwords, want)
+	}
+	if want := map[string]string{"authorization": "Bearer abc", "x-tenant": "nominas"}; !reflect.DeepEqual(cfg.Tracing.Headers, want) {
+		t.Errorf("cabeceras %q, se esperaba %q", cfg.Tracing.Headers, want)
+	}
+	if !cfg.Log.AllowTrace {
+		t.Error("la opción booleana sin valor no activó log.allow_trace")
+	}
+	if cfg.Download.MaxSizeMB != 20 {
+		t.Errorf("tamaño máximo %d MB, se esperaban 20", cfg.Download.MaxSizeMB)
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want []string
+	}{
+		{"duración sin unidad", map[string]string{"REQUEST_TIMEOUT": "90"}, nil,
+			[]string{`valor de REQUEST_TIMEOUT inválido: "90"`}},
+		{"entero", map[string]string{"OCR_DPI": "alto"}, nil,
+			[]string{`valor de OCR_DPI inválido: "alto"`}},
+		{"booleano", map[string]string{"LOG_REDACT": "quizá"}, nil,
+			[]string{`valor de LOG_REDACT inválido: "quizá"`}},
+		{"par sin valor", map[string]string{"TRACING_HEADERS": "authorization"}, nil,
+			[]string{`valor de TRACING_HEADERS inválido: "authorization"`}},
+		{"opción", nil, []string{"-timeouts.ai=dos"},
+			[]string{`valor de -timeouts.ai inválido: "dos"`}},
+		{"todos los errores a la vez", map[string]string{"OCR_DPI": "alto", "CACHE_SIZE": "x"}, nil,
+			[]string{"OCR_DPI", "CACHE_SIZE"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			_, err := Load(tt.args)
+			if err == nil {
+				t.Fatal("se esperaba un error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q, se esperaba que contuviera %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadArguments(t *testing.T) {
+	clearEnv(t)
+	if _, err := Load([]string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("con -h el error es %v, se esperaba flag.ErrHelp", err)
+	}
+	if _, err := Load([]string{"-ocr.dpii=300"}); err == nil {
+		t.Error("se esperaba un error con una opción desconocida")
+	}
+	if _, err := Load([]string{"sobrante"}); err == nil || !strings.Contains(err.Error(), "argumentos no reconocidos") {
+		t.Errorf("error %v, se esperaba uno por argumentos no reconocidos", err)
+	}
+}
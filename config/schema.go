@@ -1 +1,283 @@
+// Package config define la configuración de la aplicación. Cada valor parte del valor por
+// defecto de Default y se sobrescribe, por este orden, con el archivo YAML opcional, las
+// variables de entorno y las opciones de la línea de comandos (ver Load). Las etiquetas de
+// cada campo indican su clave en el archivo (yaml) y su variable de entorno (env); la
+// opción de la línea de comandos es la ruta de claves separada por puntos, p. ej. -ocr.dpi.
 package config
+
+import (
+	"errors"
+	"fmt"
+	"go_ocr/internal/services/ai"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/pdf_extractor"
+	"go_ocr/internal/services/pdf_extractor/downloader"
+	"go_ocr/internal/services/pdf_extractor/forensics"
+	"go_ocr/internal/services/runner"
+	"net/url"
+	"time"
+)
+
+// Config es la configuración completa de la aplicación
+type Config struct {
+	App       App       `yaml:"app"`
+	AI        AI        `yaml:"ai"`
+	UniPDF    UniPDF    `yaml:"unipdf"`
+	Log       Log       `yaml:"log"`
+	Tracing   Tracing   `yaml:"tracing"`
+	Timeouts  Timeouts  `yaml:"timeouts"`
+	Download  Download  `yaml:"download"`
+	OCR       OCR       `yaml:"ocr"`
+	Extract   Extract   `yaml:"extract"`
+	Sandbox   Sandbox   `yaml:"sandbox"`
+	Signature Signature `yaml:"signature"`
+	Forensics Forensics `yaml:"forensics"`
+	Cache     Cache     `yaml:"cache"`
+}
+
+// App es la configuración del servidor HTTP
+type App struct {
+	Port        int           `yaml:"port" env:"APP_PORT"`
+	Env         string        `yaml:"env" env:"ENV"`                       // development, production...
+	ReadTimeout time.Duration `yaml:"read_timeout" env:"APP_READ_TIMEOUT"` // Plazo para leer la petición
+}
+
+// AI es la API del modelo que extrae los datos estructurados
+type AI struct {
+	APIKey      string        `yaml:"api_key" env:"DEEPSEEK_API_KEY"`
+	BaseURL     string        `yaml:"base_url" env:"DEEPSEEK_BASE_URL"`
+	Model       string        `yaml:"model" env:"DEEPSEEK_MODEL"`
+	MaxAttempts int           `yaml:"max_attempts" env:"DEEPSEEK_MAX_ATTEMPTS"` // Llamadas como máximo ante fallos transitorios
+	RetryDelay  time.Duration `yaml:"retry_delay" env:"DEEPSEEK_RETRY_DELAY"`   // Espera antes del primer reintento; se duplica en cada uno
+}
+
+// UniPDF es la licencia de la librería UniPDF. Sin licencia se desactiva su estrategia de
+// extracción y se usan solo pdftotext y OCR.
+type UniPDF struct {
+	LicenseKey string `yaml:"license_key" env:"UNIPDF_LICENSE_KEY"`
+}
+
+// Log es la configuración del registro. Sin nivel se usa debug con ENV=development e info
+// en otro caso; en producción el enmascarado no se puede desactivar ni activar el nivel trace.
+type Log struct {
+	Format     string  `yaml:"format" env:"LOG_FORMAT"` // text o json
+	Level      string  `yaml:"level" env:"LOG_LEVEL"`
+	AllowTrace bool    `yaml:"allow_trace" env:"LOG_ALLOW_TRACE"` // Permite el nivel trace (texto de documentos y prompts)
+	Redact     bool    `yaml:"redact" env:"LOG_REDACT"`
+	AdminToken string  `yaml:"admin_token" env:"LOG_ADMIN_TOKEN"` // Exigido por /log/level y /debug/traces; vacío los desactiva
+	File       LogFile `yaml:"file"`
+}
+
+// LogFile es el archivo de log compartido; sin ruta está desactivado y 0 desactiva un límite
+type LogFile struct {
+	Path        string        `yaml:"path" env:"LOG_FILE"`
+	MaxSizeMB   int64         `yaml:"max_size_mb" env:"LOG_FILE_MAX_SIZE_MB"`
+	RotateEvery time.Duration `yaml:"rotate_every" env:"LOG_FILE_ROTATE_EVERY"`
+	MaxBackups  int           `yaml:"max_backups" env:"LOG_FILE_MAX_BACKUPS"`
+	MaxAge      time.Duration `yaml:"max_age" env:"LOG_FILE_MAX_AGE"`
+	Compress    bool          `yaml:"compress" env:"LOG_FILE_COMPRESS"`
+}
+
+// Tracing es el envío de trazas: exportador none, otlp (colector OTLP/HTTP en Endpoint) o
+// memory (últimos MemorySpans spans, consultables en /debug/traces)
+type Tracing struct {
+	Exporter    string            `yaml:"exporter" env:"TRACING_EXPORTER"`
+	Endpoint    string            `yaml:"endpoint" env:"TRACING_ENDPOINT"`
+	Headers     map[string]string `yaml:"headers" env:"TRACING_HEADERS"` // En el entorno, nombre=valor separados por comas
+	ServiceName string            `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
+	MemorySpans int               `yaml:"memory_spans" env:"TRACING_MEMORY_SPANS"`
+}
+
+// Timeouts son los plazos máximos de la petición completa y de cada etapa del
+// procesamiento de un documento
+type Timeouts struct {
+	Request  time.Duration `yaml:"request" env:"REQUEST_TIMEOUT"`
+	Download time.Duration `yaml:"download" env:"DOWNLOAD_TIMEOUT"`
+	Extract  time.Duration `yaml:"extract" env:"EXTRACT_TIMEOUT"`
+	AI       time.Duration `yaml:"ai" env:"AI_TIMEOUT"`
+}
+
+// Download son los límites de la descarga de documentos
+type Download struct {
+	MaxSizeMB int64 `yaml:"max_size_mb" env:"DOWNLOAD_MAX_SIZE_MB"`
+}
+
+// OCR son el paralelismo, el preprocesado y las opciones de Tesseract por defecto; las
+// opciones de Tesseract se pueden sobrescribir en cada petición
+type OCR struct {
+	Workers         int      `yaml:"workers" env:"OCR_WORKERS"`             // Páginas en paralelo por documento; 0 usa los núcleos
+	MaxProcesses    int      `yaml:"max_processes" env:"OCR_MAX_PROCESSES"` // Procesos externos simultáneos; 0 usa los núcleos
+	Preprocess      string   `yaml:"preprocess" env:"OCR_PREPROCESS"`       // all, none o lista de pasos
+	Languages       string   `yaml:"languages" env:"OCR_LANGUAGES"`
+	DPI             int      `yaml:"dpi" env:"OCR_DPI"`
+	PSM             int      `yaml:"psm" env:"OCR_PSM"`
+	OEM             int      `yaml:"oem" env:"OCR_OEM"`
+	TessdataDir     string   `yaml:"tessdata_dir" env:"OCR_TESSDATA_DIR"`
+	Whitelist       string   `yaml:"whitelist" env:"OCR_WHITELIST"`
+	AutoLanguage    bool     `yaml:"auto_language" env:"OCR_AUTO_LANGUAGE"`
+	DetectLanguages []string `yaml:"detect_languages" env:"OCR_DETECT_LANGUAGES"`
+	MaxPixels       int      `yaml:"max_pixels" env:"OCR_MAX_PIXELS"` // Máximo de píxeles de una imagen decodificada en el servidor
+}
+
+// Extract son las opciones de extracción de texto
+type Extract struct {
+	Layout    bool     `yaml:"layout" env:"EXTRACT_LAYOUT"`   // Conserva las columnas y reconstruye la tabla de conceptos
+	Passwords []string `yaml:"passwords" env:"PDF_PASSWORDS"` // Se prueban con todos los PDF cifrados
+}
+
+// Sandbox es el aislamiento de poppler y tesseract; 0 desactiva un límite
+type Sandbox struct {
+	CPUTime     time.Duration `yaml:"cpu_time" env:"SANDBOX_CPU_TIME"`
+	MemoryMB    int64         `yaml:"memory_mb" env:"SANDBOX_MEMORY_MB"`
+	MaxOutputMB int64         `yaml:"max_output_mb" env:"SANDBOX_MAX_OUTPUT_MB"`
+	Env         []string      `yaml:"env" env:"SANDBOX_ENV"`           // Variables de entorno heredadas
+	TempDir     string        `yaml:"tmpdir" env:"SANDBOX_TMPDIR"`     // Vacío usa el del sistema
+	Launcher    string        `yaml:"launcher" env:"SANDBOX_LAUNCHER"` // Comando y opciones separados por espacios
+}
+
+// Signature es la validación de las firmas digitales
+type Signature struct {
+	TrustStore string `yaml:"trust_store" env:"SIGNATURE_TRUST_STORE"` // Archivo o directorio; vacío deja la confianza como desconocida
+}
+
+// Forensics es el análisis de manipulación; OCRPages 0 (por defecto) desactiva el contraste con OCR
+type Forensics struct {
+	Enabled  bool `yaml:"enabled" env:"FORENSICS_ENABLED"`
+	OCRPages int  `yaml:"ocr_pages" env:"FORENSICS_OCR_PAGES"`
+}
+
+// Cache es la caché de resultados: driver memory (Size entradas), disk (en Dir) o none
+type Cache struct {
+	Driver string `yaml:"driver" env:"CACHE_DRIVER"`
+	Dir    string `yaml:"dir" env:"CACHE_DIR"`
+	Size   int    `yaml:"size" env:"CACHE_SIZE"`
+}
+
+// Default devuelve la configuración por defecto, tomada de los valores por defecto de cada servicio
+func Default() Config {
+	ocrDefaults := pdf_extractor.DefaultOptions.OCR
+	logFile := logger.DefaultFileConfig
+	sandbox := runner.DefaultConfig
+	return Config{
+		App: App{Port: 8080, ReadTimeout: 10 * time.Second},
+		AI: AI{
+			BaseURL:     ai.DefaultConfig.BaseURL,
+			Model:       ai.DefaultConfig.Model,
+			MaxAttempts: ai.DefaultConfig.MaxAttempts,
+			RetryDelay:  ai.DefaultConfig.RetryDelay,
+		},
+		Log: Log{Format: logger.FormatText, Redact: true, File: LogFile{
+			MaxSizeMB:   logFile.MaxSize >> 20,
+			RotateEvery: logFile.RotateEvery,
+			MaxBackups:  logFile.MaxBackups,
+			MaxAge:      logFile.MaxAge,
+			Compress:    logFile.Compress,
+		}},
+		Tracing: Tracing{Exporter: "none", ServiceName: "go_ocr", MemorySpans: 1000},
+		Timeouts: Timeouts{
+			Request:  10 * time.Minute,
+			Download: time.Minute,
+			Extract:  5 * time.Minute,
+			AI:       2 * time.Minute,
+		},
+		Download: Download{MaxSizeMB: downloader.DefaultMaxSize >> 20},
+		OCR: OCR{
+			Preprocess:      "all",
+			Languages:       ocrDefaults.Languages,
+			DPI:             ocrDefaults.DPI,
+			PSM:             ocrDefaults.PSM,
+			OEM:             ocrDefaults.OEM,
+			AutoLanguage:    ocrDefaults.AutoDetect,
+			DetectLanguages: append([]string(nil), ocrDefaults.Candidates...),
+			MaxPixels:       ocrDefaults.MaxPixels,
+		},
+		Extract: Extract{Layout: pdf_extractor.DefaultOptions.Layout},
+		Sandbox: Sandbox{
+			CPUTime:     sandbox.CPUTime,
+			MemoryMB:    sandbox.Memory >> 20,
+			MaxOutputMB: sandbox.MaxOutput >> 20,
+			Env:         append([]string(nil), sandbox.Env...),
+		},
+		Forensics: Forensics{Enabled: true, OCRPages: forensics.DefaultOptions.OCRPages},
+		Cache:     Cache{Driver: "memory", Dir: "storage/cache", Size: 256},
+	}
+}
+
+// Validate comprueba los valores que no dependen de un servicio concreto. Devuelve todos
+// los errores a la vez, cada uno con la clave y la variable de entorno del valor.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", describe(key), fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(c.App.Port > 0 && c.App.Port <= 65535, "app.port", "debe estar entre 1 y 65535 (es %d)", c.App.Port)
+	check(c.App.ReadTimeout > 0, "app.read_timeout", "debe ser positivo (es %v)", c.App.ReadTimeout)
+
+	baseURL, err := url.Parse(c.AI.BaseURL)
+	check(err == nil && (baseURL.Scheme == "http" || baseURL.Scheme == "https") && baseURL.Host != "",
+		"ai.base_url", "debe ser una URL http(s) (es %q)", c.AI.BaseURL)
+	check(c.AI.Model != "", "ai.model", "no puede estar vacío")
+	check(c.AI.MaxAttempts >= 1, "ai.max_attempts", "debe ser al menos 1 (es %d)", c.AI.MaxAttempts)
+	check(c.AI.RetryDelay >= 0, "ai.retry_delay", "no puede ser negativo (es %v)", c.AI.RetryDelay)
+
+	check(c.Log.Format == logger.FormatText || c.Log.Format == logger.FormatJSON,
+		"log.format", "debe ser text o json (es %q)", c.Log.Format)
+	if c.Log.Level != "" {
+		_, err := logger.ParseLevel(c.Log.Level)
+		check(err == nil, "log.level", "%v", err)
+	}
+	check(c.Log.File.MaxSizeMB >= 0, "log.file.max_size_mb", "no puede ser negativo (es %d)", c.Log.File.MaxSizeMB)
+	check(c.Log.File.RotateEvery >= 0, "log.file.rotate_every", "no puede ser negativo (es %v)", c.Log.File.RotateEvery)
+	check(c.Log.File.MaxBackups >= 0, "log.file.max_backups", "no puede ser negativo (es %d)", c.Log.File.MaxBackups)
+	check(c.Log.File.MaxAge >= 0, "log.file.max_age", "no puede ser negativo (es %v)", c.Log.File.MaxAge)
+
+	switch c.Tracing.Exporter {
+	case "none":
+	case "otlp":
+		check(c.Tracing.Endpoint != "", "tracing.endpoint", "es obligatorio con el exportador otlp")
+	case "memory":
+		check(c.Tracing.MemorySpans > 0, "tracing.memory_spans", "debe ser positivo (es %d)", c.Tracing.MemorySpans)
+	default:
+		check(false, "tracing.exporter", "debe ser none, otlp o memory (es %q)", c.Tracing.Exporter)
+	}
+
+	timeouts := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"timeouts.request", c.Timeouts.Request},
+		{"timeouts.download", c.Timeouts.Download},
+		{"timeouts.extract", c.Timeouts.Extract},
+		{"timeouts.ai", c.Timeouts.AI},
+	}
+	for _, t := range timeouts {
+		check(t.value > 0, t.key, "debe ser positivo (es %v)", t.value)
+	}
+
+	check(c.Download.MaxSizeMB > 0, "download.max_size_mb", "debe ser positivo (es %d)", c.Download.MaxSizeMB)
+
+	check(c.OCR.Workers >= 0, "ocr.workers", "no puede ser negativo (es %d)", c.OCR.Workers)
+	check(c.OCR.MaxProcesses >= 0, "ocr.max_processes", "no puede ser negativo (es %d)", c.OCR.MaxProcesses)
+	check(c.OCR.MaxPixels > 0, "ocr.max_pixels", "debe ser positivo (es %d)", c.OCR.MaxPixels)
+
+	check(c.Sandbox.CPUTime >= 0, "sandbox.cpu_time", "no puede ser negativo (es %v)", c.Sandbox.CPUTime)
+	check(c.Sandbox.MemoryMB >= 0, "sandbox.memory_mb", "no puede ser negativo (es %d)", c.Sandbox.MemoryMB)
+	check(c.Sandbox.MaxOutputMB >= 0, "sandbox.max_output_mb", "no puede ser negativo (es %d)", c.Sandbox.MaxOutputMB)
+
+	check(c.Forensics.OCRPages >= 0, "forensics.ocr_pages", "no puede ser negativo (es %d)", c.Forensics.OCRPages)
+
+	switch c.Cache.Driver {
+	case "none":
+	case "memory":
+		check(c.Cache.Size > 0, "cache.size", "debe ser positivo (es %d)", c.Cache.Size)
+	case "disk":
+		check(c.Cache.Dir != "", "cache.dir", "es obligatorio con el driver disk")
+	default:
+		check(false, "cache.driver", "debe ser memory, disk o none (es %q)", c.Cache.Driver)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("la configuración por defecto no es válida: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   string
+	}{
+		{"puerto", func(c *Config) { c.App.Port = 70000 },
+			"app.port (APP_PORT): debe estar entre 1 y 65535 (es 70000)"},
+		{"plazo de lectura", func(c *Config) { c.App.ReadTimeout = 0 },
+			"app.read_timeout (APP_READ_TIMEOUT): debe ser positivo (es 0s)"},
+		{"URL de la API", func(c *Config) { c.AI.BaseURL = "api.deepseek.com" },
+			`ai.base_url (DEEPSEEK_BASE_URL): debe ser una URL http(s) (es "api.deepseek.com")`},
+		{"modelo", func(c *Config) { c.AI.Model = "" },
+			"ai.model (DEEPSEEK_MODEL): no puede estar vacío"},
+		{"intentos", func(c *Config) { c.AI.MaxAttempts = 0 },
+			"ai.max_attempts (DEEPSEEK_MAX_ATTEMPTS): debe ser al menos 1 (es 0)"},
+		{"espera entre intentos", func(c *Config) { c.AI.RetryDelay = -1 },
+			"ai.retry_delay (DEEPSEEK_RETRY_DELAY): no puede ser negativo (es -1ns)"},
+		{"formato de log", func(c *Config) { c.Log.Format = "xml" },
+			`log.format (LOG_FORMAT): debe ser text o json (es "xml")`},
+		{"nivel de log", func(c *Config) { c.Log.Level = "verbose" },
+			"log.level (LOG_LEVEL): "},
+		{"tamaño del archivo de log", func(c *Config) { c.Log.File.MaxSizeMB = -1 },
+			"log.file.max_size_mb (LOG_FILE_MAX_SIZE_MB): no puede ser negativo (es -1)"},
+		{"rotación del log", func(c *Config) { c.Log.File.RotateEvery = -1 },
+			"log.file.rotate_every (LOG_FILE_ROTATE_EVERY): no puede ser negativo (es -1ns)"},
+		{"copias del log", func(c *Config) { c.Log.File.MaxBackups = -1 },
+			"log.file.max_backups (LOG_FILE_MAX_BACKUPS): no puede ser negativo (es -1)"},
+		{"antigüedad del log", func(c *Config) { c.Log.File.MaxAge = -1 },
+			"log.file.max_age (LOG_FILE_MAX_AGE): no puede ser negativo (es -1ns)"},
+		{"endpoint OTLP", func(c *Config) { c.Tracing.Exporter = "otlp" },
+			"tracing.endpoint (TRACING_ENDPOINT): es obligatorio con el exportador otlp"},
+		{"spans en memoria", func(c *Config) { c.Tracing.Exporter, c.Tracing.MemorySpans = "memory", 0 },
+			"tracing.memory_spans (TRACING_MEMORY_SPANS): debe ser positivo (es 0)"},
+		{"exportador", func(c *Config) { c.Tracing.Exporter = "jaeger" },
+			`tracing.exporter (TRACING_EXPORTER): debe ser none, otlp o memory (es "jaeger")`},
+		{"plazo de la petición", func(c *Config) { c.Timeouts.Request = 0 },
+			"timeouts.request (REQUEST_TIMEOUT): debe ser positivo (es 0s)"},
+		{"plazo de descarga", func(c *Config) { c.Timeouts.Download = 0 },
+			"timeouts.download (DOWNLOAD_TIMEOUT): debe ser positivo (es 0s)"},
+		{"plazo de extracción", func(c *Config) { c.Timeouts.Extract = 0 },
+			"timeouts.extract (EXTRACT_TIMEOUT): debe ser positivo (es 0s)"},
+		{"plazo de IA", func(c *Config) { c.Timeouts.AI = 0 },
+			"timeouts.ai (AI_TIMEOUT): debe ser positivo (es 0s)"},
+		{"tamaño de descarga", func(c *Config) { c.Download.MaxSizeMB = 0 },
+			"download.max_size_mb (DOWNLOAD_MAX_SIZE_MB): debe ser positivo (es 0)"},
+		{"páginas en paralelo", func(c *Config) { c.OCR.Workers = -1 },
+			"ocr.workers (OCR_WORKERS): no puede ser negativo (es -1)"},
+		{"procesos de OCR", func(c *Config) { c.OCR.MaxProcesses = -1 },
+			"ocr.max_processes (OCR_MAX_PROCESSES): no puede ser negativo (es -1)"},
+		{"píxeles de OCR", func(c *Config) { c.OCR.MaxPixels = 0 },
+			"ocr.max_pixels (OCR_MAX_PIXELS): debe ser positivo (es 0)"},
+		{"CPU del sandbox", func(c *Config) { c.Sandbox.CPUTime = -1 },
+			"sandbox.cpu_time (SANDBOX_CPU_TIME): no puede ser negativo (es -1ns)"},
+		{"memoria del sandbox", func(c *Config) { c.Sandbox.MemoryMB = -1 },
+			"sandbox.memory_mb (SANDBOX_MEMORY_MB): no puede ser negativo (es -1)"},
+		{"salida del sandbox", func(c *Config) { c.Sandbox.MaxOutputMB = -1 },
+			"sandbox.max_output_mb (SANDBOX_MAX_OUTPUT_MB): no puede ser negativo (es -1)"},
+		{"páginas forenses", func(c *Config) { c.Forensics.OCRPages = -1 },
+			"forensics.ocr_pages (FORENSICS_OCR_PAGES): no puede ser negativo (es -1)"},
+		{"tamaño de la caché", func(c *Config) { c.Cache.Size = 0 },
+			"cache.size (CACHE_SIZE): debe ser positivo (es 0)"},
+		{"directorio de la caché", func(c *Config) { c.Cache.Driver, c.Cache.Dir = "disk", "" },
+			"cache.dir (CACHE_DIR): es obligatorio con el driver disk"},
+		{"driver de la caché", func(c *Config) { c.Cache.Driver = "redis" },
+			`cache.driver (CACHE_DRIVER): debe ser memory, disk o none (es "redis")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.change(&cfg)
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatalf("se esperaba el error %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q, se esperaba %q", err, tt.want)
+			}
+			// Cada caso cambia un solo valor: no hay más errores
+			if n := len(strings.Split(err.Error(), "\n")); n != 1 {
+				t.Errorf("%d errores, se esperaba 1: %v", n, err)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.App.Port = 0
+	cfg.OCR.Workers = -1
+	cfg.Cache.Driver = "redis"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("se esperaba un error")
+	}
+	for _, key := range []string{"app.port", "ocr.workers", "cache.driver"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("el error %q no menciona %s", err, key)
+		}
+	}
+}
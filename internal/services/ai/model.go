@@ -2,20 +2,81 @@ package ai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/metrics"
+	"go_ocr/internal/services/tracing"
 	"io"
 	"net/http"
-	"os"
 	"regexp"
 	"strings"
+	"time"
 )
 
+// PromptVersion identifica la versión del prompt; hay que incrementarla al modificarlo
+const PromptVersion = "v5"
+
+// Config es la API de DeepSeek a la que se envían los textos
+type Config struct {
+	APIKey      string
+	BaseURL     string // URL base de la API, sin /chat/completions
+	Model       string
+	MaxAttempts int           // Llamadas como máximo ante fallos transitorios; menos de 1 equivale a 1
+	RetryDelay  time.Duration // Espera antes del primer reintento; se duplica en cada uno
+}
+
+// DefaultConfig es la configuración usada si no se configura otra
+var DefaultConfig = Config{
+	BaseURL:     "https://api.deepseek.com",
+	Model:       "deepseek-reasoner",
+	MaxAttempts: 3,
+	RetryDelay:  time.Second,
+}
+
+// Client envía los textos a la API configurada
+type Client struct {
+	config Config
+}
+
+// New crea un Client para la API de cfg
+func New(cfg Config) *Client {
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	return &Client{config: cfg}
+}
+
+// Model devuelve el modelo configurado, que forma parte de la clave de caché de los datos
+func (c *Client) Model() string {
+	return c.config.Model
+}
+
 var (
-	log = logger.NewLogger(false) // Logger compartido
+	llmDuration = metrics.NewHistogram("go_ocr_llm_request_duration_seconds",
+		"Duración de las llamadas a la API del modelo por resultado.", metrics.DurationBuckets, "outcome")
+	llmTokens = metrics.NewCounter("go_ocr_llm_tokens_total",
+		"Tokens consumidos en la API del modelo por tipo (prompt o completion).", "type")
+	// validationFailures cuenta las respuestas del modelo que no cumplen una regla: json (no
+	// se puede interpretar), tax_id (DNI/NIE inválido) o required (falta un campo obligatorio)
+	validationFailures = metrics.NewCounter("go_ocr_llm_validation_failures_total",
+		"Respuestas del modelo que incumplen una regla de validación.", "rule")
+	// llmRetries cuenta las llamadas repetidas por el motivo del fallo anterior: network,
+	// rate_limit (429) o server (5xx)
+	llmRetries = metrics.NewCounter("go_ocr_llm_retries_total",
+		"Llamadas a la API del modelo repetidas tras un fallo transitorio.", "reason")
 )
 
+// transientError es un fallo de la API que puede resolverse repitiendo la llamada
+type transientError struct {
+	reason string // Etiqueta de llmRetries
+	err    error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+
+func (e *transientError) Unwrap() error { return e.err }
+
 // PayrollData representa la estructura del JSON que esperamos recibir
 type PayrollData struct {
 	Employee struct {
@@ -32,9 +93,10 @@ type PayrollData struct {
 	NetAmount     float64 `json:"net_amount"`
 }
 
-// ExtractPayrollData envía el texto al modelo de IA y devuelve los datos estructurados
-func ExtractPayrollData(text string) (*PayrollData, error) {
-	apiKey := os.Getenv("DEEPSEEK_API_KEY")
+// ExtractPayrollData envía el texto al modelo de IA y devuelve los datos estructurados.
+// La llamada a la API se interrumpe si ctx se cancela o vence.
+func (c *Client) ExtractPayrollData(ctx context.Context, text string) (*PayrollData, error) {
+	log := logger.FromContext(ctx)
 
 	// Construir el prompt completo
 	prompt := `Eres un experto en nóminas españolas. Analiza el texto proporcionado y genera un JSON con esta estructura:  
@@ -59,6 +121,9 @@ func ExtractPayrollData(text string) (*PayrollData, error) {
 
 6. Net_amount: Debe ser igual a gross_amount - deductions. Validar con "Líquido a percibir".  
 
+Tabla de conceptos
+- Si el texto incluye líneas "concept | units | price | earning | deduction", cada fila siguiente es un concepto de la nómina: la columna earning es un devengo y la columna deduction una deducción. Respeta esa asignación de columnas.
+
 Validaciones
 - Si el employer_costs que has obtenido es menor que el gross_amount el employeer_costs debe ser employeer_costs + gross_amount.
 
@@ -84,11 +149,96 @@ Ejemplo de respuesta válida:
 }  
 ` + "\n\n" + text
 
-	log.Info("Prompt: %s", prompt)
+	log.Trace("Prompt: %s", prompt)
+
+	content, err := c.requestCompletion(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extraer el contenido JSON de la respuesta
+	jsonContent := cleanJSONResponse(content)
+
+	// Parsear el JSON a nuestra estructura
+	var payrollData PayrollData
+	if err := json.Unmarshal([]byte(jsonContent), &payrollData); err != nil {
+		validationFailures.Inc("json")
+		return nil, fmt.Errorf("error unmarshaling payroll data: %v", err)
+	}
+	if payrollData.Employee.Name == "" || payrollData.GrossAmount == 0 || payrollData.NetAmount == 0 {
+		validationFailures.Inc("required")
+		log.Warning("Faltan campos obligatorios en la respuesta del modelo")
+	}
+
+	// Define la expresión regular para DNI (8 dígitos + letra) y NIE (X/Y/Z + 7 dígitos + letra)
+	taxIDRegex := `(?i)^(\d{8}[a-z]|[xyz]\d{7}[a-z])$`
+	matched, err := regexp.MatchString(taxIDRegex, payrollData.Employee.TaxID)
+	if err != nil {
+		panic(err) // Maneja errores en la ejecución de la regex
+	}
+
+	if !matched {
+		validationFailures.Inc("tax_id")
+		// Busca en la variable text cualquier coincidencia de DNI/NIE
+		re := regexp.MustCompile(`(?i)(\d{8}[a-z]|[xyz]\d{7}[a-z])`)
+		found := re.FindString(text)
+		if found != "" {
+			// Actualiza el TaxID con el valor encontrado (opcional: convertir a mayúsculas)
+			payrollData.Employee.TaxID = strings.ToUpper(found)
+		}
+	}
+
+	return &payrollData, nil
+}
+
+// requestCompletion envía el prompt a la API de DeepSeek y devuelve el contenido de la
+// respuesta. Los fallos transitorios se reintentan hasta MaxAttempts llamadas, con una
+// espera que se duplica en cada reintento.
+func (c *Client) requestCompletion(ctx context.Context, prompt string) (string, error) {
+	log := logger.FromContext(ctx)
+	delay := c.config.RetryDelay
+	for attempt := 1; ; attempt++ {
+		content, err := c.requestAttempt(ctx, prompt, attempt)
+		var transient *transientError
+		if err == nil || !errors.As(err, &transient) || attempt >= c.config.MaxAttempts {
+			return content, err
+		}
+
+		log.Warning("Fallo transitorio en la API del modelo (intento %d de %d), se reintenta en %v: %v",
+			attempt, c.config.MaxAttempts, delay, err)
+		select {
+		case <-ctx.Done():
+			return "", err
+		case <-time.After(delay):
+		}
+		llmRetries.Inc(transient.reason)
+		delay *= 2
+	}
+}
+
+// requestAttempt hace la llamada número attempt a la API. Cada llamada es un span con el
+// modelo, el número de intento, el estado HTTP y los tokens consumidos.
+func (c *Client) requestAttempt(ctx context.Context, prompt string, attempt int) (string, error) {
+	ctx, span := tracing.Start(ctx, "llm.request", tracing.String("llm.model", c.config.Model),
+		tracing.String("llm.prompt_version", PromptVersion), tracing.Int("llm.attempt", attempt),
+		tracing.Int("llm.prompt_chars", len(prompt)))
+	span.SetKind(tracing.KindClient)
+	defer span.End()
+
+	startTime := time.Now()
+	content, err := c.callAPI(ctx, prompt, span)
+	span.RecordError(err)
+	llmDuration.Observe(time.Since(startTime).Seconds(), metrics.Outcome(err))
+	return content, err
+}
 
+// callAPI hace la llamada de requestAttempt y anota en span la respuesta. Los fallos de
+// red, el límite de peticiones y los errores del servidor se devuelven como transientError.
+func (c *Client) callAPI(ctx context.Context, prompt string, span *tracing.Span) (string, error) {
+	log := logger.FromContext(ctx)
 	// Estructura para la solicitud a la API
 	requestBody := map[string]interface{}{
-		"model": "deepseek-reasoner",
+		"model": c.config.Model,
 		"messages": []map[string]string{
 			{
 				"role":    "system",
@@ -105,36 +255,52 @@ Ejemplo de respuesta válida:
 	// Convertir a JSON
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("error marshaling request body: %v", err)
+		return "", fmt.Errorf("error marshaling request body: %v", err)
 	}
 
 	// Crear la solicitud HTTP
-	req, err := http.NewRequest("POST", "https://api.deepseek.com/chat/completions", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.BaseURL+"/chat/completions", bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
+		return "", fmt.Errorf("error creating request: %v", err)
 	}
 
 	// Añadir headers
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
 
 	// Realizar la solicitud
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %v", err)
+		// Si ctx venció o se canceló no tiene sentido reintentar
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("error making request: %v", err)
+		}
+		return "", &transientError{reason: "network", err: fmt.Errorf("error making request: %v", err)}
 	}
 	defer resp.Body.Close()
 
 	// Leer la respuesta
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("error reading response body: %v", err)
+		}
+		return "", &transientError{reason: "network", err: fmt.Errorf("error reading response body: %v", err)}
 	}
 
+	span.SetAttributes(tracing.Int("http.status_code", resp.StatusCode))
+
 	// Verificar el código de estado
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, body)
+		err := fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, body)
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			return "", &transientError{reason: "rate_limit", err: err}
+		case resp.StatusCode >= 500:
+			return "", &transientError{reason: "server", err: err}
+		}
+		return "", err
 	}
 
 	// Parsear la respuesta de la API
@@ -144,46 +310,31 @@ Ejemplo de respuesta válida:
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		return nil, fmt.Errorf("error unmarshaling API response: %v", err)
+		return "", fmt.Errorf("error unmarshaling API response: %v", err)
 	}
 
-	log.Info("API response: %+v", apiResponse.Choices[0].Message.Content)
+	span.SetAttributes(
+		tracing.Int("llm.usage.prompt_tokens", apiResponse.Usage.PromptTokens),
+		tracing.Int("llm.usage.completion_tokens", apiResponse.Usage.CompletionTokens),
+		tracing.Int("llm.usage.total_tokens", apiResponse.Usage.TotalTokens),
+	)
+	llmTokens.Add(float64(apiResponse.Usage.PromptTokens), "prompt")
+	llmTokens.Add(float64(apiResponse.Usage.CompletionTokens), "completion")
 
 	if len(apiResponse.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in API response")
+		return "", fmt.Errorf("no choices in API response")
 	}
 
-	// Extraer el contenido JSON de la respuesta
-	jsonContent := apiResponse.Choices[0].Message.Content
-	jsonContent = cleanJSONResponse(jsonContent)
-
-	// Parsear el JSON a nuestra estructura
-	var payrollData PayrollData
-	if err := json.Unmarshal([]byte(jsonContent), &payrollData); err != nil {
-		return nil, fmt.Errorf("error unmarshaling payroll data: %v", err)
-	}
-
-	// Define la expresión regular para DNI (8 dígitos + letra) y NIE (X/Y/Z + 7 dígitos + letra)
-	taxIDRegex := `(?i)^(\d{8}[a-z]|[xyz]\d{7}[a-z])$`
-	matched, err := regexp.MatchString(taxIDRegex, payrollData.Employee.TaxID)
-	if err != nil {
-		panic(err) // Maneja errores en la ejecución de la regex
-	}
-
-	if !matched {
-		// Busca en la variable text cualquier coincidencia de DNI/NIE
-		re := regexp.MustCompile(`(?i)(\d{8}[a-z]|[xyz]\d{7}[a-z])`)
-		found := re.FindString(text)
-		if found != "" {
-			// Actualiza el TaxID con el valor encontrado (opcional: convertir a mayúsculas)
-			payrollData.Employee.TaxID = strings.ToUpper(found)
-		}
-	}
-
-	return &payrollData, nil
+	log.Trace("API response: %+v", apiResponse.Choices[0].Message.Content)
+	return apiResponse.Choices[0].Message.Content, nil
 }
 
 func cleanJSONResponse(response string) string {
@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"go_ocr/internal/services/metrics"
+	"go_ocr/internal/services/tracing"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// completion es una respuesta válida de la API con datos de nómina
+const completion = `{"choices":[{"message":{"content":"{\"employee\":{\"name\":\"Ana Torres García\",\"tax_id\":\"12345678Z\"},\"gross_amount\":2300,\"net_amount\":1954.3}"}}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`
+
+// fakeAPI responde con statuses en orden y después con una respuesta válida. Devuelve el
+// cliente que la usa y el contador de llamadas recibidas.
+func fakeAPI(t *testing.T, statuses ...int) (*Client, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			http.Error(w, "fallo simulado", statuses[n-1])
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, completion)
+	}))
+	t.Cleanup(server.Close)
+
+	client := New(Config{BaseURL: server.URL, Model: "test", MaxAttempts: 3, RetryDelay: time.Millisecond})
+	return client, &calls
+}
+
+// retries devuelve el valor de go_ocr_llm_retries_total para reason
+func retries(reason string) int {
+	re := regexp.MustCompile(`go_ocr_llm_retries_total\{reason="` + reason + `"\} (\d+)`)
+	match := re.FindStringSubmatch(metrics.Text())
+	if match == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(match[1])
+	return n
+}
+
+func TestExtractPayrollDataRetriesTransientErrors(t *testing.T) {
+	client, calls := fakeAPI(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	server, rateLimit := retries("server"), retries("rate_limit")
+
+	data, err := client.ExtractPayrollData(context.Background(), "Líquido a percibir 1.954,30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Employee.Name != "Ana Torres García" {
+		t.Errorf("nombre %q", data.Employee.Name)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("llamadas %d, se esperaban 3", calls.Load())
+	}
+	if retries("server") != server+1 || retries("rate_limit") != rateLimit+1 {
+		t.Errorf("reintentos server %d, rate_limit %d; se esperaba uno más de cada", retries("server")-server, retries("rate_limit")-rateLimit)
+	}
+}
+
+func TestExtractPayrollDataGivesUpAfterMaxAttempts(t *testing.T) {
+	client, calls := fakeAPI(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+
+	if _, err := client.ExtractPayrollData(context.Background(), "texto"); err == nil {
+		t.Fatal("se esperaba un error")
+	}
+	if calls.Load() != 3 {
+		t.Errorf("llamadas %d, se esperaban 3", calls.Load())
+	}
+}
+
+func TestExtractPayrollDataDoesNotRetryClientErrors(t *testing.T) {
+	client, calls := fakeAPI(t, http.StatusUnauthorized)
+
+	if _, err := client.ExtractPayrollData(context.Background(), "texto"); err == nil {
+		t.Fatal("se esperaba un error")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("llamadas %d, se esperaba 1", calls.Load())
+	}
+}
+
+func TestRequestSpansCarryAttemptNumber(t *testing.T) {
+	exporter := tracing.NewMemoryExporter(100)
+	if err := tracing.Configure(tracing.Config{Exporter: exporter}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tracing.Configure(tracing.Config{}) })
+	client, _ := fakeAPI(t, http.StatusInternalServerError)
+
+	if _, err := client.ExtractPayrollData(context.Background(), "texto"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracing.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var attempts []interface{}
+	for _, span := range exporter.Spans() {
+		if span.Name != "llm.request" {
+			continue
+		}
+		for _, attr := range span.Attributes {
+			if attr.Key == "llm.attempt" {
+				attempts = append(attempts, attr.Value)
+			}
+		}
+	}
+	if fmt.Sprint(attempts) != "[1 2]" {
+		t.Errorf("intentos en los spans %v, se esperaba [1 2]", attempts)
+	}
+}
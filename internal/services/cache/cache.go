@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go_ocr/internal/services/metrics"
+	"io"
+	"os"
+	"strings"
+)
+
+// lookups cuenta las consultas a la caché por tipo de entrada (text o data) y resultado
+// (hit o miss); la tasa de aciertos es hit / (hit + miss)
+var lookups = metrics.NewCounter("go_ocr_cache_lookups_total",
+	"Consultas a la caché de resultados por tipo de entrada y resultado.", "store", "kind", "result")
+
+// Store almacena resultados serializados indexados por clave
+type Store interface {
+	// Get devuelve el valor almacenado y si existía
+	Get(key string) ([]byte, bool)
+	// Set guarda el valor bajo la clave indicada
+	Set(key string, value []byte) error
+}
+
+// recordLookup registra una consulta; el tipo es el prefijo de la clave
+func recordLookup(store, key string, hit bool) {
+	kind, _, _ := strings.Cut(key, ":")
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	lookups.Inc(store, kind, result)
+}
+
+// HashFile calcula el SHA-256 del contenido de un archivo
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error al abrir archivo: %v", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("error al calcular hash: %v", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// TextKey construye la clave del texto extraído de un documento.
+// variant identifica las opciones de extracción, que cambian el texto obtenido.
+func TextKey(docHash, variant string) string {
+	return fmt.Sprintf("text:%s:%s", docHash, variant)
+}
+
+// DataKey construye la clave de los datos estructurados de un documento.
+// Incluye las opciones de extracción, la versión del prompt y el modelo porque
+// cualquiera de ellos cambia el resultado.
+func DataKey(docHash, variant, promptVersion, model string) string {
+	return fmt.Sprintf("data:%s:%s:%s:%s", docHash, variant, promptVersion, model)
+}
+
+// ForensicsKey construye la clave del análisis forense de un documento. Incluye las
+// opciones de extracción, que configuran el OCR de contraste, y las páginas contrastadas.
+func ForensicsKey(docHash, variant string, ocrPages int) string {
+	return fmt.Sprintf("forensics:%s:%s:%d", docHash, variant, ocrPages)
+}
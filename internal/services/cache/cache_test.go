@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeys(t *testing.T) {
+	const hash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	base := DataKey(hash, "layout=false", "v5", "deepseek-reasoner")
+	tests := []struct {
+		name  string
+		a, b  string
+		equal bool
+	}{
+		{"texto con las mismas opciones", TextKey(hash, "layout=false"), TextKey(hash, "layout=false"), true},
+		{"texto con otras opciones", TextKey(hash, "layout=false"), TextKey(hash, "layout=true"), false},
+		{"texto de otro documento", TextKey(hash, "layout=false"), TextKey("otro", "layout=false"), false},
+		{"datos con los mismos parámetros", base, DataKey(hash, "layout=false", "v5", "deepseek-reasoner"), true},
+		{"datos con otra versión del prompt", base, DataKey(hash, "layout=false", "v6", "deepseek-reasoner"), false},
+		{"datos con otro modelo", base, DataKey(hash, "layout=false", "v5", "deepseek-chat"), false},
+		{"datos con otras opciones", base, DataKey(hash, "layout=true", "v5", "deepseek-reasoner"), false},
+		{"texto y datos", TextKey(hash, "layout=false"), base, false},
+		{"forense con otras páginas de OCR", ForensicsKey(hash, "layout=false", 0), ForensicsKey(hash, "layout=false", 1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.a == tt.b) != tt.equal {
+				t.Errorf("claves %q y %q: iguales %t, se esperaba %t", tt.a, tt.b, tt.a == tt.b, tt.equal)
+			}
+		})
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nomina.pdf")
+	if err := os.WriteFile(path, []byte("test"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := HashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SHA-256 de "test"
+	if want := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"; hash != want {
+		t.Errorf("hash %s, se esperaba %s", hash, want)
+	}
+
+	if _, err := HashFile(filepath.Join(t.TempDir(), "no_existe.pdf")); err == nil {
+		t.Error("se esperaba un error con un archivo inexistente")
+	}
+}
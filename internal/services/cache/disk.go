@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DiskStore guarda cada entrada en un archivo dentro de un directorio
+type DiskStore struct {
+	dir string
+}
+
+// NewDiskStore crea una caché en disco, creando el directorio si no existe. Las entradas
+// contienen datos personales de las nóminas: el directorio se crea con permisos 0700 y
+// cada entrada con 0600.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error al crear directorio de caché: %v", err)
+	}
+	return &DiskStore{dir: dir}, nil
+}
+
+// Get lee la entrada del disco
+func (s *DiskStore) Get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(s.path(key))
+	recordLookup("disk", key, err == nil)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Set escribe la entrada de forma atómica (archivo temporal + rename). os.CreateTemp crea
+// el archivo con permisos 0600.
+func (s *DiskStore) Set(key string, value []byte) error {
+	tmp, err := os.CreateTemp(s.dir, "tmp_*")
+	if err != nil {
+		return fmt.Errorf("error al crear archivo temporal: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error al escribir entrada de caché: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error al cerrar entrada de caché: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		return fmt.Errorf("error al guardar entrada de caché: %v", err)
+	}
+	return nil
+}
+
+// path convierte la clave en un nombre de archivo seguro
+func (s *DiskStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
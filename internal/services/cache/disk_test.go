@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskStoreRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := store.Get("text:abc:v1"); ok {
+		t.Fatal("la caché recién creada devolvió una entrada")
+	}
+	for _, value := range []string{`{"text":"primera"}`, `{"text":"segunda"}`} {
+		if err := store.Set("text:abc:v1", []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := store.Get("text:abc:v1"); !ok || string(got) != value {
+			t.Errorf("entrada %q (%t), se esperaba %q", got, ok, value)
+		}
+	}
+
+	// Otra instancia sobre el mismo directorio lee lo escrito
+	reopened, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := reopened.Get("text:abc:v1"); !ok || string(got) != `{"text":"segunda"}` {
+		t.Errorf("tras reabrir: %q (%t)", got, ok)
+	}
+
+	// Solo queda el archivo de la entrada: ningún temporal de la escritura
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != filepath.Base(store.path("text:abc:v1")) {
+		t.Errorf("archivos en la caché: %v", files)
+	}
+}
+
+func TestDiskStorePermissions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("data:abc", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		t.Errorf("directorio con permisos %v, se esperaba 0700", perm)
+	}
+	info, err = os.Stat(store.path("data:abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("entrada con permisos %v, se esperaba 0600", perm)
+	}
+}
+
+func TestDiskStorePathIsSafe(t *testing.T) {
+	store := &DiskStore{dir: "/var/cache/go_ocr"}
+	for _, key := range []string{"../../etc/passwd", "text:a/b:c", ""} {
+		if path := store.path(key); filepath.Dir(path) != store.dir {
+			t.Errorf("la clave %q se guarda fuera del directorio: %s", key, path)
+		}
+	}
+	if store.path("text:a") == store.path("text:b") {
+		t.Error("claves distintas comparten archivo")
+	}
+}
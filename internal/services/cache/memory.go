@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+type memoryEntry struct {
+	key   string
+	value []byte
+}
+
+// MemoryStore es una caché LRU en memoria con un número máximo de entradas
+type MemoryStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+// NewMemoryStore crea una caché LRU en memoria
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &MemoryStore{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get devuelve el valor y lo marca como usado recientemente
+func (s *MemoryStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[key]
+	recordLookup("memory", key, ok)
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(elem)
+	return elem.Value.(*memoryEntry).value, true
+}
+
+// Set guarda el valor y expulsa la entrada menos usada si se supera la capacidad
+func (s *MemoryStore) Set(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		elem.Value.(*memoryEntry).value = value
+		s.order.MoveToFront(elem)
+		return nil
+	}
+
+	s.items[key] = s.order.PushFront(&memoryEntry{key: key, value: value})
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
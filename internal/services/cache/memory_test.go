@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	tests := []struct {
+		name    string
+		ops     []string // "set:clave" o "get:clave", en orden
+		present []string
+		evicted []string
+	}{
+		{"expulsa la más antigua", []string{"set:a", "set:b", "set:c", "set:d"},
+			[]string{"b", "c", "d"}, []string{"a"}},
+		{"leer renueva la entrada", []string{"set:a", "set:b", "set:c", "get:a", "set:d"},
+			[]string{"a", "c", "d"}, []string{"b"}},
+		{"reescribir renueva la entrada", []string{"set:a", "set:b", "set:c", "set:a", "set:d"},
+			[]string{"a", "c", "d"}, []string{"b"}},
+		{"leer una clave ausente no cambia el orden", []string{"set:a", "set:b", "set:c", "get:x", "set:d"},
+			[]string{"b", "c", "d"}, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore(3)
+			for _, op := range tt.ops {
+				switch action, key := op[:3], op[4:]; action {
+				case "set":
+					if err := store.Set(key, []byte("valor "+key)); err != nil {
+						t.Fatal(err)
+					}
+				case "get":
+					store.Get(key)
+				}
+			}
+			for _, key := range tt.present {
+				if value, ok := store.Get(key); !ok || string(value) != "valor "+key {
+					t.Errorf("entrada %s: %q, %t", key, value, ok)
+				}
+			}
+			for _, key := range tt.evicted {
+				if _, ok := store.Get(key); ok {
+					t.Errorf("la entrada %s no se expulsó", key)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreOverwrite(t *testing.T) {
+	store := NewMemoryStore(2)
+	store.Set("a", []byte("uno"))
+	store.Set("a", []byte("dos"))
+	if value, _ := store.Get("a"); string(value) != "dos" {
+		t.Errorf("valor %q, se esperaba \"dos\"", value)
+	}
+	if store.order.Len() != 1 || len(store.items) != 1 {
+		t.Errorf("%d entradas en la lista y %d en el índice, se esperaba 1", store.order.Len(), len(store.items))
+	}
+}
+
+func TestMemoryStoreMinimumCapacity(t *testing.T) {
+	for _, capacity := range []int{0, -5} {
+		t.Run(fmt.Sprint(capacity), func(t *testing.T) {
+			store := NewMemoryStore(capacity)
+			store.Set("a", []byte("uno"))
+			if _, ok := store.Get("a"); !ok {
+				t.Error("con capacidad no positiva se esperaba guardar al menos una entrada")
+			}
+		})
+	}
+}
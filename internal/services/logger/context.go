@@ -0,0 +1,20 @@
+package logger
+
+import "context"
+
+// contextKey es la clave del Logger en el contexto
+type contextKey struct{}
+
+// NewContext devuelve una copia de ctx que lleva l. Los paquetes lo recuperan con
+// FromContext, así cada línea registrada al atender una petición lleva sus campos.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext devuelve el Logger de ctx, o uno sin campos si ctx no lleva ninguno
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return &Logger{}
+}
@@ -0,0 +1,267 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat es la marca de tiempo que se añade al nombre de los archivos rotados
+const backupTimeFormat = "20060102T150405.000"
+
+// FileConfig configura el archivo de log compartido por todos los Logger
+type FileConfig struct {
+	Path        string        // Ruta del archivo; vacía lo desactiva
+	MaxSize     int64         // Tamaño en bytes a partir del cual se rota; 0 sin límite
+	RotateEvery time.Duration // Antigüedad del archivo a partir de la cual se rota; 0 sin límite
+	MaxBackups  int           // Archivos rotados que se conservan; 0 sin límite
+	MaxAge      time.Duration // Antigüedad máxima de los archivos rotados; 0 sin límite
+	Compress    bool          // Comprime con gzip los archivos rotados
+}
+
+// DefaultFileConfig son los límites por defecto del archivo de log
+var DefaultFileConfig = FileConfig{
+	MaxSize:    100 << 20,
+	MaxBackups: 10,
+	MaxAge:     30 * 24 * time.Hour,
+	Compress:   true,
+}
+
+// FileSink escribe en un archivo que rota por tamaño o antigüedad. Es seguro para
+// escrituras concurrentes: todos los Logger comparten el mismo.
+type FileSink struct {
+	cfg FileConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool // Close se llamó: Write ya no reabre el archivo
+
+	// cleanup serializa la compresión y el borrado de archivos rotados, que se hacen en segundo plano
+	cleanup sync.Mutex
+}
+
+// OpenFileSink abre (o crea) el archivo de cfg.Path para añadir al final
+func OpenFileSink(cfg FileConfig) (*FileSink, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("no se indicó la ruta del archivo de log")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("error al crear directorio de log: %v", err)
+	}
+	s := &FileSink{cfg: cfg}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// open abre el archivo y toma su tamaño actual. Debe llamarse con mu bloqueado. El log
+// puede contener datos de las nóminas, así que solo lo lee el usuario del servicio.
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("error al abrir archivo de log: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error al abrir archivo de log: %v", err)
+	}
+	s.file = file
+	s.size = info.Size()
+	s.openedAt = time.Now()
+	return nil
+}
+
+// Write escribe p en el archivo, rotándolo antes si se superaría el tamaño máximo o si
+// ha alcanzado la antigüedad máxima
+func (s *FileSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, os.ErrClosed
+	}
+	if s.file == nil {
+		// Una rotación anterior no pudo abrir el archivo nuevo: se reintenta en cada escritura
+		if err := s.open(); err != nil {
+			return 0, err
+		}
+	}
+	if s.shouldRotate(int64(len(p))) {
+		if err := s.rotate(); err != nil {
+			// Si no se puede rotar se sigue escribiendo en el archivo actual
+			fmt.Fprintf(os.Stderr, "Error al rotar archivo de log: %v\n", err)
+			if s.file == nil {
+				// No hay archivo en el que escribir; la siguiente escritura vuelve a abrirlo
+				return 0, err
+			}
+		}
+	}
+
+	n, err := s.file.Write(p)
+	s.size += int64(n)
+	return n, err
+}
+
+// shouldRotate indica si hay que rotar antes de escribir n bytes. Un archivo vacío no se
+// rota aunque la línea supere el tamaño máximo.
+func (s *FileSink) shouldRotate(n int64) bool {
+	if s.size == 0 {
+		return false
+	}
+	if s.cfg.MaxSize > 0 && s.size+n > s.cfg.MaxSize {
+		return true
+	}
+	return s.cfg.RotateEvery > 0 && time.Since(s.openedAt) >= s.cfg.RotateEvery
+}
+
+// rotate renombra el archivo actual con la marca de tiempo y abre uno nuevo.
+// Debe llamarse con mu bloqueado.
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	backup := s.backupName(time.Now())
+	if err := os.Rename(s.cfg.Path, backup); err != nil && !os.IsNotExist(err) {
+		// Sin renombrar se reabre el mismo archivo para no perder líneas
+		if openErr := s.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	go s.processBackups(backup)
+	return nil
+}
+
+// backupName devuelve el nombre del archivo rotado: app.log pasa a app-<marca>.log
+func (s *FileSink) backupName(t time.Time) string {
+	ext := filepath.Ext(s.cfg.Path)
+	base := strings.TrimSuffix(s.cfg.Path, ext)
+	return fmt.Sprintf("%s-%s%s", base, t.Format(backupTimeFormat), ext)
+}
+
+// processBackups comprime el archivo recién rotado y elimina los que sobran por número o antigüedad
+func (s *FileSink) processBackups(backup string) {
+	s.cleanup.Lock()
+	defer s.cleanup.Unlock()
+
+	if s.cfg.Compress {
+		if err := compressFile(backup); err != nil {
+			fmt.Fprintf(os.Stderr, "Error al comprimir archivo de log %s: %v\n", backup, err)
+		}
+	}
+	if err := s.removeOldBackups(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error al eliminar archivos de log antiguos: %v\n", err)
+	}
+}
+
+// compressFile comprime path en path.gz y elimina el original
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// removeOldBackups aplica la retención a los archivos rotados, del más reciente al más antiguo
+func (s *FileSink) removeOldBackups() error {
+	if s.cfg.MaxBackups <= 0 && s.cfg.MaxAge <= 0 {
+		return nil
+	}
+
+	ext := filepath.Ext(s.cfg.Path)
+	pattern := strings.TrimSuffix(s.cfg.Path, ext) + "-*" + ext + "*"
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+	var backups []backup
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && !info.IsDir() {
+			backups = append(backups, backup{match, info.ModTime()})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].modTime.After(backups[j].modTime) })
+
+	cutoff := time.Now().Add(-s.cfg.MaxAge)
+	for i, b := range backups {
+		tooMany := s.cfg.MaxBackups > 0 && i >= s.cfg.MaxBackups
+		tooOld := s.cfg.MaxAge > 0 && b.modTime.Before(cutoff)
+		if tooMany || tooOld {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Reopen cierra y vuelve a abrir el archivo en la misma ruta. Lo usa logrotate (u otra
+// herramienta externa) después de mover el archivo para que se siga escribiendo en el nuevo.
+func (s *FileSink) Reopen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	s.closed = false
+	return s.open()
+}
+
+// Close cierra el archivo
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,212 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openTestSink abre un FileSink en un directorio temporal y lo cierra al acabar el test
+func openTestSink(t *testing.T, cfg FileConfig) *FileSink {
+	t.Helper()
+	cfg.Path = filepath.Join(t.TempDir(), "logs", "app.log")
+	s, err := OpenFileSink(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func writeLine(t *testing.T, s *FileSink, line string) {
+	t.Helper()
+	if _, err := s.Write([]byte(line)); err != nil {
+		t.Fatalf("Write(%q): %v", line, err)
+	}
+}
+
+// readLog devuelve el contenido de path, descomprimiéndolo si es un .gz
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// backups devuelve los archivos rotados de s ordenados por nombre, que es el orden de rotación
+func backups(t *testing.T, s *FileSink) []string {
+	t.Helper()
+	matches, err := filepath.Glob(strings.TrimSuffix(s.cfg.Path, ".log") + "-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// waitBackups espera a que el procesado en segundo plano deje los archivos rotados que cumplen done
+func waitBackups(t *testing.T, s *FileSink, done func([]string) bool) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		files := backups(t, s)
+		if done(files) {
+			return files
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("el procesado de archivos rotados no terminó: %v", files)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func checkPrivate(t *testing.T, path string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("%s con permisos %o, se esperaba 600", filepath.Base(path), perm)
+	}
+}
+
+func TestFileSinkRotatesBySize(t *testing.T) {
+	s := openTestSink(t, FileConfig{MaxSize: 100})
+
+	line := strings.Repeat("x", 39) + "\n"
+	writeLine(t, s, line)
+	writeLine(t, s, line)
+	// La tercera línea superaría los 100 bytes: se escribe en un archivo nuevo
+	third := strings.Repeat("z", 39) + "\n"
+	writeLine(t, s, third)
+
+	files := backups(t, s)
+	if len(files) != 1 {
+		t.Fatalf("archivos rotados %v, se esperaba uno", files)
+	}
+	if got := readLog(t, files[0]); got != line+line {
+		t.Errorf("archivo rotado %q, se esperaban las dos primeras líneas", got)
+	}
+	if got := readLog(t, s.cfg.Path); got != third {
+		t.Errorf("archivo actual %q, se esperaba la tercera línea", got)
+	}
+	checkPrivate(t, s.cfg.Path)
+
+	// Una línea mayor que MaxSize en un archivo vacío no rota
+	s2 := openTestSink(t, FileConfig{MaxSize: 10})
+	writeLine(t, s2, line)
+	if files := backups(t, s2); len(files) != 0 {
+		t.Errorf("se rotó un archivo vacío: %v", files)
+	}
+}
+
+func TestFileSinkPrunesBackups(t *testing.T) {
+	s := openTestSink(t, FileConfig{MaxSize: 10, MaxBackups: 2, Compress: true})
+
+	for i, line := range []string{"línea 1\n", "línea 2\n", "línea 3\n", "línea 4\n", "línea 5\n"} {
+		writeLine(t, s, line)
+		// Se espera a que cada rotación termine de comprimirse para que el orden sea el de rotación
+		waitBackups(t, s, func(files []string) bool {
+			for _, f := range files {
+				if !strings.HasSuffix(f, ".gz") {
+					return false
+				}
+			}
+			return len(files) == min(i, 2)
+		})
+		time.Sleep(5 * time.Millisecond) // Marca de tiempo distinta en el nombre de cada rotado
+	}
+
+	files := backups(t, s)
+	var got []string
+	for _, f := range files {
+		got = append(got, readLog(t, f))
+		checkPrivate(t, f)
+	}
+	if strings.Join(got, "") != "línea 3\nlínea 4\n" {
+		t.Errorf("se conservaron %q, se esperaban los dos rotados más recientes", got)
+	}
+	if got := readLog(t, s.cfg.Path); got != "línea 5\n" {
+		t.Errorf("archivo actual %q", got)
+	}
+}
+
+func TestFileSinkReopen(t *testing.T) {
+	s := openTestSink(t, FileConfig{})
+	writeLine(t, s, "antes\n")
+
+	// logrotate mueve el archivo y avisa con SIGHUP
+	moved := s.cfg.Path + ".1"
+	if err := os.Rename(s.cfg.Path, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	writeLine(t, s, "después\n")
+
+	if got := readLog(t, moved); got != "antes\n" {
+		t.Errorf("archivo movido %q", got)
+	}
+	if got := readLog(t, s.cfg.Path); got != "después\n" {
+		t.Errorf("archivo nuevo %q", got)
+	}
+	checkPrivate(t, s.cfg.Path)
+}
+
+func TestFileSinkRecoversFromFailedRotation(t *testing.T) {
+	s := openTestSink(t, FileConfig{MaxSize: 10})
+	writeLine(t, s, "primera\n")
+
+	// Sin el directorio no se puede abrir el archivo nuevo al rotar
+	dir := filepath.Dir(s.cfg.Path)
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Write([]byte("perdida\n")); err == nil {
+		t.Fatal("se esperaba un error al rotar sin directorio")
+	}
+	if _, err := s.Write([]byte("perdida\n")); err == nil || errors.Is(err, os.ErrClosed) {
+		t.Fatalf("error %v, se esperaba el error al abrir el archivo", err)
+	}
+
+	// En cuanto el archivo se puede abrir, la siguiente escritura vuelve a funcionar
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeLine(t, s, "segunda\n")
+	if got := readLog(t, s.cfg.Path); got != "segunda\n" {
+		t.Errorf("archivo tras recuperarse %q", got)
+	}
+
+	s.Close()
+	if _, err := s.Write([]byte("cerrado\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("error %v tras Close, se esperaba os.ErrClosed", err)
+	}
+}
@@ -1,91 +1,296 @@
+// Package logger es el registro de la aplicación, construido sobre log/slog. Todos los
+// Logger comparten el handler (JSON o texto) y el nivel mínimo, que se configuran al
+// arrancar y se pueden cambiar en caliente con SetLevel.
 package logger
 
 import (
+	"context"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
-type Logger struct {
-	infoLogger    *log.Logger
-	errorLogger   *log.Logger
-	debugLogger   *log.Logger
-	warningLogger *log.Logger
-	fatalLogger   *log.Logger
-	file          *os.File
-}
-
-// NewLogger crea una nueva instancia de Logger
-func NewLogger(logToFile bool) *Logger {
-	l := &Logger{
-		infoLogger:    log.New(os.Stdout, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile),
-		errorLogger:   log.New(os.Stderr, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile),
-		debugLogger:   log.New(io.Discard, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile),
-		warningLogger: log.New(os.Stdout, "WARNING: ", log.Ldate|log.Ltime|log.Lshortfile),
-		fatalLogger:   log.New(os.Stderr, "FATAL: ", log.Ldate|log.Ltime|log.Lshortfile),
-	}
-
-	if logToFile {
-		file, err := os.OpenFile("app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-		if err == nil {
-			l.file = file
-
-			// Configurar los writers para cada logger
-			setupMultiWriter := func(logger *log.Logger) {
-				logger.SetOutput(io.MultiWriter(logger.Writer(), file))
-			}
-
-			setupMultiWriter(l.infoLogger)
-			setupMultiWriter(l.errorLogger)
-			setupMultiWriter(l.warningLogger)
-			setupMultiWriter(l.fatalLogger)
-		} else {
-			l.Error("No se pudo abrir archivo de log: %v", err)
+// Niveles propios además de los de slog
+const (
+	// LevelTrace es el nivel de los mensajes con el texto de los documentos. Solo se puede
+	// activar si la configuración lo permite y sus mensajes no se enmascaran.
+	LevelTrace = slog.Level(-8)
+	// LevelFatal es el nivel de los mensajes que terminan la aplicación
+	LevelFatal = slog.Level(12)
+)
+
+// Formatos de salida
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config es la configuración del registro
+type Config struct {
+	Format           string    // text o json
+	Level            string    // trace, debug, info, warn, error
+	Output           io.Writer // Por defecto la salida estándar
+	AllowTrace       bool      // Permite el nivel trace, que registra el texto de los documentos
+	DisableRedaction bool      // No enmascara los datos personales (solo para desarrollo)
+	File             FileConfig
+}
+
+var (
+	// level es el nivel mínimo compartido por todos los Logger
+	level = new(slog.LevelVar)
+
+	// handler es el handler compartido; se sustituye al configurar
+	handler atomic.Pointer[slog.Handler]
+
+	// allowTrace permite activar el nivel trace
+	allowTrace atomic.Bool
+
+	// redact enmascara los datos personales de los mensajes por debajo de trace
+	redact atomic.Bool
+
+	// sink es el archivo de log compartido, si está configurado
+	sink atomic.Pointer[FileSink]
+)
+
+func init() {
+	h := newHandler(FormatText, os.Stdout)
+	handler.Store(&h)
+	redact.Store(true)
+}
+
+// Configure establece el formato, la salida y el nivel mínimo de todos los Logger,
+// incluidos los creados antes de llamarla. Con cfg.File.Path los mensajes se escriben
+// además en ese archivo, compartido por todos los Logger.
+func Configure(cfg Config) error {
+	if cfg.Format == "" {
+		cfg.Format = FormatText
+	}
+	if cfg.Format != FormatText && cfg.Format != FormatJSON {
+		return fmt.Errorf("formato de log inválido: %q", cfg.Format)
+	}
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+	allowTrace.Store(cfg.AllowTrace)
+	redact.Store(!cfg.DisableRedaction)
+	if cfg.Level != "" {
+		if err := SetLevel(cfg.Level); err != nil {
+			return err
+		}
+	}
+
+	output := cfg.Output
+	var fileSink *FileSink
+	if cfg.File.Path != "" {
+		var err error
+		if fileSink, err = OpenFileSink(cfg.File); err != nil {
+			return err
 		}
+		output = io.MultiWriter(cfg.Output, fileSink)
+	}
+
+	h := newHandler(cfg.Format, output)
+	handler.Store(&h)
+	if previous := sink.Swap(fileSink); previous != nil {
+		previous.Close()
+	}
+	return nil
+}
+
+// Reopen vuelve a abrir el archivo de log, si está configurado
+func Reopen() error {
+	if s := sink.Load(); s != nil {
+		return s.Reopen()
+	}
+	return nil
+}
+
+// Close cierra el archivo de log, si está configurado. Los mensajes posteriores solo se
+// escriben en la salida principal.
+func Close() error {
+	if s := sink.Swap(nil); s != nil {
+		return s.Close()
 	}
+	return nil
+}
 
-	return l
+// newHandler crea el handler del formato indicado con el nivel compartido
+func newHandler(format string, output io.Writer) slog.Handler {
+	opts := &slog.HandlerOptions{AddSource: true, Level: level, ReplaceAttr: replaceAttr}
+	if format == FormatJSON {
+		return slog.NewJSONHandler(output, opts)
+	}
+	return slog.NewTextHandler(output, opts)
 }
 
-// EnableDebug activa los mensajes de debug
-func (l *Logger) EnableDebug() {
-	var output io.Writer = os.Stdout
-	if l.file != nil {
-		output = io.MultiWriter(os.Stdout, l.file)
+// replaceAttr da nombre a los niveles propios y acorta la ruta del código fuente
+func replaceAttr(_ []string, a slog.Attr) slog.Attr {
+	switch a.Key {
+	case slog.LevelKey:
+		if lvl, ok := a.Value.Any().(slog.Level); ok {
+			a.Value = slog.StringValue(LevelName(lvl))
+		}
+	case slog.SourceKey:
+		if source, ok := a.Value.Any().(*slog.Source); ok {
+			a.Value = slog.StringValue(fmt.Sprintf("%s:%d", shortPath(source.File), source.Line))
+		}
 	}
-	l.debugLogger.SetOutput(output)
+	return a
+}
+
+// shortPath deja el directorio y el archivo, como log.Lshortfile con el paquete
+func shortPath(file string) string {
+	if i := strings.LastIndexByte(file, '/'); i >= 0 {
+		if j := strings.LastIndexByte(file[:i], '/'); j >= 0 {
+			return file[j+1:]
+		}
+	}
+	return file
+}
+
+// ParseLevel interpreta el nombre de un nivel (trace, debug, info, warn o warning, error)
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "trace":
+		return LevelTrace, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("nivel de log inválido: %q", name)
+}
+
+// LevelName devuelve el nombre de un nivel
+func LevelName(lvl slog.Level) string {
+	switch {
+	case lvl >= LevelFatal:
+		return "FATAL"
+	case lvl >= slog.LevelError:
+		return "ERROR"
+	case lvl >= slog.LevelWarn:
+		return "WARN"
+	case lvl >= slog.LevelInfo:
+		return "INFO"
+	case lvl >= slog.LevelDebug:
+		return "DEBUG"
+	}
+	return "TRACE"
+}
+
+// SetLevel cambia el nivel mínimo de todos los Logger. El nivel trace solo se acepta si
+// la configuración lo permite.
+func SetLevel(name string) error {
+	lvl, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	if lvl <= LevelTrace && !allowTrace.Load() {
+		return fmt.Errorf("el nivel trace no está permitido en este entorno")
+	}
+	level.Set(lvl)
+	return nil
+}
+
+// GetLevel devuelve el nombre del nivel mínimo actual, en minúsculas
+func GetLevel() string {
+	return strings.ToLower(LevelName(level.Level()))
+}
+
+// Logger añade a cada mensaje sus campos clave-valor
+type Logger struct {
+	attrs []slog.Attr
+}
+
+// NewLogger crea una nueva instancia de Logger sin campos
+func NewLogger() *Logger {
+	return &Logger{}
+}
+
+// With devuelve un Logger que añade los campos indicados, en pares clave-valor
+// (p. ej. "request_id", id, "stage", "extract") o como slog.Attr
+func (l *Logger) With(args ...interface{}) *Logger {
+	record := slog.Record{}
+	record.Add(args...)
+	attrs := make([]slog.Attr, 0, len(l.attrs)+record.NumAttrs())
+	attrs = append(attrs, l.attrs...)
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return &Logger{attrs: attrs}
+}
+
+// Enabled indica si se registran los mensajes del nivel indicado, para evitar preparar
+// mensajes costosos que se van a descartar
+func (l *Logger) Enabled(lvl slog.Level) bool {
+	return lvl >= level.Level()
 }
 
 // Info registra un mensaje informativo
 func (l *Logger) Info(format string, v ...interface{}) {
-	l.infoLogger.Printf(format, v...)
+	l.log(slog.LevelInfo, format, v...)
 }
 
 // Error registra un mensaje de error
 func (l *Logger) Error(format string, v ...interface{}) {
-	l.errorLogger.Printf(format, v...)
+	l.log(slog.LevelError, format, v...)
+}
+
+// Trace registra un mensaje con contenido de los documentos (texto, prompts, datos
+// extraídos). No se enmascara, por eso el nivel solo se permite fuera de producción.
+func (l *Logger) Trace(format string, v ...interface{}) {
+	l.log(LevelTrace, format, v...)
 }
 
 // Debug registra un mensaje de debug
 func (l *Logger) Debug(format string, v ...interface{}) {
-	l.debugLogger.Printf(format, v...)
+	l.log(slog.LevelDebug, format, v...)
 }
 
 // Warning registra un mensaje de advertencia
 func (l *Logger) Warning(format string, v ...interface{}) {
-	l.warningLogger.Printf(format, v...)
+	l.log(slog.LevelWarn, format, v...)
 }
 
 // Fatal registra un mensaje fatal y termina la aplicación
 func (l *Logger) Fatal(format string, v ...interface{}) {
-	l.fatalLogger.Printf(format, v...)
-	l.Close()
+	l.log(LevelFatal, format, v...)
+	Close()
 	os.Exit(1)
 }
 
-// Close cierra los recursos del logger
-func (l *Logger) Close() {
-	if l.file != nil {
-		l.file.Close()
+// log construye el registro con el llamante de Info, Error, etc. y lo envía al handler.
+// Salvo en trace, el mensaje y los campos se enmascaran con Redact.
+func (l *Logger) log(lvl slog.Level, format string, v ...interface{}) {
+	if !l.Enabled(lvl) {
+		return
+	}
+
+	message := fmt.Sprintf(format, v...)
+	attrs := l.attrs
+	if lvl > LevelTrace && redact.Load() {
+		message = Redact(message)
+		attrs = make([]slog.Attr, len(l.attrs))
+		for i, a := range l.attrs {
+			attrs[i] = redactAttr(a)
+		}
 	}
+
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // runtime.Callers, log y el método del nivel
+	record := slog.NewRecord(time.Now(), lvl, message, pcs[0])
+	record.AddAttrs(attrs...)
+
+	h := *handler.Load()
+	_ = h.Handle(context.Background(), record)
 }
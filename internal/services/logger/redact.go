@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"log/slog"
+	"net/url"
+	"regexp"
+)
+
+// redactionRule sustituye por label los fragmentos que reconoce pattern
+type redactionRule struct {
+	label   string
+	pattern *regexp.Regexp
+}
+
+// redactionRules son los datos personales que se enmascaran en el registro. El orden
+// importa: el IBAN y el NAF contienen series de cifras que otras reglas también reconocerían.
+var redactionRules = []redactionRule{
+	{"[EMAIL]", regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{"[IBAN]", regexp.MustCompile(`\b[A-Z]{2}\d{2}(?:[ -]?[A-Z0-9]{4}){3,7}(?:[ -]?[A-Z0-9]{1,4})?\b`)},
+	// Número de afiliación a la Seguridad Social: provincia, número y control (28/12345678/90)
+	{"[NAF]", regexp.MustCompile(`\b\d{2}[/ -]?\d{8}[/ -]?\d{2}\b`)},
+	{"[NIE]", regexp.MustCompile(`\b[XYZxyz][ -]?\d{7}[ -]?[A-Za-z]\b`)},
+	{"[DNI]", regexp.MustCompile(`\b\d{8}[ -]?[A-Za-z]\b`)},
+	// Teléfonos con prefijo +34/0034 o escritos con separadores (600 123 456, 91 123 45 67).
+	// Nueve cifras seguidas sin prefijo son más a menudo referencias o importes que teléfonos.
+	{"[TELEFONO]", regexp.MustCompile(`(?:(?:\+|\b00)34[ -]?(?:[6789]\d{2}(?:[ -]?\d{3}[ -]?\d{3}|[ -]?\d{2}[ -]?\d{2}[ -]?\d{2})|[89]\d[ -]?\d{3}[ -]?\d{2}[ -]?\d{2})|\b(?:[6789]\d{2}(?:[ -]\d{3}[ -]\d{3}|[ -]\d{2}[ -]\d{2}[ -]\d{2})|[89]\d[ -]\d{3}[ -]\d{2}[ -]\d{2}))\b`)},
+	// Importes con decimales en coma (1.234,56) o con símbolo de moneda (1234.56 €);
+	// los decimales con punto sin moneda son puntuaciones y tiempos del propio registro
+	{"[IMPORTE]", regexp.MustCompile(`(?:\d{1,3}(?:\.\d{3})+|\d+),\d{2}\b(?:\s?(?:€|EUR))?|\d+(?:\.\d{1,2})?\s?(?:€|EUR)|€\s?\d+(?:[.,]\d{1,2})?`)},
+}
+
+// Redact enmascara DNI/NIE, IBAN, NAF, correos, teléfonos e importes del texto
+func Redact(text string) string {
+	for _, rule := range redactionRules {
+		text = rule.pattern.ReplaceAllString(text, rule.label)
+	}
+	return text
+}
+
+// RedactURL reduce url a esquema y host para el registro: la ruta y la consulta pueden
+// llevar tokens de acceso o datos personales
+func RedactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return "[URL]"
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// redactAttr enmascara los valores de texto de un campo, también dentro de grupos
+func redactAttr(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(Redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.StringValue(Redact(err.Error()))
+		}
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		redacted := make([]slog.Attr, len(attrs))
+		for i, attr := range attrs {
+			redacted[i] = redactAttr(attr)
+		}
+		a.Value = slog.GroupValue(redacted...)
+	}
+	return a
+}
@@ -0,0 +1,6 @@
+//go:build !unix
+
+package logger
+
+// ReopenOnSIGHUP no hace nada en sistemas sin SIGHUP
+func ReopenOnSIGHUP() {}
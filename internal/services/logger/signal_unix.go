@@ -0,0 +1,26 @@
+//go:build unix
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReopenOnSIGHUP vuelve a abrir el archivo de log cada vez que el proceso recibe SIGHUP,
+// la señal que envía logrotate (postrotate) tras mover el archivo
+func ReopenOnSIGHUP() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		l := NewLogger()
+		for range signals {
+			if err := Reopen(); err != nil {
+				l.Error("Error al reabrir archivo de log: %v", err)
+			} else {
+				l.Info("Archivo de log reabierto")
+			}
+		}
+	}()
+}
@@ -0,0 +1,304 @@
+// Package metrics expone métricas de la aplicación en el formato de texto de Prometheus.
+// Cada paquete declara sus métricas como variables con NewCounter, NewGauge o
+// NewHistogram, que las registran para Handler; los valores de las etiquetas se pasan
+// en el mismo orden en que se declararon sus nombres.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DurationBuckets son los límites (en segundos) de los histogramas de duración de las
+// etapas: desde milisegundos hasta los plazos de extracción
+var DurationBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// labelEscaper escapa los valores de las etiquetas según el formato de texto
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// metric es una familia de series con el mismo nombre y etiquetas
+type metric interface {
+	// write escribe la familia en el formato de texto de Prometheus
+	write(b *strings.Builder)
+	name() string
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]metric)
+)
+
+// register añade la métrica al registro; un nombre repetido es un error de programación
+func register(m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[m.name()]; ok {
+		panic(fmt.Sprintf("métrica %s registrada dos veces", m.name()))
+	}
+	registry[m.name()] = m
+}
+
+// Handler sirve todas las métricas registradas
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write([]byte(Text()))
+	})
+}
+
+// Text devuelve todas las métricas registradas, ordenadas por nombre
+func Text() string {
+	registryMu.Lock()
+	metrics := make([]metric, 0, len(registry))
+	for _, m := range registry {
+		metrics = append(metrics, m)
+	}
+	registryMu.Unlock()
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name() < metrics[j].name() })
+
+	var b strings.Builder
+	for _, m := range metrics {
+		m.write(&b)
+	}
+	return b.String()
+}
+
+// family guarda los valores de una familia indexados por los valores de sus etiquetas
+type family[T any] struct {
+	metricName string
+	help       string
+	kind       string
+	labels     []string
+
+	mu     sync.Mutex
+	series map[string]*T
+	values map[string][]string
+}
+
+func newFamily[T any](name, help, kind string, labels []string) family[T] {
+	return family[T]{
+		metricName: name, help: help, kind: kind, labels: labels,
+		series: make(map[string]*T), values: make(map[string][]string),
+	}
+}
+
+func (f *family[T]) name() string { return f.metricName }
+
+// get devuelve la serie de los valores de etiqueta indicados, creándola con create si no existe
+func (f *family[T]) get(labelValues []string, create func() *T) *T {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("métrica %s: se esperaban %d etiquetas y se recibieron %d",
+			f.metricName, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = create()
+		f.series[key] = s
+		f.values[key] = append([]string(nil), labelValues...)
+	}
+	return s
+}
+
+// each recorre las series ordenadas por sus etiquetas
+func (f *family[T]) each(fn func(labels string, s *T)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fn(f.formatLabels(f.values[key], ""), f.series[key])
+	}
+}
+
+// header escribe las líneas HELP y TYPE
+func (f *family[T]) header(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", f.metricName, f.help, f.metricName, f.kind)
+}
+
+// formatLabels construye {nombre="valor",...}; extra es un par adicional ya formateado (le="...")
+func (f *family[T]) formatLabels(values []string, extra string) string {
+	if len(values) == 0 && extra == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(values)+1)
+	for i, value := range values {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, f.labels[i], labelEscaper.Replace(value)))
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter es un contador que solo crece
+type Counter struct {
+	family[float64]
+}
+
+// NewCounter crea y registra un contador con las etiquetas indicadas. Sin etiquetas se
+// expone desde el principio con valor 0.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newFamily[float64](name, help, "counter", labels)}
+	if len(labels) == 0 {
+		c.Add(0)
+	}
+	register(c)
+	return c
+}
+
+// Inc suma 1 a la serie de las etiquetas indicadas
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add suma v (>= 0) a la serie de las etiquetas indicadas
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	s := c.get(labelValues, func() *float64 { return new(float64) })
+	c.mu.Lock()
+	*s += v
+	c.mu.Unlock()
+}
+
+func (c *Counter) write(b *strings.Builder) {
+	c.header(b)
+	c.each(func(labels string, v *float64) {
+		fmt.Fprintf(b, "%s%s %s\n", c.metricName, labels, formatValue(*v))
+	})
+}
+
+// Gauge es un valor que sube y baja
+type Gauge struct {
+	family[float64]
+}
+
+// NewGauge crea y registra un indicador con las etiquetas indicadas. Sin etiquetas se
+// expone desde el principio con valor 0.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{newFamily[float64](name, help, "gauge", labels)}
+	if len(labels) == 0 {
+		g.Add(0)
+	}
+	register(g)
+	return g
+}
+
+// Add suma v (que puede ser negativo) a la serie de las etiquetas indicadas
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	s := g.get(labelValues, func() *float64 { return new(float64) })
+	g.mu.Lock()
+	*s += v
+	g.mu.Unlock()
+}
+
+// Inc suma 1 a la serie de las etiquetas indicadas
+func (g *Gauge) Inc(labelValues ...string) { g.Add(1, labelValues...) }
+
+// Dec resta 1 a la serie de las etiquetas indicadas
+func (g *Gauge) Dec(labelValues ...string) { g.Add(-1, labelValues...) }
+
+// Set fija el valor de la serie de las etiquetas indicadas
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	s := g.get(labelValues, func() *float64 { return new(float64) })
+	g.mu.Lock()
+	*s = v
+	g.mu.Unlock()
+}
+
+func (g *Gauge) write(b *strings.Builder) {
+	g.header(b)
+	g.each(func(labels string, v *float64) {
+		fmt.Fprintf(b, "%s%s %s\n", g.metricName, labels, formatValue(*v))
+	})
+}
+
+// histogramSeries son los contadores de una serie de un histograma
+type histogramSeries struct {
+	counts []uint64 // Observaciones <= cada límite, sin acumular
+	count  uint64
+	sum    float64
+}
+
+// Histogram cuenta observaciones por intervalos
+type Histogram struct {
+	family[histogramSeries]
+	buckets []float64
+}
+
+// NewHistogram crea y registra un histograma con los límites (crecientes) y las etiquetas indicadas
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{family: newFamily[histogramSeries](name, help, "histogram", labels), buckets: buckets}
+	register(h)
+	return h
+}
+
+// Observe añade una observación a la serie de las etiquetas indicadas
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	s := h.get(labelValues, func() *histogramSeries {
+		return &histogramSeries{counts: make([]uint64, len(h.buckets))}
+	})
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	if i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+	h.mu.Unlock()
+}
+
+func (h *Histogram) write(b *strings.Builder) {
+	h.header(b)
+	h.mu.Lock()
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	h.mu.Unlock()
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		h.mu.Lock()
+		values, s := h.values[key], h.series[key]
+		counts := append([]uint64(nil), s.counts...)
+		count, sum := s.count, s.sum
+		h.mu.Unlock()
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += counts[i]
+			fmt.Fprintf(b, "%s_bucket%s %d\n", h.metricName,
+				h.formatLabels(values, fmt.Sprintf(`le="%s"`, formatValue(bound))), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", h.metricName, h.formatLabels(values, `le="+Inf"`), count)
+		labels := h.formatLabels(values, "")
+		fmt.Fprintf(b, "%s_sum%s %s\n", h.metricName, labels, formatValue(sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", h.metricName, labels, count)
+	}
+}
+
+// formatValue escribe un número como lo espera Prometheus
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
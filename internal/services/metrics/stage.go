@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// stageDuration es la duración de cada etapa del procesamiento de un documento
+var stageDuration = NewHistogram("go_ocr_stage_duration_seconds",
+	"Duración de cada etapa del procesamiento (download, extract, ocr, ai, signatures, forensics, inspect).",
+	DurationBuckets, "stage", "outcome")
+
+// ObserveStage registra la duración de una etapa que empezó en start y terminó con err
+func ObserveStage(stage string, start time.Time, err error) {
+	stageDuration.Observe(time.Since(start).Seconds(), stage, Outcome(err))
+}
+
+// Outcome clasifica el resultado de una operación para las etiquetas: success, timeout,
+// canceled o error
+func Outcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return "error"
+}
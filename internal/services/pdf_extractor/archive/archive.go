@@ -0,0 +1,168 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/pdf_extractor/doctype"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Limits acota los recursos que puede consumir el desempaquetado de un archivo
+type Limits struct {
+	MaxFiles     int   // Número máximo de documentos extraídos
+	MaxFileSize  int64 // Tamaño máximo descomprimido por documento
+	MaxTotalSize int64 // Tamaño máximo descomprimido en total
+	MaxDepth     int   // Profundidad máxima de archivos anidados
+	MaxRatio     int64 // Ratio máximo de compresión por entrada ZIP
+}
+
+// DefaultLimits son los límites usados por la API de conversión
+var DefaultLimits = Limits{
+	MaxFiles:     100,
+	MaxFileSize:  50 << 20,
+	MaxTotalSize: 200 << 20,
+	MaxDepth:     3,
+	MaxRatio:     100,
+}
+
+// ErrLimitExceeded indica que el archivo supera alguno de los límites configurados
+var ErrLimitExceeded = errors.New("el archivo supera los límites permitidos")
+
+// Entry es un documento (PDF o imagen) extraído de un archivo
+type Entry struct {
+	Name string       // Nombre original dentro del archivo, con el prefijo de los archivos anidados
+	Path string       // Ruta del archivo temporal extraído
+	Type doctype.Type // Formato detectado
+}
+
+// unpacker mantiene el estado compartido entre niveles de anidamiento
+type unpacker struct {
+	dir     string
+	limits  Limits
+	entries []Entry
+	written int64
+	counter int
+	log     *logger.Logger
+}
+
+// Unpack extrae recursivamente los PDF e imágenes contenidos en un ZIP o EML dentro de dir.
+// Los nombres de las entradas nunca se usan como rutas en disco.
+func Unpack(ctx context.Context, archivePath, dir string, limits Limits) ([]Entry, error) {
+	docType, err := doctype.Detect(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &unpacker{dir: dir, limits: limits, log: logger.FromContext(ctx)}
+	if err := u.unpack(archivePath, docType, "", 0); err != nil {
+		return nil, err
+	}
+
+	u.log.Info("Archivo desempaquetado: %d documentos, %d bytes", len(u.entries), u.written)
+	return u.entries, nil
+}
+
+func (u *unpacker) unpack(archivePath string, docType doctype.Type, prefix string, depth int) error {
+	if depth >= u.limits.MaxDepth {
+		return fmt.Errorf("%w: profundidad de anidamiento mayor que %d", ErrLimitExceeded, u.limits.MaxDepth)
+	}
+
+	switch docType {
+	case doctype.ZIP:
+		return u.unpackZIP(archivePath, prefix, depth)
+	case doctype.EML:
+		return u.unpackEML(archivePath, prefix, depth)
+	default:
+		return fmt.Errorf("formato de archivo no soportado: %s", docType)
+	}
+}
+
+// add guarda el contenido de una entrada y la registra o la desempaqueta si es otro archivo
+func (u *unpacker) add(name string, r io.Reader, prefix string, depth int) error {
+	displayName, ok := sanitizeName(name)
+	if !ok {
+		u.log.Warning("Entrada ignorada por nombre inseguro: %q", name)
+		return nil
+	}
+	displayName = prefix + displayName
+
+	u.counter++
+	target := filepath.Join(u.dir, fmt.Sprintf("entry_%d", u.counter))
+	n, err := u.write(target, r)
+	if err != nil {
+		return fmt.Errorf("error al extraer %s: %w", displayName, err)
+	}
+
+	docType, err := doctype.Detect(target)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case docType.IsArchive():
+		u.log.Debug("Archivo anidado %s (%s, %d bytes)", displayName, docType, n)
+		return u.unpack(target, docType, displayName+"/", depth+1)
+	case docType == doctype.PDF || docType.IsImage():
+		if len(u.entries) >= u.limits.MaxFiles {
+			return fmt.Errorf("%w: más de %d documentos", ErrLimitExceeded, u.limits.MaxFiles)
+		}
+		finalPath := target + docType.Extension()
+		if err := os.Rename(target, finalPath); err != nil {
+			return fmt.Errorf("error al renombrar %s: %v", displayName, err)
+		}
+		u.entries = append(u.entries, Entry{Name: displayName, Path: finalPath, Type: docType})
+		u.log.Debug("Documento extraído %s (%s, %d bytes)", displayName, docType, n)
+	default:
+		u.log.Debug("Entrada ignorada por formato no soportado: %s", displayName)
+		os.Remove(target)
+	}
+
+	return nil
+}
+
+// write copia r en path respetando los límites de tamaño por archivo y totales
+func (u *unpacker) write(path string, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	limit := u.limits.MaxFileSize
+	if remaining := u.limits.MaxTotalSize - u.written; remaining < limit {
+		limit = remaining
+	}
+
+	// Leer un byte más que el límite para detectar si se supera
+	n, err := io.Copy(f, io.LimitReader(r, limit+1))
+	u.written += n
+	if err != nil {
+		return n, err
+	}
+	if n > limit {
+		return n, fmt.Errorf("%w: tamaño descomprimido excesivo", ErrLimitExceeded)
+	}
+
+	return n, nil
+}
+
+// sanitizeName normaliza el nombre de una entrada y rechaza rutas absolutas o que escapen del archivo
+func sanitizeName(name string) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if name == "" || path.IsAbs(name) || strings.Contains(name, "\x00") || filepath.VolumeName(name) != "" {
+		return "", false
+	}
+
+	cleaned := path.Clean(name)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false
+	}
+
+	return cleaned, true
+}
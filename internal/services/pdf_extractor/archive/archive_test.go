@@ -0,0 +1,247 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pdf es el contenido de un documento PDF de prueba
+var pdf = []byte("%PDF-1.4\n%%EOF\n")
+
+// file es una entrada de un ZIP de prueba
+type file struct {
+	name   string
+	data   []byte
+	stored bool // Sin comprimir
+}
+
+// buildZIP devuelve un ZIP con files
+func buildZIP(t *testing.T, files ...file) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, f := range files {
+		method := zip.Deflate
+		if f.stored {
+			method = zip.Store
+		}
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: f.name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// unpack escribe data en un directorio temporal y lo desempaqueta en otro dentro de él
+func unpack(t *testing.T, data []byte, limits Limits) ([]Entry, string, error) {
+	t.Helper()
+	root := t.TempDir()
+	archivePath := filepath.Join(root, "archivo")
+	if err := os.WriteFile(archivePath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "extraido")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Unpack(context.Background(), archivePath, dir, limits)
+	return entries, dir, err
+}
+
+// names devuelve los nombres de las entradas
+func names(entries []Entry) []string {
+	var result []string
+	for _, entry := range entries {
+		result = append(result, entry.Name)
+	}
+	return result
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"nomina.pdf", "nomina.pdf", true},
+		{"2024/enero/nomina.pdf", "2024/enero/nomina.pdf", true},
+		{"2024/./enero//nomina.pdf", "2024/enero/nomina.pdf", true},
+		{"2024/../nomina.pdf", "nomina.pdf", true},
+		{"2024\\enero\\nomina.pdf", "2024/enero/nomina.pdf", true},
+		{"../nomina.pdf", "", false},
+		{"2024/../../nomina.pdf", "", false},
+		{"..\\..\\nomina.pdf", "", false},
+		{"/etc/passwd", "", false},
+		{"\\windows\\nomina.pdf", "", false},
+		{"nomina\x00.pdf", "", false},
+		{"..", "", false},
+		{".", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.name), func(t *testing.T) {
+			got, ok := sanitizeName(tt.name)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("sanitizeName(%q) = %q, %t; se esperaba %q, %t", tt.name, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestUnpackIgnoresPathTraversal(t *testing.T) {
+	data := buildZIP(t,
+		file{name: "../../fuera.pdf", data: pdf},
+		file{name: "/tmp/absoluta.pdf", data: pdf},
+		file{name: "..\\fuera2.pdf", data: pdf},
+		file{name: "nominas/enero.pdf", data: pdf},
+	)
+	entries, dir, err := unpack(t, data, DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := names(entries); len(got) != 1 || got[0] != "nominas/enero.pdf" {
+		t.Fatalf("entradas %v, se esperaba solo nominas/enero.pdf", got)
+	}
+	// Los nombres de las entradas no se usan como rutas: todo queda dentro de dir
+	if filepath.Dir(entries[0].Path) != dir {
+		t.Errorf("entrada extraída fuera del directorio: %s", entries[0].Path)
+	}
+	for _, outside := range []string{"fuera.pdf", "fuera2.pdf"} {
+		for _, d := range []string{filepath.Dir(dir), filepath.Dir(filepath.Dir(dir))} {
+			if _, err := os.Stat(filepath.Join(d, outside)); err == nil {
+				t.Errorf("se escribió %s fuera del directorio de extracción", filepath.Join(d, outside))
+			}
+		}
+	}
+}
+
+func TestUnpackLimits(t *testing.T) {
+	// Datos poco comprimibles para no disparar el límite de ratio
+	random := make([]byte, 4096)
+	for i := range random {
+		random[i] = byte(i*7919 + i/13)
+	}
+	zeros := make([]byte, 1<<20)
+
+	nested := func(depth int) []byte {
+		data := buildZIP(t, file{name: "nomina.pdf", data: pdf})
+		for i := 1; i < depth; i++ {
+			data = buildZIP(t, file{name: fmt.Sprintf("nivel%d.zip", i), data: data, stored: true})
+		}
+		return data
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		limits func(*Limits)
+		err    string // "" si no se espera error
+		want   []string
+	}{
+		{
+			name:   "documento mayor que el máximo por archivo",
+			data:   buildZIP(t, file{name: "grande.pdf", data: append(pdf, random...), stored: true}),
+			limits: func(l *Limits) { l.MaxFileSize = 1024 },
+			err:    "ocupa",
+		},
+		{
+			name: "suma mayor que el máximo total",
+			data: buildZIP(t,
+				file{name: "a.pdf", data: append(pdf, random[:3000]...), stored: true},
+				file{name: "b.pdf", data: append(pdf, random[:3000]...), stored: true}),
+			limits: func(l *Limits) { l.MaxTotalSize = 5000 },
+			err:    "tamaño descomprimido excesivo",
+		},
+		{
+			name: "zip bomb",
+			data: buildZIP(t, file{name: "bomba.pdf", data: append(pdf, zeros...)}),
+			err:  "ratio de compresión sospechoso",
+		},
+		{
+			name:   "demasiados documentos",
+			data:   buildZIP(t, file{name: "a.pdf", data: pdf}, file{name: "b.pdf", data: pdf}, file{name: "c.pdf", data: pdf}),
+			limits: func(l *Limits) { l.MaxFiles = 2 },
+			err:    "más de 2 documentos",
+		},
+		{
+			name: "anidamiento dentro del límite",
+			data: nested(DefaultLimits.MaxDepth),
+			want: []string{"nivel2.zip/nivel1.zip/nomina.pdf"},
+		},
+		{
+			name: "anidamiento más allá del límite",
+			data: nested(DefaultLimits.MaxDepth + 1),
+			err:  "profundidad de anidamiento",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits := DefaultLimits
+			if tt.limits != nil {
+				tt.limits(&limits)
+			}
+			entries, _, err := unpack(t, tt.data, limits)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := names(entries); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+					t.Errorf("entradas %v, se esperaban %v", got, tt.want)
+				}
+				return
+			}
+			if !errors.Is(err, ErrLimitExceeded) || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error %v, se esperaba ErrLimitExceeded con %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestUnpackEMLIgnoresPathTraversal(t *testing.T) {
+	eml := strings.Join([]string{
+		"From: rrhh@example.com",
+		"Subject: Nominas",
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/mixed; boundary="limite"`,
+		"",
+		"--limite",
+		"Content-Type: text/plain",
+		"",
+		"Adjuntamos las nóminas.",
+		"--limite",
+		"Content-Type: application/pdf",
+		`Content-Disposition: attachment; filename="../../fuera.pdf"`,
+		"",
+		string(pdf),
+		"--limite",
+		"Content-Type: application/pdf",
+		`Content-Disposition: attachment; filename="enero.pdf"`,
+		"",
+		string(pdf),
+		"--limite--",
+		"",
+	}, "\r\n")
+
+	entries, _, err := unpack(t, []byte(eml), DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(entries); len(got) != 1 || got[0] != "enero.pdf" {
+		t.Errorf("entradas %v, se esperaba solo enero.pdf", got)
+	}
+}
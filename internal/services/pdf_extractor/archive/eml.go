@@ -0,0 +1,99 @@
+package archive
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+var wordDecoder = new(mime.WordDecoder)
+
+func (u *unpacker) unpackEML(archivePath, prefix string, depth int) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("error al abrir EML: %v", err)
+	}
+	defer f.Close()
+
+	msg, err := mail.ReadMessage(f)
+	if err != nil {
+		return fmt.Errorf("error al leer EML: %v", err)
+	}
+
+	return u.walkPart(textproto.MIMEHeader(msg.Header), msg.Body, prefix, depth, 0)
+}
+
+// walkPart recorre una parte MIME y extrae los adjuntos que contiene
+func (u *unpacker) walkPart(header textproto.MIMEHeader, body io.Reader, prefix string, depth, level int) error {
+	if level > 10 {
+		return fmt.Errorf("%w: demasiados niveles MIME", ErrLimitExceeded)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("error al leer parte MIME: %v", err)
+			}
+			err = u.walkPart(part.Header, part, prefix, depth, level+1)
+			part.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	name := attachmentName(header, params)
+	if mediaType == "message/rfc822" && name == "" {
+		name = "mensaje.eml"
+	}
+	if name == "" {
+		return nil
+	}
+
+	return u.add(name, decodeBody(header, body), prefix, depth)
+}
+
+// attachmentName obtiene el nombre del adjunto de Content-Disposition o Content-Type
+func attachmentName(header textproto.MIMEHeader, typeParams map[string]string) string {
+	name := ""
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	if name == "" {
+		name = typeParams["name"]
+	}
+	if decoded, err := wordDecoder.DecodeHeader(name); err == nil {
+		name = decoded
+	}
+	return name
+}
+
+// decodeBody aplica la Content-Transfer-Encoding de la parte
+func decodeBody(header textproto.MIMEHeader, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		// El decodificador ignora los saltos de línea del base64 de los correos
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
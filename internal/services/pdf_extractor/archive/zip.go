@@ -0,0 +1,44 @@
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+)
+
+func (u *unpacker) unpackZIP(archivePath, prefix string, depth int) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("error al abrir ZIP: %v", err)
+	}
+	defer reader.Close()
+
+	if len(reader.File) > u.limits.MaxFiles*10 {
+		return fmt.Errorf("%w: el ZIP contiene %d entradas", ErrLimitExceeded, len(reader.File))
+	}
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		// Rechazar entradas con un ratio de compresión típico de una zip bomb
+		if file.CompressedSize64 > 0 && file.UncompressedSize64/file.CompressedSize64 > uint64(u.limits.MaxRatio) {
+			return fmt.Errorf("%w: ratio de compresión sospechoso en %s", ErrLimitExceeded, file.Name)
+		}
+		if file.UncompressedSize64 > uint64(u.limits.MaxFileSize) {
+			return fmt.Errorf("%w: %s ocupa %d bytes descomprimido", ErrLimitExceeded, file.Name, file.UncompressedSize64)
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("error al abrir %s: %v", file.Name, err)
+		}
+		err = u.add(file.Name, rc, prefix, depth)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
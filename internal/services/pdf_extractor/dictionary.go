@@ -0,0 +1,238 @@
+package pdf_extractor
+
+// vocabulary son las palabras frecuentes y los términos de nómina de un idioma, en
+// minúsculas y sin tildes. No pretende ser exhaustivo: solo distinguir texto real de basura.
+type vocabulary struct {
+	words    map[string]bool
+	keywords []string // Términos presentes en casi cualquier nómina del idioma
+}
+
+// defaultLanguage es el idioma con el que se puntúa si no se configura ninguno conocido
+const defaultLanguage = "spa"
+
+// vocabularies contiene el vocabulario de cada idioma soportado, por su código de Tesseract
+var vocabularies = map[string]vocabulary{
+	"spa": {
+		words: toSet(
+			// Palabras funcionales
+			"de", "la", "el", "en", "y", "a", "los", "las", "del", "se", "por", "con", "para",
+			"un", "una", "su", "al", "lo", "como", "mas", "o", "pero", "sus", "le", "ya", "si",
+			"sin", "sobre", "este", "esta", "entre", "cuando", "todo", "todos", "ha", "son",
+			"dos", "tambien", "fue", "era", "muy", "hasta", "desde", "mes", "ano", "anos", "dia",
+			"dias", "que", "no", "es", "total", "otros", "otras", "otro", "otra", "segun",
+			"n", "no", "num", "numero", "fecha", "hora", "horas",
+
+			// Cabecera de la nómina
+			"empresa", "domicilio", "cif", "nif", "dni", "nie", "ccc", "codigo", "cuenta",
+			"cotizacion", "trabajador", "trabajadora", "nombre", "apellidos", "categoria",
+			"grupo", "profesional", "puesto", "antiguedad", "afiliacion", "seguridad", "social",
+			"periodo", "liquidacion", "nomina", "recibo", "individual", "justificativo",
+			"pago", "salarios", "tot", "mensual", "contrato", "indefinido", "temporal",
+			"jornada", "completa", "parcial", "centro", "trabajo", "tarifa", "oficial",
+			"administrativo", "administrativa", "auxiliar", "tecnico", "tecnica", "peon",
+
+			// Devengos
+			"devengos", "devengo", "devengado", "devengada", "devengadas", "percepciones",
+			"salariales", "salarial", "extrasalariales", "extrasalarial", "salario", "base",
+			"plus", "pluses", "complemento", "complementos", "convenio", "transporte",
+			"productividad", "incentivos", "incentivo", "horas", "extraordinarias",
+			"extraordinaria", "extra", "pagas", "paga", "prorrata", "prorrateo", "vacaciones",
+			"dietas", "kilometraje", "gastos", "locomocion", "manutencion", "nocturnidad",
+			"festivos", "festivo", "turnicidad", "peligrosidad", "toxicidad", "penosidad",
+			"personal", "absorbible", "mejora", "voluntaria", "comision", "comisiones",
+			"bonus", "indemnizacion", "suplidos", "especie", "retribucion", "retribuciones",
+			"cuantia", "precio", "unidades", "importe", "importes", "concepto", "conceptos",
+
+			// Deducciones
+			"deducciones", "deduccion", "deducir", "aportaciones", "aportacion", "irpf",
+			"retencion", "retenciones", "impuesto", "renta", "personas", "fisicas",
+			"contingencias", "comunes", "desempleo", "formacion", "horas", "mei",
+			"anticipos", "anticipo", "embargo", "embargos", "cuota", "sindical", "valor",
+			"productos", "recibidos", "tipo", "porcentaje",
+
+			// Totales y pie
+			"liquido", "percibir", "neto", "bruto", "suma", "firma", "sello", "recibi",
+			"determinacion", "bases", "accidentes", "enfermedades", "profesionales", "fogasa",
+			"aportacion", "empresarial", "coste", "cargo", "iban", "banco", "transferencia",
+			"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto",
+			"septiembre", "octubre", "noviembre", "diciembre", "euros", "eur",
+		),
+		keywords: []string{
+			"devengado", "devengos", "deducciones", "liquido", "nomina", "salario",
+			"irpf", "seguridad social", "cotizacion", "contingencias", "percibir",
+			"trabajador", "empresa", "periodo", "categoria", "antiguedad",
+		},
+	},
+
+	"cat": {
+		words: toSet(
+			"de", "la", "el", "els", "les", "en", "i", "a", "al", "als", "del", "dels", "per",
+			"amb", "que", "un", "una", "es", "se", "no", "com", "pero", "mes", "seu", "seva",
+			"aquest", "aquesta", "sobre", "entre", "fins", "des", "tot", "tots", "total",
+			"any", "dia", "dies", "data", "hora", "hores", "num", "numero",
+
+			"empresa", "domicili", "cif", "nif", "dni", "nie", "ccc", "codi", "compte",
+			"cotitzacio", "treballador", "treballadora", "nom", "cognoms", "categoria", "grup",
+			"professional", "lloc", "antiguitat", "afiliacio", "seguretat", "social", "periode",
+			"liquidacio", "nomina", "rebut", "individual", "justificatiu", "pagament", "salaris",
+			"mensual", "contracte", "indefinit", "jornada", "completa", "parcial", "centre", "treball",
+
+			"meritacions", "meritat", "meritats", "meritada", "percepcions", "salarials",
+			"extrasalarials", "salari", "sou", "base", "plus", "complement", "complements",
+			"conveni", "transport", "hores", "extraordinaries", "pagues", "paga", "prorrata",
+			"vacances", "dietes", "quilometratge", "despeses", "nocturnitat", "festius",
+			"millora", "voluntaria", "comissio", "indemnitzacio", "retribucio", "quantia",
+			"preu", "unitats", "import", "imports", "concepte", "conceptes",
+
+			"deduccions", "deduccio", "aportacions", "aportacio", "irpf", "retencio", "impost",
+			"contingencies", "comunes", "desocupacio", "atur", "formacio", "bestreta",
+			"embargament", "quota", "sindical", "tipus", "percentatge",
+
+			"liquid", "percebre", "net", "brut", "suma", "signatura", "segell", "rebut",
+			"determinacio", "bases", "accidents", "malalties", "professionals", "fogasa",
+			"empresarial", "cost", "carrec", "iban", "banc", "transferencia",
+			"gener", "febrer", "marc", "abril", "maig", "juny", "juliol", "agost",
+			"setembre", "octubre", "novembre", "desembre", "euros", "eur",
+		),
+		keywords: []string{
+			"meritat", "meritacions", "deduccions", "liquid", "nomina", "salari",
+			"irpf", "seguretat social", "cotitzacio", "contingencies", "percebre",
+			"treballador", "empresa", "periode", "categoria", "antiguitat",
+		},
+	},
+
+	"glg": {
+		words: toSet(
+			"de", "o", "a", "os", "as", "do", "da", "dos", "das", "e", "en", "no", "na", "nos",
+			"nas", "por", "con", "para", "un", "unha", "que", "se", "ao", "como", "pero", "mais",
+			"seu", "sua", "este", "esta", "sobre", "entre", "ata", "dende", "desde", "todo",
+			"total", "mes", "ano", "anos", "dia", "dias", "data", "hora", "horas", "num", "numero",
+
+			"empresa", "enderezo", "domicilio", "cif", "nif", "dni", "nie", "ccc", "codigo",
+			"conta", "cotizacion", "traballador", "traballadora", "nome", "apelidos", "categoria",
+			"grupo", "profesional", "posto", "antiguidade", "afiliacion", "seguridade", "social",
+			"periodo", "liquidacion", "nomina", "recibo", "individual", "xustificativo", "pagamento",
+			"pago", "salarios", "mensual", "contrato", "indefinido", "xornada", "completa",
+			"parcial", "centro", "traballo",
+
+			"devengos", "devengo", "devengado", "devengada", "percepcions", "salariais",
+			"extrasalariais", "salario", "soldo", "base", "plus", "complemento", "complementos",
+			"convenio", "transporte", "extraordinarias", "pagas", "paga", "prorrata",
+			"vacacions", "axudas", "custo", "quilometraxe", "gastos", "nocturnidade", "festivos",
+			"mellora", "voluntaria", "comision", "indemnizacion", "retribucion", "contia",
+			"prezo", "unidades", "importe", "importes", "concepto", "conceptos",
+
+			"deducions", "deducion", "achegas", "achega", "aportacion", "irpf", "retencion",
+			"imposto", "continxencias", "comuns", "desemprego", "formacion", "anticipo",
+			"embargo", "cota", "sindical", "tipo", "porcentaxe",
+
+			"liquido", "percibir", "neto", "bruto", "suma", "sinatura", "selo",
+			"determinacion", "bases", "accidentes", "enfermidades", "profesionais", "fogasa",
+			"empresarial", "cargo", "iban", "banco", "transferencia",
+			"xaneiro", "febreiro", "marzo", "abril", "maio", "xuno", "xullo", "agosto",
+			"setembro", "outubro", "novembro", "decembro", "euros", "eur",
+		),
+		keywords: []string{
+			"devengado", "devengos", "deducions", "liquido", "nomina", "salario",
+			"irpf", "seguridade social", "cotizacion", "continxencias", "percibir",
+			"traballador", "empresa", "periodo", "categoria", "antiguidade",
+		},
+	},
+
+	"por": {
+		words: toSet(
+			"de", "o", "a", "os", "as", "do", "da", "dos", "das", "e", "em", "no", "na", "nos",
+			"nas", "por", "com", "para", "um", "uma", "que", "se", "ao", "como", "mas", "nao",
+			"seu", "sua", "este", "esta", "sobre", "entre", "ate", "desde", "todo", "total",
+			"mes", "ano", "anos", "dia", "dias", "data", "hora", "horas", "num", "numero",
+
+			"empresa", "morada", "sede", "nif", "niss", "nipc", "contribuinte", "codigo", "conta",
+			"contribuicao", "trabalhador", "trabalhadora", "nome", "categoria", "profissional",
+			"funcao", "antiguidade", "seguranca", "social", "periodo", "processamento", "recibo",
+			"vencimento", "vencimentos", "pagamento", "mensal", "contrato", "termo", "sem",
+			"certo", "horario", "completo", "parcial", "estabelecimento",
+
+			"remuneracao", "remuneracoes", "abonos", "salario", "ordenado", "base", "subsidio",
+			"subsidios", "alimentacao", "ferias", "natal", "diuturnidades", "trabalho",
+			"suplementar", "extraordinario", "isencao", "horario", "ajudas", "custo", "deslocacao",
+			"quilometros", "premio", "premios", "comissoes", "indemnizacao", "valor",
+			"quantidade", "unitario", "importancia", "descricao",
+
+			"descontos", "desconto", "irs", "retencao", "fonte", "taxa", "quotizacao",
+			"sindicato", "sindical", "seguro", "adiantamento", "penhora", "fundo", "compensacao",
+
+			"liquido", "iliquido", "receber", "bruto", "soma", "assinatura", "acidentes",
+			"trabalho", "encargos", "entidade", "patronal", "iban", "banco", "transferencia",
+			"janeiro", "fevereiro", "marco", "abril", "maio", "junho", "julho", "agosto",
+			"setembro", "outubro", "novembro", "dezembro", "euros", "eur",
+		),
+		keywords: []string{
+			"vencimento", "remuneracao", "descontos", "liquido", "recibo", "salario",
+			"retencao", "seguranca social", "contribuicao", "subsidio", "receber",
+			"trabalhador", "empresa", "periodo", "categoria", "antiguidade",
+		},
+	},
+
+	"eus": {
+		words: toSet(
+			"eta", "da", "du", "dira", "ditu", "ez", "bai", "bat", "ere", "baina", "edo", "bere",
+			"hau", "hori", "honen", "zen", "dago", "egin", "behar", "arte", "guztira", "guztiak",
+			"urtea", "urteak", "hilabetea", "hileko", "eguna", "egunak", "data", "ordua", "orduak",
+			"zenbakia", "zk",
+
+			"enpresa", "enpresaren", "helbidea", "ifk", "nan", "kodea", "kontua", "kotizazioa",
+			"kotizazio", "langilea", "langilearen", "izena", "abizenak", "kategoria", "taldea",
+			"lanbide", "profesionala", "lanpostua", "antzinatasuna", "afiliazioa", "gizarte",
+			"segurantza", "segurantzako", "aldia", "likidazioa", "nomina", "ordainagiria",
+			"banakako", "ordainketa", "soldatak", "kontratua", "mugagabea", "aldi",
+			"baterakoa", "lanaldia", "osoa", "partziala", "lantokia",
+
+			"sortzapenak", "sortzapena", "sortutakoa", "sortua", "soldata", "soldatazkoak",
+			"oinarria", "oinarrizko", "plusa", "osagarria", "osagarriak", "hitzarmena",
+			"garraioa", "aparteko", "ordainsaria", "ordainsariak", "ordainsari", "hainbanatzea",
+			"oporrak", "dietak", "gastuak", "gaueko", "jaiegunak", "hobekuntza", "borondatezkoa",
+			"kalte", "ordaina", "zenbatekoa", "prezioa", "unitateak", "kopurua", "kontzeptua",
+			"kontzeptuak",
+
+			"kenkariak", "kenkaria", "ekarpenak", "ekarpena", "pfez", "atxikipena", "zerga",
+			"kontingentzia", "kontingentziak", "arruntak", "langabezia", "prestakuntza",
+			"aurrerakina", "bahitura", "kuota", "sindikala", "tasa", "ehunekoa",
+
+			"likidoa", "jasotzeko", "garbia", "gordina", "batura", "sinadura", "zigilua",
+			"zehaztea", "oinarriak", "istripuak", "gaixotasunak", "enpresaburua", "kostua",
+			"iban", "bankua", "transferentzia",
+			"urtarrila", "otsaila", "martxoa", "apirila", "maiatza", "ekaina", "uztaila",
+			"abuztua", "iraila", "urria", "azaroa", "abendua", "euro", "eur",
+		),
+		keywords: []string{
+			"sortzapenak", "kenkariak", "likidoa", "nomina", "soldata",
+			"pfez", "gizarte segurantza", "kotizazio", "kontingentzia", "jasotzeko",
+			"langilea", "enpresa", "aldia", "kategoria", "antzinatasuna", "ordainsari",
+		},
+	},
+}
+
+// vocabulariesFor devuelve el vocabulario de los idiomas conocidos de languages o, si no
+// hay ninguno, el del idioma por defecto
+func vocabulariesFor(languages []string) []vocabulary {
+	var result []vocabulary
+	seen := make(map[string]bool)
+	for _, lang := range languages {
+		if v, ok := vocabularies[lang]; ok && !seen[lang] {
+			seen[lang] = true
+			result = append(result, v)
+		}
+	}
+	if len(result) == 0 {
+		result = append(result, vocabularies[defaultLanguage])
+	}
+	return result
+}
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
@@ -0,0 +1,110 @@
+package doctype
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Type es el formato de un documento detectado por sus magic bytes
+type Type string
+
+const (
+	Unknown Type = ""
+	PDF     Type = "pdf"
+	JPEG    Type = "jpeg"
+	PNG     Type = "png"
+	TIFF    Type = "tiff"
+	ZIP     Type = "zip"
+	EML     Type = "eml"
+)
+
+// headerSize es la cantidad de bytes leídos para detectar el formato
+const headerSize = 512
+
+// emlHeaders son cabeceras con las que suele empezar un mensaje de correo (RFC 5322)
+var emlHeaders = []string{
+	"received:", "return-path:", "delivered-to:", "from:", "to:", "date:",
+	"subject:", "message-id:", "mime-version:", "x-",
+}
+
+// Detect lee la cabecera del archivo y determina su formato
+func Detect(path string) (Type, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Unknown, fmt.Errorf("error al abrir archivo: %v", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return Unknown, fmt.Errorf("error al leer cabecera: %v", err)
+	}
+
+	return DetectBytes(header[:n]), nil
+}
+
+// DetectBytes determina el formato a partir de los primeros bytes del contenido
+func DetectBytes(header []byte) Type {
+	switch {
+	case bytes.HasPrefix(header, []byte("%PDF-")):
+		return PDF
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8, 0xFF}):
+		return JPEG
+	case bytes.HasPrefix(header, []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}):
+		return PNG
+	case bytes.HasPrefix(header, []byte{'I', 'I', 42, 0}),
+		bytes.HasPrefix(header, []byte{'M', 'M', 0, 42}):
+		return TIFF
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")),
+		bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return ZIP
+	case isEML(header):
+		return EML
+	default:
+		return Unknown
+	}
+}
+
+// isEML comprueba si el contenido empieza por una cabecera de correo
+func isEML(header []byte) bool {
+	lower := bytes.ToLower(header)
+	for _, h := range emlHeaders {
+		if bytes.HasPrefix(lower, []byte(h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsImage indica si el formato es una imagen que puede ir directamente a Tesseract
+func (t Type) IsImage() bool {
+	return t == JPEG || t == PNG || t == TIFF
+}
+
+// IsArchive indica si el formato es un contenedor de otros documentos
+func (t Type) IsArchive() bool {
+	return t == ZIP || t == EML
+}
+
+// Extension devuelve la extensión de archivo asociada al formato
+func (t Type) Extension() string {
+	switch t {
+	case PDF:
+		return ".pdf"
+	case JPEG:
+		return ".jpg"
+	case PNG:
+		return ".png"
+	case TIFF:
+		return ".tif"
+	case ZIP:
+		return ".zip"
+	case EML:
+		return ".eml"
+	default:
+		return ""
+	}
+}
@@ -1,74 +1,146 @@
 package downloader
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/metrics"
+	"go_ocr/internal/services/pdf_extractor/doctype"
+	"go_ocr/internal/services/tracing"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"os"
-	"strings"
 	"time"
 )
 
+// DefaultMaxSize es el tamaño máximo por defecto de un documento descargado
+const DefaultMaxSize int64 = 50 << 20
+
+// ErrTooLarge indica que el documento supera el tamaño máximo de descarga
+var ErrTooLarge = errors.New("el documento supera el tamaño máximo de descarga")
+
 var (
-	// Logger compartido para el paquete
-	log = logger.NewLogger(false) // Cambia a true para escribir en archivo
+	downloads     = metrics.NewCounter("go_ocr_downloads_total", "Descargas de documentos por resultado.", "outcome")
+	downloadBytes = metrics.NewCounter("go_ocr_download_bytes_total", "Bytes descargados de documentos.")
 )
 
-func init() {
-	// Opcional: Habilitar debug en desarrollo
-	if os.Getenv("ENV") == "development" {
-		log.EnableDebug()
-	}
+// DownloadPDF descarga el documento (PDF, imagen o archivo ZIP/EML) y lo guarda en un archivo
+// temporal con la extensión correspondiente al formato detectado por sus magic bytes.
+// Los documentos de más de maxSize bytes devuelven ErrTooLarge sin dejar archivo.
+// La descarga se interrumpe si ctx se cancela o vence.
+func DownloadPDF(ctx context.Context, url string, maxSize int64) (string, error) {
+	// Del URL solo se registra el host: la ruta y la consulta pueden llevar credenciales
+	ctx, span := tracing.Start(ctx, "download", tracing.String("url.host", urlHost(url)))
+	span.SetKind(tracing.KindClient)
+	defer span.End()
+
+	startTime := time.Now()
+	path, err := download(ctx, url, maxSize, span)
+	span.RecordError(err)
+	downloads.Inc(metrics.Outcome(err))
+	metrics.ObserveStage("download", startTime, err)
+	return path, err
 }
 
-func DownloadPDF(url string) (string, error) {
+// download hace la descarga de DownloadPDF y anota en span el estado HTTP, el tamaño y el formato
+func download(ctx context.Context, url string, maxSize int64, span *tracing.Span) (string, error) {
+	log := logger.FromContext(ctx)
 	startTime := time.Now()
-	log.Info("Iniciando descarga de PDF desde URL: %s", url)
-	log.Debug("Parámetros de DownloadPDF - url: %s", url)
+	log.Info("Iniciando descarga de documento desde %s", logger.RedactURL(url))
 
-	// Verificar que sea una URL de PDF
-	if !strings.HasSuffix(strings.ToLower(url), ".pdf") {
-		errMsg := fmt.Sprintf("URL no es un PDF: %s", url)
-		log.Error(errMsg)
-		return "", fmt.Errorf("la URL no apunta a un PDF")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		log.Error("Error al crear la petición de descarga: %v", err)
+		return "", fmt.Errorf("error al crear la petición de descarga: %v", err)
 	}
 
-	resp, err := http.Get(url)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
+		// El error de net/http incluye la URL completa
+		if urlErr, ok := err.(*neturl.Error); ok {
+			urlErr.URL = logger.RedactURL(urlErr.URL)
+		}
 		log.Error("Error al descargar PDF: %v", err)
 		return "", fmt.Errorf("error al descargar: %v", err)
 	}
 	defer resp.Body.Close()
 
 	log.Debug("Respuesta HTTP - Status: %s, ContentLength: %d", resp.Status, resp.ContentLength)
+	span.SetAttributes(tracing.Int("http.status_code", resp.StatusCode))
 
 	if resp.StatusCode != http.StatusOK {
 		errMsg := fmt.Sprintf("Respuesta HTTP no OK: %s", resp.Status)
 		log.Error(errMsg)
 		return "", fmt.Errorf("respuesta no OK: %s", resp.Status)
 	}
+	if resp.ContentLength > maxSize {
+		log.Error("Documento de %d bytes, el máximo es %d", resp.ContentLength, maxSize)
+		return "", fmt.Errorf("%w: ocupa %d bytes y el máximo es %d", ErrTooLarge, resp.ContentLength, maxSize)
+	}
 
 	// Crear archivo temporal
-	tmpFile, err := os.CreateTemp("", "pdf_*.pdf")
+	tmpFile, err := os.CreateTemp("", "doc_*")
 	if err != nil {
 		log.Error("Error al crear archivo temporal: %v", err)
 		return "", fmt.Errorf("error al crear archivo temporal: %v", err)
 	}
 	defer tmpFile.Close()
 
-	// Copiar contenido
-	_, err = io.Copy(tmpFile, resp.Body)
+	// Copiar contenido: un byte más que el máximo basta para saber que lo supera, aunque
+	// el servidor no anuncie Content-Length
+	size, err := io.Copy(tmpFile, io.LimitReader(resp.Body, maxSize+1))
+	span.SetAttributes(tracing.Int64("bytes", size))
+	downloadBytes.Add(float64(size))
+	if err != nil {
+		log.Error("Error al guardar documento: %v", err)
+		CleanupFile(ctx, tmpFile.Name())
+		return "", fmt.Errorf("error al guardar documento: %v", err)
+	}
+	if size > maxSize {
+		log.Error("Documento de más de %d bytes, el máximo permitido", maxSize)
+		CleanupFile(ctx, tmpFile.Name())
+		return "", fmt.Errorf("%w: ocupa más de %d bytes", ErrTooLarge, maxSize)
+	}
+
+	// Detectar el formato por contenido, no por la URL
+	docType, err := doctype.Detect(tmpFile.Name())
 	if err != nil {
-		log.Error("Error al guardar PDF: %v", err)
-		return "", fmt.Errorf("error al guardar PDF: %v", err)
+		log.Error("Error al detectar formato: %v", err)
+		CleanupFile(ctx, tmpFile.Name())
+		return "", fmt.Errorf("error al detectar formato: %v", err)
+	}
+	if docType == doctype.Unknown {
+		log.Error("Formato de documento no soportado: %s", logger.RedactURL(url))
+		CleanupFile(ctx, tmpFile.Name())
+		return "", fmt.Errorf("formato no soportado: se admiten PDF, JPEG, PNG, TIFF, ZIP y EML")
+	}
+
+	span.SetAttributes(tracing.String("document.type", string(docType)))
+
+	path := tmpFile.Name() + docType.Extension()
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		log.Error("Error al renombrar archivo temporal: %v", err)
+		CleanupFile(ctx, tmpFile.Name())
+		return "", fmt.Errorf("error al renombrar archivo temporal: %v", err)
 	}
 
-	log.Info("PDF descargado exitosamente en %s. Tiempo de ejecución: %v", tmpFile.Name(), time.Since(startTime))
-	return tmpFile.Name(), nil
+	log.With("stage", "download", "duration_ms", time.Since(startTime).Milliseconds()).Info("Documento %s descargado exitosamente en %s. Tiempo de ejecución: %v", docType, path, time.Since(startTime))
+	return path, nil
+}
+
+// urlHost devuelve el host de url, o "" si no se puede interpretar
+func urlHost(url string) string {
+	parsed, err := neturl.Parse(url)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
 }
 
-func CleanupFile(path string) {
+func CleanupFile(ctx context.Context, path string) {
+	log := logger.FromContext(ctx)
 	if path == "" {
 		log.Debug("CleanupFile llamado con path vacío")
 		return
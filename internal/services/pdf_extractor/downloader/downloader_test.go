@@ -0,0 +1,76 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pdf devuelve un documento PDF de prueba de size bytes
+func pdf(size int) []byte {
+	data := []byte("%PDF-1.4\n")
+	return append(data, bytes.Repeat([]byte{' '}, size-len(data))...)
+}
+
+// useTempDir fija durante el test un directorio temporal vacío para comprobar que no
+// quedan archivos
+func useTempDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	return dir
+}
+
+func TestDownloadPDFLimitsSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		chunked bool // Sin Content-Length: solo se detecta al copiar el cuerpo
+		tooBig  bool
+	}{
+		{"dentro del límite", 1024, false, false},
+		{"justo en el límite", 2048, false, false},
+		{"supera el límite con Content-Length", 2049, false, true},
+		{"supera el límite sin Content-Length", 4096, true, true},
+		{"dentro del límite sin Content-Length", 1024, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := useTempDir(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body := pdf(tt.size)
+				if tt.chunked {
+					w.Write(body[:10])
+					w.(http.Flusher).Flush()
+					w.Write(body[10:])
+					return
+				}
+				w.Write(body)
+			}))
+			defer server.Close()
+
+			path, err := DownloadPDF(context.Background(), server.URL+"/nomina.pdf", 2048)
+			if tt.tooBig {
+				if !errors.Is(err, ErrTooLarge) {
+					t.Fatalf("error %v, se esperaba ErrTooLarge", err)
+				}
+				if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 0 {
+					t.Errorf("quedaron archivos temporales: %v", files)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(path)
+			if info, err := os.Stat(path); err != nil || info.Size() != int64(tt.size) {
+				t.Errorf("documento descargado %v (%v), se esperaban %d bytes", info, err, tt.size)
+			}
+		})
+	}
+}
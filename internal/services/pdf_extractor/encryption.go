@@ -0,0 +1,260 @@
+package pdf_extractor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/pdf_extractor/doctype"
+	"go_ocr/internal/services/runner"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ErrPasswordRequired indica que el PDF está cifrado y ninguna contraseña candidata lo abre
+var ErrPasswordRequired = errors.New("el PDF está protegido con contraseña")
+
+// Document es un documento abierto con Open. Las estrategias de extracción y poppler leen
+// Plain, que nunca necesita contraseña; las firmas y el análisis forense leen Path, el
+// archivo original, con Password.
+type Document struct {
+	Path     string // Archivo original
+	Password string // Contraseña que abre Path; "" si no está cifrado o solo tiene contraseña de propietario
+	Plain    string // Copia descifrada si Path tiene contraseña de usuario; si no, Path
+}
+
+// Open comprueba si el documento es un PDF cifrado y, si lo es, busca entre candidates la
+// contraseña que lo abre y lo descifra con UniPDF en un archivo temporal con permisos 0600.
+// Así la contraseña no llega nunca a la línea de comandos de poppler. Devuelve
+// ErrPasswordRequired si ninguna candidata es válida. Close borra la copia descifrada.
+func (s *Service) Open(ctx context.Context, path string, candidates []string) (*Document, error) {
+	log := logger.FromContext(ctx)
+	doc := &Document{Path: path, Plain: path}
+	docType, err := doctype.Detect(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al detectar formato: %v", err)
+	}
+	if docType != doctype.PDF {
+		return doc, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir archivo: %v", err)
+	}
+	defer f.Close()
+
+	parser, err := core.NewParser(f)
+	if err != nil {
+		// Un PDF sin cifrar que UniPDF no sabe leer puede servir a poppler
+		log.Warning("UniPDF no pudo comprobar el cifrado, se usa poppler: %v", err)
+		return doc, checkWithPoppler(ctx, s.runner, path)
+	}
+	encrypted, err := parser.IsEncrypted()
+	if err != nil {
+		return nil, fmt.Errorf("error al comprobar cifrado: %v", err)
+	}
+	if !encrypted {
+		return doc, nil
+	}
+	log.Info("PDF cifrado (%s), probando %d contraseñas candidatas", parser.GetCrypter(), len(candidates))
+
+	// La contraseña vacía abre los PDF que solo restringen permisos, que poppler lee sin más
+	for _, password := range append([]string{""}, candidates...) {
+		ok, err := parser.Decrypt([]byte(password))
+		if err != nil {
+			return nil, fmt.Errorf("error al descifrar: %v", err)
+		}
+		if !ok {
+			continue
+		}
+		if password == "" {
+			return doc, nil
+		}
+		doc.Password = password
+		if doc.Plain, err = writeDecrypted(parser); err != nil {
+			return nil, err
+		}
+		log.Debug("PDF descifrado en %s", doc.Plain)
+		return doc, nil
+	}
+	return nil, ErrPasswordRequired
+}
+
+// Close borra la copia descifrada del documento, si la hay
+func (d *Document) Close() error {
+	if d.Plain == d.Path {
+		return nil
+	}
+	if err := os.Remove(d.Plain); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error al borrar copia descifrada: %v", err)
+	}
+	return nil
+}
+
+// checkWithPoppler comprueba con pdfinfo que poppler puede abrir el documento sin
+// contraseña. Si está cifrado devuelve un error: UniPDF no lo puede descifrar y la
+// contraseña no se pasa a poppler.
+func checkWithPoppler(ctx context.Context, r *runner.Runner, path string) error {
+	_, stderr, err := r.Run(ctx, "pdfinfo", path)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if strings.Contains(stderr, "Incorrect password") {
+		return fmt.Errorf("error al descifrar: UniPDF no puede leer el cifrado del PDF")
+	}
+	return fmt.Errorf("error al abrir el PDF con pdfinfo: %v\nSalida: %s", err, stderr)
+}
+
+// writeDecrypted escribe los objetos de parser, ya descifrado, en un PDF temporal sin
+// cifrado y devuelve su ruta. os.CreateTemp crea el archivo con permisos 0600.
+func writeDecrypted(parser *core.PdfParser) (string, error) {
+	f, err := os.CreateTemp("", "decrypted_*.pdf")
+	if err != nil {
+		return "", fmt.Errorf("error al crear archivo temporal: %v", err)
+	}
+	err = writePlainPDF(f, parser)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("error al escribir PDF descifrado: %v", err)
+	}
+	return f.Name(), nil
+}
+
+// writePlainPDF escribe en w los objetos de parser sin el diccionario de cifrado. Los
+// flujos de objetos y de xref se omiten: sus objetos se escriben sueltos y la tabla se rehace.
+func writePlainPDF(w io.Writer, parser *core.PdfParser) error {
+	skip := make(map[int]bool)
+	if encrypt := parser.GetEncryptObj(); encrypt != nil {
+		skip[int(encrypt.ObjectNumber)] = true
+	}
+
+	nums := parser.GetObjectNums()
+	sort.Ints(nums)
+	var objects []core.PdfObject
+	for _, num := range nums {
+		if skip[num] {
+			continue
+		}
+		obj, err := parser.LookupByNumber(num)
+		if err != nil {
+			return fmt.Errorf("error al leer objeto %d: %v", num, err)
+		}
+		if stream, ok := obj.(*core.PdfObjectStream); ok {
+			if name, _ := core.GetNameVal(stream.Get("Type")); name == "ObjStm" || name == "XRef" {
+				continue
+			}
+		}
+		objects = append(objects, obj)
+	}
+
+	trailer := core.MakeDict()
+	for _, key := range []core.PdfObjectName{"Root", "Info", "ID"} {
+		if value := parser.GetTrailer().Get(key); value != nil {
+			trailer.Set(key, value)
+		}
+	}
+	return writeObjects(w, parser.PdfVersion().String(), objects, trailer)
+}
+
+// writeObjects escribe un PDF con los objetos indirectos y flujos de objects, una tabla
+// xref clásica y trailer, al que se añade /Size. Los objetos que no son indirectos se omiten.
+func writeObjects(w io.Writer, version string, objects []core.PdfObject, trailer *core.PdfObjectDictionary) error {
+	out := &countingWriter{w: bufio.NewWriter(w)}
+	fmt.Fprintf(out, "%%PDF-%s\n%%\xe2\xe3\xcf\xd3\n", version)
+	offsets := make(map[int64]int64)
+	generations := make(map[int64]int64)
+	var size int64 = 1
+	for _, obj := range objects {
+		offset := out.n
+		var num, gen int64
+		switch obj := obj.(type) {
+		case *core.PdfIndirectObject:
+			num, gen = obj.ObjectNumber, obj.GenerationNumber
+			fmt.Fprintf(out, "%d %d obj\n%s\nendobj\n", num, gen, writeObject(obj.PdfObject))
+		case *core.PdfObjectStream:
+			num, gen = obj.ObjectNumber, obj.GenerationNumber
+			obj.Set("Length", core.MakeInteger(int64(len(obj.Stream))))
+			fmt.Fprintf(out, "%d %d obj\n%s\nstream\n", num, gen, obj.PdfObjectDictionary.WriteString())
+			out.Write(obj.Stream)
+			fmt.Fprint(out, "\nendstream\nendobj\n")
+		default:
+			continue
+		}
+		offsets[num], generations[num] = offset, gen
+		if num >= size {
+			size = num + 1
+		}
+	}
+
+	trailer.Set("Size", core.MakeInteger(size))
+	xref := out.n
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for num := int64(1); num < size; num++ {
+		if offset, ok := offsets[num]; ok {
+			fmt.Fprintf(out, "%010d %05d n \n", offset, generations[num])
+		} else {
+			fmt.Fprint(out, "0000000000 65535 f \n")
+		}
+	}
+	fmt.Fprintf(out, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", trailer.WriteString(), xref)
+	if out.err != nil {
+		return out.err
+	}
+	return out.w.Flush()
+}
+
+// writeObject serializa un objeto directo. Los flujos y objetos indirectos se escriben
+// como referencia.
+func writeObject(obj core.PdfObject) string {
+	if obj == nil {
+		return "null"
+	}
+	return obj.WriteString()
+}
+
+// countingWriter cuenta los bytes escritos para calcular los desplazamientos de la xref y
+// guarda el primer error
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// decryptReader abre con la contraseña vacía los PDF que solo restringen permisos. Los
+// cifrados con contraseña de usuario se descifran antes con Open y se leen de Plain.
+func decryptReader(pdfReader *model.PdfReader) error {
+	encrypted, err := pdfReader.IsEncrypted()
+	if err != nil || !encrypted {
+		return err
+	}
+	ok, err := pdfReader.Decrypt([]byte(""))
+	if err != nil {
+		return fmt.Errorf("error al descifrar: %v", err)
+	}
+	if !ok {
+		return ErrPasswordRequired
+	}
+	return nil
+}
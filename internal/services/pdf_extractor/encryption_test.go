@@ -0,0 +1,186 @@
+package pdf_extractor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/core/security"
+	"github.com/unidoc/unipdf/v3/core/security/crypt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// content es el flujo de contenido de la página de los PDF de prueba
+const content = "BT /F1 12 Tf 72 720 Td (Liquido a percibir 1.234,56) Tj ET"
+
+// plainPDF devuelve un PDF mínimo de una página con texto
+func plainPDF() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Title (Nomina de Ana Torres) >>",
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 6 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// writeFile escribe data en un archivo temporal y devuelve su ruta
+func writeFile(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "nomina.pdf")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// encryptedPDF cifra plainPDF con RC4 de 128 bits y la contraseña de usuario password
+func encryptedPDF(t *testing.T, password string) string {
+	t.Helper()
+	parser, err := core.NewParser(bytes.NewReader(plainPDF()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crypter, info, err := core.PdfCryptNewEncrypt(crypt.NewFilterV2(16), []byte(password), []byte("propietario"), security.PermOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var objects []core.PdfObject
+	for num := 1; num <= 6; num++ {
+		obj, err := parser.LookupByNumber(num)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := crypter.Encrypt(obj, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, obj)
+	}
+	trailer := core.MakeDict()
+	trailer.Set("Root", parser.GetTrailer().Get("Root"))
+	trailer.Set("Info", parser.GetTrailer().Get("Info"))
+	trailer.Set("Encrypt", info.Encrypt)
+	trailer.Set("ID", core.MakeArray(core.MakeString(info.ID0), core.MakeString(info.ID1)))
+
+	var buf bytes.Buffer
+	if err := writeObjects(&buf, "1.4", objects, trailer); err != nil {
+		t.Fatal(err)
+	}
+	return writeFile(t, buf.Bytes())
+}
+
+func TestOpenDecryptsIntoPrivateCopy(t *testing.T) {
+	path := encryptedPDF(t, "12345678Z")
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(original, []byte("Liquido a percibir")) {
+		t.Fatal("el PDF de prueba no está cifrado")
+	}
+
+	doc, err := new(Service).Open(context.Background(), path, []string{"incorrecta", "12345678Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Path != path || doc.Password != "12345678Z" || doc.Plain == path {
+		t.Fatalf("documento inesperado: %+v", doc)
+	}
+
+	info, err := os.Stat(doc.Plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("permisos de la copia descifrada %v, se esperaba 0600", info.Mode().Perm())
+	}
+
+	// La copia se abre sin contraseña y conserva contenido y metadatos
+	plain, err := os.ReadFile(doc.Plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser, err := core.NewParser(bytes.NewReader(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encrypted, err := parser.IsEncrypted(); err != nil || encrypted {
+		t.Fatalf("copia cifrada: %v, %v", encrypted, err)
+	}
+	stream, err := parser.LookupByNumber(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := core.GetStream(stream); !ok || string(s.Stream) != content {
+		t.Errorf("contenido de la página descifrado incorrectamente: %v", stream)
+	}
+	infoDict, err := parser.LookupByNumber(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dict, ok := core.GetDict(infoDict); !ok || dict.Get("Title").WriteString() != "(Nomina de Ana Torres)" {
+		t.Errorf("metadatos descifrados incorrectamente: %v", infoDict)
+	}
+
+	if err := doc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(doc.Plain); !os.IsNotExist(err) {
+		t.Errorf("la copia descifrada no se borró: %v", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       func(t *testing.T) string
+		candidates []string
+		err        error
+	}{
+		{"sin cifrar", func(t *testing.T) string { return writeFile(t, plainPDF()) }, nil, nil},
+		{"contraseña incorrecta", func(t *testing.T) string { return encryptedPDF(t, "12345678Z") }, []string{"87654321X"}, ErrPasswordRequired},
+		{"sin candidatas", func(t *testing.T) string { return encryptedPDF(t, "12345678Z") }, nil, ErrPasswordRequired},
+		{"solo contraseña de propietario", func(t *testing.T) string { return encryptedPDF(t, "") }, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path(t)
+			doc, err := new(Service).Open(context.Background(), path, tt.candidates)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error %v, se esperaba %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			// Sin contraseña de usuario poppler lee el original: no hace falta copia
+			if doc.Plain != path || doc.Password != "" {
+				t.Errorf("documento inesperado: %+v", doc)
+			}
+			if err := doc.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("Close borró el original: %v", err)
+			}
+		})
+	}
+}
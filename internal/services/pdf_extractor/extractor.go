@@ -0,0 +1,96 @@
+package pdf_extractor
+
+import (
+	"context"
+	"go_ocr/internal/services/pdf_extractor/ocr"
+	"go_ocr/internal/services/runner"
+)
+
+// Extractor es una estrategia de extracción de texto de un documento
+type Extractor interface {
+	// Name identifica la estrategia en logs y respuestas
+	Name() string
+	// Extract devuelve cada página del documento, en orden.
+	// Debe abandonar el trabajo si ctx se cancela o vence.
+	Extract(ctx context.Context, path string, opts Options) ([]Page, error)
+}
+
+// Page es el texto de una página y, si se obtuvo con OCR, su reconocimiento detallado
+type Page struct {
+	Text string
+	OCR  *ocr.Page
+}
+
+// textPages convierte el texto de cada página en páginas sin información de OCR
+func textPages(texts []string, err error) ([]Page, error) {
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]Page, len(texts))
+	for i, text := range texts {
+		pages[i] = Page{Text: text}
+	}
+	return pages, nil
+}
+
+// ocrPages convierte un documento reconocido en páginas con su detalle de OCR
+func ocrPages(doc *ocr.Document, layout bool) []Page {
+	pages := make([]Page, len(doc.Pages))
+	for i := range doc.Pages {
+		pages[i] = ocrPage(&doc.Pages[i], layout)
+	}
+	return pages
+}
+
+// ocrPage convierte una página reconocida, conservando su disposición si layout es true
+func ocrPage(page *ocr.Page, layout bool) Page {
+	if layout {
+		return Page{Text: page.LayoutText(), OCR: page}
+	}
+	return Page{Text: page.Text(), OCR: page}
+}
+
+// Strategies devuelve los nombres de las estrategias configuradas, en el orden en que se prueban
+func (s *Service) Strategies() []string {
+	names := make([]string, len(s.extractors))
+	for i, extractor := range s.extractors {
+		names[i] = extractor.Name()
+	}
+	return names
+}
+
+// PdfToTextExtractor usa la capa de texto del PDF a través de poppler.
+// Con Options.Layout usa el modo -layout, que conserva las columnas.
+type PdfToTextExtractor struct {
+	Runner *runner.Runner
+}
+
+func (PdfToTextExtractor) Name() string { return "pdftotext" }
+
+func (e PdfToTextExtractor) Extract(ctx context.Context, path string, opts Options) ([]Page, error) {
+	return textPages(extractWithPdfToText(ctx, e.Runner, path, opts.Layout))
+}
+
+// UniPDFExtractor usa la capa de texto del PDF a través de UniPDF; necesita licencia
+type UniPDFExtractor struct{}
+
+func (UniPDFExtractor) Name() string { return "unipdf" }
+
+func (UniPDFExtractor) Extract(ctx context.Context, path string, opts Options) ([]Page, error) {
+	return textPages(extractWithUniPDF(ctx, path))
+}
+
+// OCRExtractor rasteriza el documento y lo reconoce con Tesseract
+type OCRExtractor struct {
+	Engine *ocr.Engine
+}
+
+func (OCRExtractor) Name() string { return "ocr" }
+
+func (e OCRExtractor) Extract(ctx context.Context, path string, opts Options) ([]Page, error) {
+	doc, err := e.Engine.ExtractWithOCR(ctx, path, opts.OCR)
+	if err != nil {
+		return nil, err
+	}
+	return ocrPages(doc, opts.Layout), nil
+}
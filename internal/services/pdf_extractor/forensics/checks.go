@@ -0,0 +1,331 @@
+package forensics
+
+import (
+	"context"
+	"fmt"
+	"github.com/unidoc/unipdf/v3/extractor"
+	"github.com/unidoc/unipdf/v3/model"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/pdf_extractor/ocr"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Peso de cada indicio en la puntuación
+const (
+	revisionsWeight   = 25
+	editorWeight      = 30
+	modifiedWeight    = 10
+	amountFontsWeight = 30
+	textLayerWeight   = 40
+	totalsWeight      = 35
+)
+
+const (
+	// totalsTolerance es la diferencia de redondeo admitida entre el líquido y su cálculo
+	totalsTolerance = 0.05
+
+	// modifiedThreshold es el tiempo entre creación y modificación a partir del cual el
+	// documento se considera editado; los generadores de nóminas escriben ambas a la vez
+	modifiedThreshold = time.Hour
+
+	// minAmountFontDigits es el mínimo de cifras de una fuente para juzgar si solo pinta importes
+	minAmountFontDigits = 4
+	// amountFontRatio es la proporción de caracteres de importe de una fuente "solo de importes"
+	amountFontRatio = 0.95
+
+	// minTextChars es el mínimo de caracteres para considerar que una página tiene capa de texto
+	minTextChars = 50
+	// minLayerAmounts es el mínimo de importes en la capa de texto para contrastarla con OCR
+	minLayerAmounts = 3
+	// missingAmountRatio es la proporción de importes ausentes en el OCR que se considera discrepancia
+	missingAmountRatio = 0.3
+	// minOCRConfidence es la confianza por debajo de la cual el OCR no sirve de contraste
+	minOCRConfidence = ocr.LowWordConfidence
+)
+
+// editingTools son los nombres, en minúsculas, de editores y conversores en línea que no
+// generan nóminas. Los productores genéricos (Quartz PDFContext de la impresión de macOS,
+// Acrobat, impresoras PDF) no figuran: también generan nóminas legítimas.
+var editingTools = []string{
+	"pdfescape", "sejda", "ilovepdf", "smallpdf", "pdf-xchange editor", "pdfelement",
+	"phantompdf", "foxit pdf editor", "pdffiller", "pdf candy", "inkscape", "photoshop",
+	"gimp", "canva", "pdfsam", "master pdf editor", "soda pdf",
+}
+
+// amountRegex reconoce importes con dos decimales: 1.234,56, 1234,56 o 1234.56
+var amountRegex = regexp.MustCompile(`\d{1,3}(?:\.\d{3})+,\d{2}\b|\d+[,.]\d{2}\b`)
+
+// pageText es la capa de texto de una página
+type pageText struct {
+	number int
+	text   string
+	marks  []extractor.TextMark
+}
+
+// readPages lee la capa de texto de cada página. ctx se comprueba entre páginas. Si el
+// texto de una página no se puede extraer (p. ej. UniPDF sin licencia) se devuelve el
+// error: el de las demás páginas fallaría igual y las comprobaciones de texto no se hacen.
+func readPages(ctx context.Context, pdfReader *model.PdfReader) ([]pageText, error) {
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener número de páginas: %v", err)
+	}
+
+	pages := make([]pageText, 0, numPages)
+	for i := 1; i <= numPages; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, err := pdfReader.GetPage(i)
+		if err != nil {
+			return nil, fmt.Errorf("error en página %d: %v", i, err)
+		}
+
+		ex, err := extractor.New(page)
+		if err != nil {
+			return nil, fmt.Errorf("error al extraer texto de página %d: %v", i, err)
+		}
+		text, _, _, err := ex.ExtractPageText()
+		if err != nil {
+			return nil, fmt.Errorf("error al extraer texto de página %d: %v", i, err)
+		}
+		pages = append(pages, pageText{number: i, text: text.Text(), marks: text.Marks().Elements()})
+	}
+	return pages, nil
+}
+
+// checkRevisions marca los PDF con actualizaciones incrementales: cada edición guardada
+// sin reescribir el archivo añade una revisión al final. Las revisiones que solo añaden una
+// firma o datos de validación (DSS) no cuentan, porque firmar también añade una.
+func (r *Report) checkRevisions(ctx context.Context, path, password string, pdfReader *model.PdfReader) {
+	log := logger.FromContext(ctx)
+	revisions := pdfReader.GetRevisionNumber()
+	if revisions == 0 {
+		return
+	}
+	edits, err := countEdits(path, password)
+	if err != nil {
+		log.Debug("No se pudieron clasificar las revisiones, se cuentan todas: %v", err)
+		edits = revisions
+	}
+	if edits > 0 {
+		r.add(CheckIncrementalUpdates, revisionsWeight,
+			"el documento tiene %d revisiones añadidas después de su creación que no son firmas (%d en total)",
+			edits, revisions)
+	}
+}
+
+// checkMetadata marca los documentos creados o guardados con herramientas de edición y los
+// modificados mucho después de crearse
+func (r *Report) checkMetadata(ctx context.Context, pdfReader *model.PdfReader) {
+	log := logger.FromContext(ctx)
+	info, err := pdfReader.GetPdfInfo()
+	if err != nil {
+		log.Debug("PDF sin información de documento: %v", err)
+		return
+	}
+
+	var producer, creator string
+	if info.Producer != nil {
+		producer = info.Producer.Decoded()
+	}
+	if info.Creator != nil {
+		creator = info.Creator.Decoded()
+	}
+	for _, field := range []struct{ name, value string }{{"Producer", producer}, {"Creator", creator}} {
+		if tool := editingTool(field.value); tool != "" {
+			r.add(CheckMetadata, editorWeight, "%s %q corresponde a una herramienta de edición (%s), Creator %q",
+				field.name, field.value, tool, creator)
+			break
+		}
+	}
+
+	if info.CreationDate != nil && info.ModifiedDate != nil {
+		created, modified := info.CreationDate.ToGoTime(), info.ModifiedDate.ToGoTime()
+		if modified.Sub(created) > modifiedThreshold {
+			r.add(CheckMetadata, modifiedWeight, "modificado el %s, %v después de su creación",
+				modified.Format(time.RFC3339), modified.Sub(created).Round(time.Minute))
+		}
+	}
+}
+
+// editingTool devuelve la herramienta de edición que aparece en value, o "". Los nombres
+// se buscan como palabras completas: "Canva" es una herramienta, "Canvas Builder" no.
+func editingTool(value string) string {
+	words := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+	joined := " " + strings.Join(words, " ") + " "
+	for _, tool := range editingTools {
+		if strings.Contains(joined, " "+tool+" ") {
+			return tool
+		}
+	}
+	return ""
+}
+
+// fontUsage cuenta los caracteres que pinta una fuente
+type fontUsage struct {
+	chars   int // Caracteres visibles
+	amount  int // Cifras y signos de importe
+	digits  int
+	pages   map[int]bool
+	example string
+}
+
+// checkAmountFonts marca las fuentes que solo pintan importes cuando otros importes del
+// documento usan otra fuente: es lo que queda al sobrescribir una cifra con un editor
+func (r *Report) checkAmountFonts(pages []pageText) {
+	usage := make(map[string]*fontUsage)
+	for _, page := range pages {
+		for _, mark := range page.marks {
+			if mark.Meta || mark.Font == nil {
+				continue
+			}
+			name := mark.Font.BaseFont()
+			u, ok := usage[name]
+			if !ok {
+				u = &fontUsage{pages: make(map[int]bool)}
+				usage[name] = u
+			}
+			u.pages[page.number] = true
+			for _, c := range mark.Text {
+				if unicode.IsSpace(c) {
+					continue
+				}
+				u.chars++
+				if unicode.IsDigit(c) {
+					u.digits++
+				}
+				if unicode.IsDigit(c) || strings.ContainsRune(".,-€%", c) {
+					u.amount++
+					if len(u.example) < 20 {
+						u.example += string(c)
+					}
+				}
+			}
+		}
+	}
+
+	var numeric, amountOnly []string
+	for name, u := range usage {
+		if u.digits < minAmountFontDigits {
+			continue
+		}
+		numeric = append(numeric, name)
+		if float64(u.amount)/float64(u.chars) >= amountFontRatio {
+			amountOnly = append(amountOnly, name)
+		}
+	}
+	// Si todos los importes usan fuentes "solo de importes", es el diseño del documento
+	if len(amountOnly) == 0 || len(amountOnly) == len(numeric) {
+		return
+	}
+	sort.Strings(amountOnly)
+	for _, name := range amountOnly {
+		u := usage[name]
+		r.add(CheckAmountFonts, amountFontsWeight,
+			"la fuente %s solo se usa en importes (%d caracteres en %d páginas, p. ej. %q)",
+			name, u.chars, len(u.pages), u.example)
+	}
+}
+
+// checkTextLayer reconoce con OCR las primeras páginas con capa de texto y comprueba que
+// los importes de la capa de texto se vean en la página. Un importe tapado con un
+// rectángulo o dibujado en blanco sigue en la capa de texto pero no en la imagen.
+func (r *Report) checkTextLayer(ctx context.Context, path string, pages []pageText, opts Options) {
+	log := logger.FromContext(ctx)
+	if opts.OCRPages <= 0 || opts.Engine == nil {
+		return
+	}
+
+	var numbers []int
+	layers := make(map[int][]string)
+	for _, page := range pages {
+		if len(numbers) == opts.OCRPages {
+			break
+		}
+		if countChars(page.text) < minTextChars {
+			continue
+		}
+		if amounts := findAmounts(page.text); len(amounts) >= minLayerAmounts {
+			numbers = append(numbers, page.number)
+			layers[page.number] = amounts
+		}
+	}
+	if len(numbers) == 0 {
+		return
+	}
+
+	// poppler no recibe la contraseña: rasteriza la copia descifrada
+	if opts.Plain != "" {
+		path = opts.Plain
+	}
+	doc, err := opts.Engine.ExtractPages(ctx, path, numbers, opts.OCR)
+	if err != nil {
+		r.skip(ctx, CheckTextLayer, err)
+		return
+	}
+
+	for _, page := range doc.Pages {
+		if page.Confidence < minOCRConfidence {
+			log.Debug("OCR de página %d con confianza %.1f, no se contrasta", page.Number, page.Confidence)
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, amount := range findAmounts(page.Text()) {
+			seen[amount] = true
+		}
+
+		layer := layers[page.Number]
+		var missing []string
+		for _, amount := range layer {
+			if !seen[amount] {
+				missing = append(missing, amount)
+			}
+		}
+		if float64(len(missing))/float64(len(layer)) > missingAmountRatio {
+			if len(missing) > 5 {
+				missing = missing[:5]
+			}
+			r.add(CheckTextLayer, textLayerWeight,
+				"página %d: %d de %d importes de la capa de texto no aparecen en la imagen (p. ej. %s)",
+				page.Number, len(missing), len(layer), strings.Join(missing, ", "))
+		}
+	}
+}
+
+// findAmounts devuelve los importes distintos del texto reducidos a sus cifras, para que
+// 1.234,56 y 1234.56 coincidan
+func findAmounts(text string) []string {
+	var amounts []string
+	seen := make(map[string]bool)
+	for _, match := range amountRegex.FindAllString(text, -1) {
+		digits := strings.Map(func(c rune) rune {
+			if unicode.IsDigit(c) {
+				return c
+			}
+			return -1
+		}, match)
+		if !seen[digits] {
+			seen[digits] = true
+			amounts = append(amounts, digits)
+		}
+	}
+	return amounts
+}
+
+// countChars cuenta los caracteres no blancos del texto
+func countChars(text string) int {
+	count := 0
+	for _, c := range text {
+		if !unicode.IsSpace(c) {
+			count++
+		}
+	}
+	return count
+}
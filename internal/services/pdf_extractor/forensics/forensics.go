@@ -0,0 +1,184 @@
+// Package forensics busca indicios de manipulación en una nómina: revisiones añadidas
+// después de crearla, metadatos de herramientas de edición, fuentes que solo aparecen en
+// los importes, capa de texto que no coincide con el OCR de la página y totales incoherentes.
+// Cada indicio suma un peso a una puntuación de riesgo de 0 a 100.
+package forensics
+
+import (
+	"context"
+	"fmt"
+	"github.com/unidoc/unipdf/v3/model"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/metrics"
+	"go_ocr/internal/services/pdf_extractor/doctype"
+	"go_ocr/internal/services/pdf_extractor/ocr"
+	"os"
+	"time"
+)
+
+// findings cuenta los indicios encontrados por comprobación
+var findings = metrics.NewCounter("go_ocr_forensics_findings_total",
+	"Indicios de manipulación encontrados por comprobación.", "check")
+
+// Comprobaciones que pueden aportar un motivo
+const (
+	CheckIncrementalUpdates = "incremental_updates"
+	CheckMetadata           = "metadata"
+	CheckAmountFonts        = "amount_fonts"
+	CheckTextLayer          = "text_layer"
+	CheckTotals             = "totals"
+)
+
+// Niveles de riesgo según la puntuación
+const (
+	LevelLow    = "low"
+	LevelMedium = "medium"
+	LevelHigh   = "high"
+)
+
+// Umbrales de puntuación a partir de los que el riesgo es medio y alto
+const (
+	mediumScore = 30
+	highScore   = 60
+)
+
+// Options configura el análisis
+type Options struct {
+	Password string      // Contraseña que abre el PDF si está cifrado
+	Plain    string      // Copia descifrada que rasteriza el OCR; vacía usa el PDF analizado
+	Engine   *ocr.Engine // Motor del OCR con el que se contrasta la capa de texto; nil lo desactiva
+	OCR      ocr.Options // Opciones del OCR con el que se contrasta la capa de texto
+	OCRPages int         // Páginas con capa de texto que se contrastan con OCR; 0 lo desactiva
+}
+
+// DefaultOptions no contrasta la capa de texto con OCR: pasaría Tesseract por todos los PDF
+// digitales. Con OCRPages 1 se contrasta la primera página, donde están los importes.
+var DefaultOptions = Options{OCR: ocr.DefaultOptions}
+
+// TextChecks son las comprobaciones que necesitan la capa de texto de UniPDF
+var TextChecks = []string{CheckAmountFonts, CheckTextLayer}
+
+// Reason es un indicio de manipulación
+type Reason struct {
+	Check  string `json:"check"`
+	Detail string `json:"detail"`
+	Weight int    `json:"weight"`
+}
+
+// Report es el resultado del análisis
+type Report struct {
+	Score   int      `json:"score"` // Riesgo de 0 a 100
+	Level   string   `json:"level"`
+	Reasons []Reason `json:"reasons"`
+	Skipped []Skip   `json:"skipped,omitempty"` // Comprobaciones que no se pudieron hacer
+}
+
+// Skip es una comprobación que no se pudo hacer y el motivo
+type Skip struct {
+	Check  string `json:"check"`
+	Reason string `json:"reason"`
+}
+
+// newReport crea un informe sin indicios
+func newReport() *Report {
+	return &Report{Level: LevelLow, Reasons: []Reason{}}
+}
+
+// add registra un indicio y recalcula la puntuación
+func (r *Report) add(check string, weight int, format string, args ...interface{}) {
+	r.Reasons = append(r.Reasons, Reason{Check: check, Detail: fmt.Sprintf(format, args...), Weight: weight})
+	findings.Inc(check)
+
+	r.Score = 0
+	for _, reason := range r.Reasons {
+		r.Score += reason.Weight
+	}
+	if r.Score > 100 {
+		r.Score = 100
+	}
+	switch {
+	case r.Score >= highScore:
+		r.Level = LevelHigh
+	case r.Score >= mediumScore:
+		r.Level = LevelMedium
+	default:
+		r.Level = LevelLow
+	}
+}
+
+// skip registra una comprobación que no se pudo hacer
+func (r *Report) skip(ctx context.Context, check string, err error) {
+	log := logger.FromContext(ctx)
+	log.Warning("Comprobación forense %s omitida: %v", check, err)
+	r.Skipped = append(r.Skipped, Skip{Check: check, Reason: err.Error()})
+}
+
+// Analyze aplica las comprobaciones sobre el archivo. Las imágenes no tienen estructura
+// que analizar y devuelven un informe vacío al que solo se pueden añadir los totales.
+// ctx limita el OCR de contraste.
+func Analyze(ctx context.Context, path string, opts Options) (*Report, error) {
+	log := logger.FromContext(ctx)
+	startTime := time.Now()
+	report := newReport()
+
+	docType, err := doctype.Detect(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al detectar formato: %v", err)
+	}
+	if docType != doctype.PDF {
+		return report, nil
+	}
+	log.Info("Iniciando análisis forense de PDF: %s", path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir archivo: %v", err)
+	}
+	defer f.Close()
+
+	pdfReader, err := model.NewPdfReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear PDF reader: %v", err)
+	}
+	if encrypted, err := pdfReader.IsEncrypted(); err == nil && encrypted {
+		if ok, err := pdfReader.Decrypt([]byte(opts.Password)); err != nil || !ok {
+			return nil, fmt.Errorf("error al descifrar: %v", err)
+		}
+	}
+
+	report.checkRevisions(ctx, path, opts.Password, pdfReader)
+	report.checkMetadata(ctx, pdfReader)
+
+	// Sin capa de texto las comprobaciones que la usan se omiten; las anteriores siguen valiendo
+	pages, err := readPages(ctx, pdfReader)
+	switch {
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case err != nil:
+		for _, check := range TextChecks {
+			report.skip(ctx, check, err)
+		}
+	default:
+		report.checkAmountFonts(pages)
+		report.checkTextLayer(ctx, path, pages, opts)
+	}
+
+	log.With("stage", "forensics", "duration_ms", time.Since(startTime).Milliseconds()).
+		Info("Análisis forense completado. Riesgo: %d (%s), indicios: %d. Tiempo total: %v",
+			report.Score, report.Level, len(report.Reasons), time.Since(startTime))
+	return report, nil
+}
+
+// CheckTotals comprueba que el líquido sea el devengado menos las deducciones.
+// No hace nada si falta alguno de los importes.
+func (r *Report) CheckTotals(gross, deductions, net float64) {
+	if gross <= 0 || net <= 0 {
+		return
+	}
+	expected := gross - deductions
+	if diff := net - expected; diff > totalsTolerance || diff < -totalsTolerance {
+		r.add(CheckTotals, totalsWeight,
+			"el líquido (%.2f) no coincide con devengado menos deducciones (%.2f - %.2f = %.2f)",
+			net, gross, deductions, expected)
+	}
+}
@@ -0,0 +1,273 @@
+package forensics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// baseObjects son los objetos de un PDF mínimo de una página con texto: 1 catálogo,
+// 2 árbol de páginas, 3 página, 4 contenido y 5 fuente
+var baseObjects = []string{
+	"<< /Type /Catalog /Pages 2 0 R >>",
+	"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+	"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+	stream("BT /F1 12 Tf 72 720 Td (Liquido a percibir 1.234,56) Tj ET"),
+	"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+}
+
+func stream(content string) string {
+	return fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content)
+}
+
+// buildPDF escribe los objetos numerados desde 1 con su tabla xref
+func buildPDF(objects []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// appendUpdate añade a pdf una actualización incremental con objects, por número de objeto
+func appendUpdate(t *testing.T, pdf []byte, objects map[int]string) []byte {
+	t.Helper()
+	var prev, size int
+	tail := pdf[bytes.LastIndex(pdf, []byte("startxref")):]
+	if _, err := fmt.Sscanf(string(tail), "startxref\n%d", &prev); err != nil {
+		t.Fatal(err)
+	}
+	trailer := pdf[bytes.LastIndex(pdf, []byte("/Size")):]
+	if _, err := fmt.Sscanf(string(trailer), "/Size %d", &size); err != nil {
+		t.Fatal(err)
+	}
+
+	nums := make([]int, 0, len(objects))
+	for num := range objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	buf := bytes.NewBuffer(append([]byte{}, pdf...))
+	offsets := make(map[int]int)
+	for _, num := range nums {
+		offsets[num] = buf.Len()
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", num, objects[num])
+		if num >= size {
+			size = num + 1
+		}
+	}
+	xref := buf.Len()
+	buf.WriteString("xref\n")
+	for _, num := range nums {
+		fmt.Fprintf(buf, "%d 1\n%010d 00000 n \n", num, offsets[num])
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R /Prev %d >>\nstartxref\n%d\n%%%%EOF\n", size, prev, xref)
+	return buf.Bytes()
+}
+
+// writePDF guarda el PDF en un archivo temporal y devuelve su ruta
+func writePDF(t *testing.T, pdf []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "nomina.pdf")
+	if err := os.WriteFile(path, pdf, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// signatureUpdate son los objetos de una actualización que firma el documento: valor de la
+// firma, campo con widget, formulario, catálogo que lo enlaza y página con el widget
+var signatureObjects = map[int]string{
+	6: "<< /Type /Sig /Filter /Adobe.PPKLite /SubFilter /adbe.pkcs7.detached /ByteRange [0 0 0 0] /Contents <00> >>",
+	7: "<< /FT /Sig /T (Firma1) /V 6 0 R /Type /Annot /Subtype /Widget /Rect [0 0 0 0] /P 3 0 R >>",
+	8: "<< /Fields [7 0 R] /SigFlags 3 >>",
+	1: "<< /Type /Catalog /Pages 2 0 R /AcroForm 8 0 R >>",
+	3: "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> /Annots [7 0 R] >>",
+}
+
+// dssObjects son los objetos de una actualización que añade datos de validación (PAdES LTV)
+var dssObjects = map[int]string{
+	9:  "<< /Type /DSS /Certs [10 0 R] /OCSPs [] >>",
+	10: stream("certificado"),
+	1:  "<< /Type /Catalog /Pages 2 0 R /AcroForm 8 0 R /DSS 9 0 R >>",
+}
+
+func TestCountEdits(t *testing.T) {
+	base := buildPDF(baseObjects)
+	signed := appendUpdate(t, base, signatureObjects)
+	edited := func(pdf []byte) []byte {
+		return appendUpdate(t, pdf, map[int]string{4: stream("BT /F1 12 Tf 72 720 Td (Liquido a percibir 9.234,56) Tj ET")})
+	}
+
+	tests := []struct {
+		name  string
+		pdf   []byte
+		edits int
+	}{
+		{"sin revisiones", base, 0},
+		{"firma", signed, 0},
+		{"firma y DSS", appendUpdate(t, signed, dssObjects), 0},
+		{"contenido editado", edited(base), 1},
+		{"contenido editado después de firmar", edited(signed), 1},
+		{"página nueva", appendUpdate(t, base, map[int]string{
+			6: "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R >>",
+			2: "<< /Type /Pages /Kids [3 0 R 6 0 R] /Count 2 >>",
+		}), 1},
+		{"página cambiada con la firma", appendUpdate(t, base, map[int]string{
+			6: "<< /Type /Sig /Contents <00> >>",
+			7: "<< /FT /Sig /T (Firma1) /V 6 0 R /Subtype /Widget /Rect [0 0 0 0] >>",
+			3: "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 6 0 R /Annots [7 0 R] >>",
+		}), 1},
+		{"catálogo con otro árbol de páginas", appendUpdate(t, base, map[int]string{
+			6: "<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+			1: "<< /Type /Catalog /Pages 6 0 R /DSS 7 0 R >>",
+			7: "<< /Type /DSS >>",
+		}), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits, err := countEdits(writePDF(t, tt.pdf), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if edits != tt.edits {
+				t.Errorf("ediciones %d, se esperaban %d", edits, tt.edits)
+			}
+		})
+	}
+}
+
+// Los tests se ejecutan sin licencia de UniPDF, así que la capa de texto no se puede leer
+func TestAnalyzeWithoutTextLayerSkipsTextChecks(t *testing.T) {
+	report, err := Analyze(context.Background(), writePDF(t, buildPDF(baseObjects)), Options{OCRPages: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	skipped := make(map[string]string)
+	for _, skip := range report.Skipped {
+		skipped[skip.Check] = skip.Reason
+	}
+	if len(skipped) == 0 {
+		t.Skip("la capa de texto se pudo leer: UniPDF tiene licencia")
+	}
+	for _, check := range TextChecks {
+		if skipped[check] == "" {
+			t.Errorf("la comprobación %s no figura como omitida con motivo: %+v", check, report.Skipped)
+		}
+	}
+	for _, reason := range report.Reasons {
+		if reason.Check == CheckAmountFonts || reason.Check == CheckTextLayer {
+			t.Errorf("indicio de una comprobación omitida: %+v", reason)
+		}
+	}
+}
+
+func TestEditingTool(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		// Productores y creadores habituales de nóminas legítimas
+		{"macOS Version 14.2 (Build 23C64) Quartz PDFContext", ""},
+		{"Preview", ""},
+		{"Adobe PDF Library 23.1.175", ""},
+		{"Acrobat PDFMaker 23 para Word", ""},
+		{"Adobe Acrobat Pro (64-bit) 23.8.20470", ""},
+		{"Nitro PDF PrimoPDF", ""},
+		{"PDF24 Creator", ""},
+		{"Microsoft® Word para Microsoft 365", ""},
+		{"A3 Nom", ""},
+		{"JasperReports Library version 6.20.0", ""},
+		{"iText® 7.2.5 ©2000-2023 iText Group NV", ""},
+		{"Canvas Payroll Builder", ""},
+		{"", ""},
+		// Editores y conversores en línea
+		{"iLovePDF", "ilovepdf"},
+		{"Sejda Desktop 7.6.3", "sejda"},
+		{"sejda.com (4.3.14)", "sejda"},
+		{"PDFescape Online", "pdfescape"},
+		{"PDF-XChange Editor 10.1.1.381", "pdf-xchange editor"},
+		{"Wondershare PDFelement", "pdfelement"},
+		{"Foxit PDF Editor 12.1.2", "foxit pdf editor"},
+		{"Adobe Photoshop 25.0 (Windows)", "photoshop"},
+		{"GIMP 2.10.34", "gimp"},
+		{"Canva", "canva"},
+		{"Smallpdf.com", "smallpdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := editingTool(tt.value); got != tt.want {
+				t.Errorf("editingTool(%q) = %q, se esperaba %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeMetadata(t *testing.T) {
+	tests := []struct {
+		name              string
+		producer, creator string
+		flagged           bool
+	}{
+		{"impresión de macOS", "macOS Version 14.2 (Build 23C64) Quartz PDFContext", "Preview", false},
+		{"exportación de Acrobat", "Adobe PDF Library 23.1.175", "Adobe Acrobat Pro (64-bit) 23.8.20470", false},
+		{"productor de edición", "iLovePDF", "Microsoft Word", true},
+		{"creador de edición", "Adobe PDF Library 15.0", "PDFescape Online", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append(append([]string{}, baseObjects...),
+				fmt.Sprintf("<< /Producer (%s) /Creator (%s) >>", tt.producer, tt.creator))
+			pdf := bytes.Replace(buildPDF(objects), []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Info 6 0 R"), 1)
+
+			report, err := Analyze(context.Background(), writePDF(t, pdf), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var flagged bool
+			for _, reason := range report.Reasons {
+				flagged = flagged || reason.Check == CheckMetadata
+			}
+			if flagged != tt.flagged {
+				t.Errorf("indicio de metadatos %t, se esperaba %t: %+v", flagged, tt.flagged, report.Reasons)
+			}
+		})
+	}
+}
+
+func TestCheckTotals(t *testing.T) {
+	tests := []struct {
+		name                   string
+		gross, deductions, net float64
+		flagged                bool
+	}{
+		{"cuadra", 2000, 350.25, 1649.75, false},
+		{"redondeo", 2000, 350.25, 1649.79, false},
+		{"no cuadra", 2000, 350.25, 1849.75, true},
+		{"falta el devengado", 0, 350.25, 1649.75, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := newReport()
+			report.CheckTotals(tt.gross, tt.deductions, tt.net)
+			if flagged := len(report.Reasons) > 0; flagged != tt.flagged {
+				t.Errorf("indicio %t, se esperaba %t: %+v", flagged, tt.flagged, report.Reasons)
+			}
+		})
+	}
+}
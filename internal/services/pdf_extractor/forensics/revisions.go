@@ -0,0 +1,219 @@
+package forensics
+
+import (
+	"fmt"
+	"github.com/unidoc/unipdf/v3/core"
+	"os"
+)
+
+// Claves que una firma o unos datos de validación pueden cambiar en el catálogo, la página
+// con el widget de la firma y el formulario
+var (
+	signatureCatalogKeys  = []core.PdfObjectName{"AcroForm", "DSS", "Extensions", "Perms"}
+	signaturePageKeys     = []core.PdfObjectName{"Annots"}
+	signatureAcroFormKeys = []core.PdfObjectName{"Fields", "SigFlags", "DR", "DA"}
+)
+
+// countEdits cuenta las revisiones añadidas al documento que no son solo una firma o datos
+// de validación (DSS). Una revisión de firma añade objetos nuevos y, de los existentes, solo
+// cambia el catálogo (para enlazar /AcroForm o /DSS), el formulario, campos de firma, listas
+// de widgets o de campos a las que solo se añaden elementos y las páginas en sus /Annots.
+// Cualquier otro objeto existente modificado (p. ej. el contenido de una página) es una edición.
+func countEdits(path, password string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("error al abrir archivo: %v", err)
+	}
+	defer f.Close()
+
+	parser, err := core.NewParser(f)
+	if err != nil {
+		return 0, fmt.Errorf("error al leer estructura: %v", err)
+	}
+	if encrypted, err := parser.IsEncrypted(); err == nil && encrypted {
+		if ok, err := parser.Decrypt([]byte(password)); err != nil || !ok {
+			return 0, fmt.Errorf("error al descifrar: %v", err)
+		}
+	}
+
+	edits := 0
+	for n := 1; n <= parser.GetRevisionNumber(); n++ {
+		current, err := parser.GetRevision(n)
+		if err != nil {
+			return 0, fmt.Errorf("error al leer revisión %d: %v", n, err)
+		}
+		previous, err := parser.GetRevision(n - 1)
+		if err != nil {
+			return 0, fmt.Errorf("error al leer revisión %d: %v", n-1, err)
+		}
+		// Una revisión que no se puede clasificar cuenta como edición
+		if ok, err := signatureUpdate(current, previous); err != nil || !ok {
+			edits++
+		}
+	}
+	return edits, nil
+}
+
+// signatureUpdate indica si la revisión current solo añade firmas o datos de validación a previous
+func signatureUpdate(current, previous *core.PdfParser) (bool, error) {
+	trailer := current.GetTrailer()
+	root := referenceNumber(trailer.Get("Root"))
+	info := referenceNumber(trailer.Get("Info"))
+
+	before := previous.GetXrefTable().ObjectMap
+	for num, entry := range current.GetXrefTable().ObjectMap {
+		// Un objeto nuevo solo se ve si lo enlaza un objeto existente, que se comprueba aquí
+		prior, existing := before[num]
+		if !existing || prior == entry {
+			continue
+		}
+		obj, err := current.LookupByNumber(num)
+		if err != nil {
+			return false, err
+		}
+		old, err := previous.LookupByNumber(num)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case num == root:
+			if !sameExcept(obj, old, signatureCatalogKeys) {
+				return false, nil
+			}
+		case num == info:
+			// Los metadatos se juzgan en checkMetadata
+		case isSignatureObject(current, obj):
+		case isType(obj, "Page"):
+			if !sameExcept(obj, old, signaturePageKeys) {
+				return false, nil
+			}
+		case hasKey(obj, "Fields") && hasKey(old, "Fields"):
+			if !sameExcept(obj, old, signatureAcroFormKeys) {
+				return false, nil
+			}
+		case isAppendedList(current, obj, old):
+		default:
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isSignatureObject indica si obj es el valor de una firma, un campo o widget de firma o
+// parte del DSS de PAdES
+func isSignatureObject(parser *core.PdfParser, obj core.PdfObject) bool {
+	dict, ok := dictOf(obj)
+	if !ok {
+		return false
+	}
+	switch name, _ := core.GetNameVal(dict.Get("Type")); name {
+	case "Sig", "DocTimeStamp", "DSS", "VRI":
+		return true
+	}
+	if hasKey(dict, "Certs") || hasKey(dict, "OCSPs") || hasKey(dict, "CRLs") {
+		return true
+	}
+	if ft, _ := core.GetNameVal(dict.Get("FT")); ft == "Sig" {
+		return true
+	}
+	// Widget separado de su campo de firma
+	if parent, err := parser.Resolve(dict.Get("Parent")); err == nil {
+		if parentDict, ok := dictOf(parent); ok {
+			ft, _ := core.GetNameVal(parentDict.Get("FT"))
+			return ft == "Sig"
+		}
+	}
+	return false
+}
+
+// isAppendedList indica si obj es una lista de anotaciones o de campos (/Annots, /Fields)
+// que conserva los elementos de old y solo añade otros
+func isAppendedList(parser *core.PdfParser, obj, old core.PdfObject) bool {
+	array, ok := core.GetArray(obj)
+	if !ok {
+		return false
+	}
+	oldArray, ok := core.GetArray(old)
+	if !ok || oldArray.Len() > array.Len() {
+		return false
+	}
+	for i, item := range oldArray.Elements() {
+		if item.WriteString() != array.Get(i).WriteString() {
+			return false
+		}
+	}
+	// Los elementos son anotaciones o campos, no flujos de contenido
+	for _, item := range array.Elements() {
+		resolved, err := parser.Resolve(item)
+		if err != nil {
+			return false
+		}
+		dict, ok := core.GetDict(resolved)
+		if !ok || !(hasKey(dict, "Subtype") || hasKey(dict, "FT") || hasKey(dict, "T")) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameExcept indica si los diccionarios obj y old son iguales salvo en las claves ignored
+func sameExcept(obj, old core.PdfObject, ignored []core.PdfObjectName) bool {
+	dict, ok := dictOf(obj)
+	if !ok {
+		return false
+	}
+	oldDict, ok := dictOf(old)
+	if !ok {
+		return false
+	}
+	skip := make(map[core.PdfObjectName]bool)
+	for _, key := range ignored {
+		skip[key] = true
+	}
+	for _, pair := range [][2]*core.PdfObjectDictionary{{dict, oldDict}, {oldDict, dict}} {
+		for _, key := range pair[0].Keys() {
+			if skip[key] {
+				continue
+			}
+			other := pair[1].Get(key)
+			if other == nil || pair[0].Get(key).WriteString() != other.WriteString() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// dictOf devuelve el diccionario de un objeto indirecto o directo. Los flujos no se aceptan:
+// un flujo existente modificado cambia contenido.
+func dictOf(obj core.PdfObject) (*core.PdfObjectDictionary, bool) {
+	if _, ok := core.GetStream(obj); ok {
+		return nil, false
+	}
+	return core.GetDict(obj)
+}
+
+// isType indica si obj es un diccionario con /Type name
+func isType(obj core.PdfObject, name string) bool {
+	dict, ok := dictOf(obj)
+	if !ok {
+		return false
+	}
+	value, _ := core.GetNameVal(dict.Get("Type"))
+	return value == name
+}
+
+// hasKey indica si obj es un diccionario con la clave key
+func hasKey(obj core.PdfObject, key core.PdfObjectName) bool {
+	dict, ok := dictOf(obj)
+	return ok && dict.Get(key) != nil
+}
+
+// referenceNumber devuelve el número de objeto de una referencia, o -1
+func referenceNumber(obj core.PdfObject) int {
+	if ref, ok := obj.(*core.PdfObjectReference); ok {
+		return int(ref.ObjectNumber)
+	}
+	return -1
+}
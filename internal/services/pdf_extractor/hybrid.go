@@ -0,0 +1,57 @@
+package pdf_extractor
+
+import (
+	"context"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/pdf_extractor/ocr"
+)
+
+// MinPageQuality es la puntuación mínima para aceptar la capa de texto de una página
+const MinPageQuality = 0.5
+
+// pageOCR reconoce con OCR las páginas sin capa de texto válida, recordando las ya
+// reconocidas para no repetir el OCR si se prueba otra estrategia
+type pageOCR struct {
+	ocr  *ocr.Engine
+	path string
+	opts Options
+	done map[int]*ocr.Page
+}
+
+func newPageOCR(engine *ocr.Engine, path string, opts Options) *pageOCR {
+	return &pageOCR{ocr: engine, path: path, opts: opts, done: make(map[int]*ocr.Page)}
+}
+
+// fill sustituye en pages las páginas de baja calidad por su OCR
+// y devuelve los números de página (empezando en 1) que se han reconocido
+func (p *pageOCR) fill(ctx context.Context, pages []Page) ([]int, error) {
+	log := logger.FromContext(ctx)
+	var poor, pending []int
+	for i, page := range pages {
+		quality := ScoreText(page.Text, p.opts.languages())
+		if quality.Score >= MinPageQuality {
+			continue
+		}
+		log.Debug("Página %d con capa de texto insuficiente (puntuación %.2f)", i+1, quality.Score)
+		poor = append(poor, i+1)
+		if _, ok := p.done[i+1]; !ok {
+			pending = append(pending, i+1)
+		}
+	}
+
+	if len(pending) > 0 {
+		log.Info("Aplicando OCR a %d de %d páginas: %v", len(pending), len(pages), pending)
+		doc, err := p.ocr.ExtractPages(ctx, p.path, pending, p.opts.OCR)
+		if err != nil {
+			return nil, err
+		}
+		for i := range doc.Pages {
+			p.done[doc.Pages[i].Number] = &doc.Pages[i]
+		}
+	}
+
+	for _, number := range poor {
+		pages[number-1] = ocrPage(p.done[number], p.opts.Layout)
+	}
+	return poor, nil
+}
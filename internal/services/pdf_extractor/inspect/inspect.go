@@ -0,0 +1,364 @@
+// Package inspect describe la estructura de un PDF (páginas, metadatos, fuentes, adjuntos,
+// formularios y cifrado) leyéndolo con UniPDF, sin OCR ni llamadas al modelo.
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/extractor"
+	"github.com/unidoc/unipdf/v3/model"
+	"go_ocr/internal/services/logger"
+	"os"
+	"sort"
+	"time"
+	"unicode"
+)
+
+// maxFormDepth limita la recursión en los XObject de formulario al buscar fuentes
+const maxFormDepth = 5
+
+// Report es la descripción estructural de un PDF
+type Report struct {
+	Version          string       `json:"pdf_version"`
+	Encrypted        bool         `json:"encrypted"`
+	EncryptionMethod string       `json:"encryption_method,omitempty"`
+	Locked           bool         `json:"locked"` // Cifrado y sin contraseña válida: el resto de campos no está disponible
+	Pages            int          `json:"pages"`
+	Title            string       `json:"title,omitempty"`
+	Author           string       `json:"author,omitempty"`
+	Producer         string       `json:"producer,omitempty"`
+	Creator          string       `json:"creator,omitempty"`
+	CreationDate     *time.Time   `json:"creation_date,omitempty"`
+	ModifiedDate     *time.Time   `json:"modified_date,omitempty"`
+	PageDetails      []PageInfo   `json:"page_details"`
+	TextError        string       `json:"text_error,omitempty"` // Motivo por el que no se leyó la capa de texto
+	Fonts            []Font       `json:"fonts"`
+	Attachments      []Attachment `json:"attachments"`
+	FormFields       []FormField  `json:"form_fields"`
+}
+
+// PageInfo describe una página. Las medidas están en puntos (1/72 de pulgada).
+type PageInfo struct {
+	Number    int     `json:"page"`
+	Width     float64 `json:"width"`
+	Height    float64 `json:"height"`
+	Rotation  int64   `json:"rotation"`
+	TextLayer *bool   `json:"text_layer,omitempty"` // La página tiene texto extraíble; ausente si no se pudo leer
+	TextChars *int    `json:"text_chars,omitempty"` // Caracteres no blancos de la capa de texto
+}
+
+// Font es una fuente usada en el documento
+type Font struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Embedded bool   `json:"embedded"`
+	Pages    []int  `json:"pages"`
+}
+
+// Attachment es un archivo adjunto al PDF
+type Attachment struct {
+	Name        string `json:"name"`
+	Size        int    `json:"size"`
+	MimeType    string `json:"mime_type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// FormField es un campo de formulario (AcroForm)
+type FormField struct {
+	Name  string `json:"name"`
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// Inspect abre el PDF y describe su estructura. Si está cifrado se prueban la contraseña
+// vacía y passwords; si ninguna lo abre se devuelve un informe con Locked y sin contenido.
+// ctx se comprueba entre páginas.
+func Inspect(ctx context.Context, path string, passwords []string) (*Report, error) {
+	log := logger.FromContext(ctx)
+	startTime := time.Now()
+	log.Info("Iniciando inspección de PDF: %s", path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Error("Error al abrir archivo: %v", err)
+		return nil, fmt.Errorf("error al abrir archivo: %v", err)
+	}
+	defer f.Close()
+
+	pdfReader, err := model.NewPdfReader(f)
+	if err != nil {
+		log.Error("Error al crear PDF reader: %v", err)
+		return nil, fmt.Errorf("error al crear PDF reader: %v", err)
+	}
+
+	report := &Report{
+		Version:     pdfReader.PdfVersion().String(),
+		PageDetails: []PageInfo{},
+		Fonts:       []Font{},
+		Attachments: []Attachment{},
+		FormFields:  []FormField{},
+	}
+
+	report.Encrypted, err = pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, fmt.Errorf("error al comprobar cifrado: %v", err)
+	}
+	if report.Encrypted {
+		report.EncryptionMethod = pdfReader.GetEncryptionMethod()
+		unlocked, err := decrypt(pdfReader, passwords)
+		if err != nil {
+			return nil, err
+		}
+		if !unlocked {
+			log.Info("PDF cifrado sin contraseña válida, inspección limitada")
+			report.Locked = true
+			return report, nil
+		}
+	}
+
+	if err := report.readInfo(ctx, pdfReader); err != nil {
+		return nil, err
+	}
+	if err := report.readPages(ctx, pdfReader); err != nil {
+		return nil, err
+	}
+	if err := report.readAttachments(pdfReader); err != nil {
+		return nil, err
+	}
+	report.readFormFields(pdfReader)
+
+	log.With("stage", "inspect", "pages", report.Pages, "duration_ms", time.Since(startTime).Milliseconds()).
+		Info("Inspección completada. Páginas: %d, fuentes: %d, adjuntos: %d, campos: %d. Tiempo total: %v",
+			report.Pages, len(report.Fonts), len(report.Attachments), len(report.FormFields), time.Since(startTime))
+	return report, nil
+}
+
+// decrypt prueba la contraseña vacía y las candidatas
+func decrypt(pdfReader *model.PdfReader, passwords []string) (bool, error) {
+	for _, password := range append([]string{""}, passwords...) {
+		ok, err := pdfReader.Decrypt([]byte(password))
+		if err != nil {
+			return false, fmt.Errorf("error al descifrar: %v", err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// readInfo lee el diccionario de información del documento
+func (r *Report) readInfo(ctx context.Context, pdfReader *model.PdfReader) error {
+	log := logger.FromContext(ctx)
+	info, err := pdfReader.GetPdfInfo()
+	if err != nil {
+		// Muchos PDF no tienen diccionario Info; no es un error del documento
+		log.Debug("PDF sin información de documento: %v", err)
+		return nil
+	}
+
+	r.Title = pdfString(info.Title)
+	r.Author = pdfString(info.Author)
+	r.Producer = pdfString(info.Producer)
+	r.Creator = pdfString(info.Creator)
+	if info.CreationDate != nil {
+		t := info.CreationDate.ToGoTime()
+		r.CreationDate = &t
+	}
+	if info.ModifiedDate != nil {
+		t := info.ModifiedDate.ToGoTime()
+		r.ModifiedDate = &t
+	}
+	return nil
+}
+
+// readPages lee el tamaño, la capa de texto y las fuentes de cada página. Si el texto de
+// una página no se puede extraer (p. ej. UniPDF sin licencia) no se intenta en las demás:
+// la capa de texto queda desconocida y el motivo en TextError.
+func (r *Report) readPages(ctx context.Context, pdfReader *model.PdfReader) error {
+	log := logger.FromContext(ctx)
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return fmt.Errorf("error al obtener número de páginas: %v", err)
+	}
+	r.Pages = numPages
+
+	fonts := make(map[string]*Font)
+	for i := 1; i <= numPages; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		page, err := pdfReader.GetPage(i)
+		if err != nil {
+			return fmt.Errorf("error en página %d: %v", i, err)
+		}
+
+		info := PageInfo{Number: i}
+		if box, err := page.GetMediaBox(); err == nil {
+			info.Width, info.Height = box.Width(), box.Height()
+		}
+		info.Rotation, _ = page.GetRotate()
+
+		if r.TextError == "" {
+			if chars, err := pageChars(page); err == nil {
+				hasText := chars > 0
+				info.TextChars, info.TextLayer = &chars, &hasText
+			} else {
+				r.TextError = fmt.Sprintf("error al extraer texto de página %d: %v", i, err)
+				log.Warning("Capa de texto no disponible: %s", r.TextError)
+			}
+		}
+		r.PageDetails = append(r.PageDetails, info)
+
+		collectFonts(ctx, page.Resources, i, fonts, 0)
+	}
+
+	names := make([]string, 0, len(fonts))
+	for key := range fonts {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	for _, key := range names {
+		r.Fonts = append(r.Fonts, *fonts[key])
+	}
+	return nil
+}
+
+// pageChars cuenta los caracteres no blancos de la capa de texto de la página
+func pageChars(page *model.PdfPage) (int, error) {
+	ex, err := extractor.New(page)
+	if err != nil {
+		return 0, err
+	}
+	text, err := ex.ExtractText()
+	if err != nil {
+		return 0, err
+	}
+	return countChars(text), nil
+}
+
+// collectFonts añade a fonts las fuentes de los recursos de la página number, incluidas
+// las de sus XObject de formulario
+func collectFonts(ctx context.Context, resources *model.PdfPageResources, number int, fonts map[string]*Font, depth int) {
+	log := logger.FromContext(ctx)
+	if resources == nil || depth > maxFormDepth {
+		return
+	}
+
+	if dict, ok := core.GetDict(resources.Font); ok {
+		for _, name := range dict.Keys() {
+			obj, ok := resources.GetFontByName(name)
+			if !ok {
+				continue
+			}
+			font, err := model.NewPdfFontFromPdfObject(obj)
+			if err != nil {
+				log.Debug("Fuente %s ilegible en página %d: %v", name, number, err)
+				continue
+			}
+
+			key := font.BaseFont() + "/" + font.Subtype()
+			entry, ok := fonts[key]
+			if !ok {
+				entry = &Font{Name: font.BaseFont(), Type: font.Subtype(), Embedded: isEmbedded(font)}
+				fonts[key] = entry
+			}
+			if len(entry.Pages) == 0 || entry.Pages[len(entry.Pages)-1] != number {
+				entry.Pages = append(entry.Pages, number)
+			}
+		}
+	}
+
+	if dict, ok := core.GetDict(resources.XObject); ok {
+		for _, name := range dict.Keys() {
+			if _, kind := resources.GetXObjectByName(name); kind != model.XObjectTypeForm {
+				continue
+			}
+			form, err := resources.GetXObjectFormByName(name)
+			if err != nil || form == nil {
+				continue
+			}
+			collectFonts(ctx, form.Resources, number, fonts, depth+1)
+		}
+	}
+}
+
+// isEmbedded indica si el programa de la fuente va incluido en el PDF
+func isEmbedded(font *model.PdfFont) bool {
+	descriptor := font.FontDescriptor()
+	return descriptor != nil &&
+		(descriptor.FontFile != nil || descriptor.FontFile2 != nil || descriptor.FontFile3 != nil)
+}
+
+// readAttachments lista los archivos adjuntos
+func (r *Report) readAttachments(pdfReader *model.PdfReader) error {
+	files, err := pdfReader.GetAttachedFiles()
+	if err != nil {
+		return fmt.Errorf("error al leer adjuntos: %v", err)
+	}
+	for _, file := range files {
+		r.Attachments = append(r.Attachments, Attachment{
+			Name:        file.Name,
+			Size:        len(file.Content),
+			MimeType:    file.FileType,
+			Description: file.Description,
+		})
+	}
+	return nil
+}
+
+// readFormFields lista los campos terminales del formulario
+func (r *Report) readFormFields(pdfReader *model.PdfReader) {
+	if pdfReader.AcroForm == nil {
+		return
+	}
+	for _, field := range pdfReader.AcroForm.AllFields() {
+		if !field.IsTerminal() {
+			continue
+		}
+		name, err := field.FullName()
+		if err != nil {
+			name = field.PartialName()
+		}
+		entry := FormField{Name: name, Value: objectString(field.V)}
+		if field.FT != nil {
+			entry.Type = field.FT.String()
+		}
+		r.FormFields = append(r.FormFields, entry)
+	}
+}
+
+// pdfString devuelve el texto de una cadena PDF opcional
+func pdfString(s *core.PdfObjectString) string {
+	if s == nil {
+		return ""
+	}
+	return s.Decoded()
+}
+
+// objectString devuelve el valor de un objeto cadena o nombre
+func objectString(obj core.PdfObject) string {
+	if obj == nil {
+		return ""
+	}
+	if s, ok := core.GetString(obj); ok {
+		return s.Decoded()
+	}
+	if name, ok := core.GetNameVal(obj); ok {
+		return name
+	}
+	return ""
+}
+
+// countChars cuenta los caracteres no blancos del texto
+func countChars(text string) int {
+	count := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,76 @@
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePDF escribe un PDF mínimo de pages páginas con texto y devuelve su ruta
+func writePDF(t *testing.T, pages int) string {
+	t.Helper()
+	content := "BT /F1 12 Tf 72 720 Td (Liquido a percibir 1.234,56) Tj ET"
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // Páginas, cuando se conocen sus objetos
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	kids := ""
+	for i := 0; i < pages; i++ {
+		objects = append(objects, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 3 0 R /Resources << /Font << /F1 4 0 R >> >> >>")
+		kids += fmt.Sprintf("%d 0 R ", len(objects))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, pages)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	path := filepath.Join(t.TempDir(), "nomina.pdf")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Los tests se ejecutan sin licencia de UniPDF, así que la capa de texto no se puede leer
+func TestInspectWithoutTextLayer(t *testing.T) {
+	report, err := Inspect(context.Background(), writePDF(t, 3), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TextError == "" {
+		t.Skip("la capa de texto se pudo leer: UniPDF tiene licencia")
+	}
+
+	if report.Pages != 3 || len(report.PageDetails) != 3 {
+		t.Fatalf("páginas %d, detalles %d; se esperaban 3", report.Pages, len(report.PageDetails))
+	}
+	for _, page := range report.PageDetails {
+		// Desconocida, no "sin texto"
+		if page.TextLayer != nil || page.TextChars != nil {
+			t.Errorf("página %d: capa de texto %v, caracteres %v; se esperaba desconocida",
+				page.Number, page.TextLayer, page.TextChars)
+		}
+		if page.Width != 612 || page.Height != 792 {
+			t.Errorf("página %d: tamaño %vx%v", page.Number, page.Width, page.Height)
+		}
+	}
+	if len(report.Fonts) != 1 || report.Fonts[0].Name != "Helvetica" {
+		t.Errorf("fuentes inesperadas: %+v", report.Fonts)
+	}
+}
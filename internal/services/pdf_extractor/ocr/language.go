@@ -0,0 +1,51 @@
+package ocr
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopwords son palabras muy frecuentes y poco ambiguas de cada idioma soportado,
+// en minúsculas y sin tildes
+var stopwords = map[string][]string{
+	"spa": {"el", "la", "los", "las", "del", "de", "que", "y", "en", "por", "con", "para", "una", "es", "se", "su", "al", "como", "mas", "pero", "este", "esta", "trabajador", "empresa", "nomina", "liquido", "devengado", "deducciones", "periodo", "cotizacion"},
+	"cat": {"el", "la", "els", "les", "del", "de", "que", "i", "en", "per", "amb", "una", "es", "al", "com", "pero", "aquest", "aquesta", "treballador", "empresa", "nomina", "liquid", "meritat", "deduccions", "periode", "cotitzacio", "dels", "seva"},
+	"glg": {"o", "a", "os", "as", "do", "da", "dos", "das", "de", "que", "e", "en", "por", "con", "para", "unha", "un", "se", "ao", "como", "pero", "traballador", "empresa", "nomina", "liquido", "devengado", "deducions", "periodo", "cotizacion", "no", "na"},
+	"por": {"o", "a", "os", "as", "do", "da", "dos", "das", "de", "que", "e", "em", "por", "com", "para", "uma", "um", "se", "ao", "como", "mas", "trabalhador", "empresa", "recibo", "liquido", "vencimento", "descontos", "periodo", "contribuicao", "no", "na", "nao"},
+	"eus": {"eta", "da", "du", "ez", "bat", "ere", "baina", "edo", "langilea", "enpresa", "nomina", "likidoa", "sortua", "kenkariak", "aldia", "kotizazioa", "zenbatekoa", "guztira", "oinarria", "ordainsaria"},
+	"eng": {"the", "of", "and", "to", "in", "for", "is", "on", "that", "by", "with", "from", "employee", "employer", "payslip", "net", "gross", "pay", "deductions", "period", "total", "tax"},
+}
+
+// DetectLanguage devuelve el idioma candidato con más palabras frecuentes en el texto.
+// Si ninguno aparece devuelve una cadena vacía.
+func DetectLanguage(text string, candidates []string) string {
+	counts := make(map[string]int)
+	for _, token := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		token = stripAccents(token)
+		for _, lang := range candidates {
+			for _, word := range stopwords[lang] {
+				if token == word {
+					counts[lang]++
+					break
+				}
+			}
+		}
+	}
+
+	best, bestCount := "", 0
+	for _, lang := range candidates {
+		if counts[lang] > bestCount {
+			best, bestCount = lang, counts[lang]
+		}
+	}
+	return best
+}
+
+var accentReplacer = strings.NewReplacer(
+	"á", "a", "à", "a", "ã", "a", "â", "a", "é", "e", "è", "e", "ê", "e", "í", "i", "ï", "i",
+	"ó", "o", "ò", "o", "õ", "o", "ô", "o", "ú", "u", "ü", "u", "ç", "c",
+)
+
+func stripAccents(s string) string {
+	return accentReplacer.Replace(s)
+}
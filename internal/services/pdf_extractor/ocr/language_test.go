@@ -0,0 +1,83 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	all := DefaultOptions.Candidates
+	tests := []struct {
+		name       string
+		text       string
+		candidates []string
+		want       string
+	}{
+		{"español", "Líquido a percibir por el trabajador", all, "spa"},
+		{"catalán", "Líquid a percebre pel treballador", all, "cat"},
+		{"catalán en mayúsculas", "TREBALLADOR", all, "cat"},
+		{"gallego", "Traballador: Xoán Pereira", all, "glg"},
+		{"portugués", "Recibo de vencimento do trabalhador", all, "por"},
+		{"euskera", "Jasotzeko likidoa", all, "eus"},
+		{"inglés", "Net pay", all, "eng"},
+		{"con tildes", "NÒMINA de l'empresa", []string{"cat", "eng"}, "cat"},
+
+		// Con el mismo número de palabras gana el primer candidato
+		{"empate", "nómina", all, "spa"},
+		{"empate con otro orden", "nómina", []string{"glg", "spa"}, "glg"},
+
+		{"fuera de los candidatos", "Líquid a percebre", []string{"spa", "eng"}, ""},
+		{"sin candidatos", "Líquido a percibir", nil, ""},
+		{"solo cifras", "12345 6.789,00 %", all, ""},
+		{"vacío", "", all, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguage(tt.text, tt.candidates); got != tt.want {
+				t.Errorf("DetectLanguage(%q) = %q, se esperaba %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveLanguages(t *testing.T) {
+	sample := func(text string, err error) func(Options) (Page, error) {
+		return func(opts Options) (Page, error) {
+			if opts.Languages != "spa+cat+eng" {
+				t.Errorf("muestra reconocida con %q, se esperaban todos los candidatos", opts.Languages)
+			}
+			return newPage(1, []Word{{Text: text, Confidence: 90}}), err
+		}
+	}
+	opts := DefaultOptions
+	opts.AutoDetect = true
+	opts.Candidates = []string{"spa", "cat", "eng"}
+
+	tests := []struct {
+		name   string
+		opts   Options
+		sample func(Options) (Page, error)
+		want   string
+	}{
+		{"detectado", opts, sample("treballador", nil), "cat+eng"},
+		{"inglés sin repetir", opts, sample("payslip", nil), "eng"},
+		{"sin detectar", opts, sample("12345", nil), "spa+eng"},
+		{"muestra fallida", opts, sample("", errors.New("tesseract falló")), "spa+eng"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveLanguages(context.Background(), tt.opts, tt.sample); got.Languages != tt.want {
+				t.Errorf("idiomas %q, se esperaba %q", got.Languages, tt.want)
+			}
+		})
+	}
+
+	// Sin detección automática no se reconoce ninguna muestra
+	off := opts
+	off.AutoDetect = false
+	resolveLanguages(context.Background(), off, func(Options) (Page, error) {
+		t.Error("se reconoció una muestra sin detección automática")
+		return Page{}, nil
+	})
+}
@@ -0,0 +1,84 @@
+package ocr
+
+import (
+	"slices"
+	"strings"
+)
+
+// LayoutText reconstruye el texto de la página conservando su disposición, como
+// pdftotext -layout: las palabras se agrupan en filas por su posición vertical, aunque
+// Tesseract las asigne a bloques distintos (habitual en las columnas de importes), y cada
+// una se coloca en la columna de caracteres que corresponde a su posición horizontal
+func (p Page) LayoutText() string {
+	if len(p.Words) == 0 {
+		return ""
+	}
+
+	// Tamaño de carácter típico de la página, para pasar de píxeles a columnas y filas
+	charWidths := make([]float64, 0, len(p.Words))
+	heights := make([]float64, 0, len(p.Words))
+	for _, word := range p.Words {
+		if n := len([]rune(word.Text)); n > 0 && word.BBox.Width > 0 {
+			charWidths = append(charWidths, float64(word.BBox.Width)/float64(n))
+		}
+		heights = append(heights, float64(word.BBox.Height))
+	}
+	charWidth := max(median(charWidths), 1)
+	lineHeight := max(median(heights), 1)
+
+	words := slices.Clone(p.Words)
+	slices.SortStableFunc(words, func(a, b Word) int { return verticalCenter(a) - verticalCenter(b) })
+
+	// Agrupar en filas las palabras cuyo centro vertical está a menos de media línea
+	var rows [][]Word
+	for _, word := range words {
+		last := len(rows) - 1
+		if last >= 0 && float64(verticalCenter(word)-verticalCenter(rows[last][0])) <= lineHeight/2 {
+			rows[last] = append(rows[last], word)
+			continue
+		}
+		rows = append(rows, []Word{word})
+	}
+
+	var text strings.Builder
+	for i, row := range rows {
+		// Una separación vertical grande se conserva como línea en blanco
+		if i > 0 && float64(verticalCenter(row[0])-verticalCenter(rows[i-1][0])) > 2*lineHeight {
+			text.WriteString("\n")
+		}
+
+		slices.SortFunc(row, func(a, b Word) int { return a.BBox.Left - b.BBox.Left })
+		length := 0
+		for j, word := range row {
+			column := int(float64(word.BBox.Left) / charWidth)
+			if j > 0 {
+				// Las palabras cercanas van separadas por un único espacio, como en el
+				// texto original; solo los huecos amplios marcan un cambio de columna
+				prev := row[j-1].BBox
+				if float64(word.BBox.Left-prev.Left-prev.Width) < 1.5*charWidth {
+					column = length + 1
+				}
+				column = max(column, length+1)
+			}
+			text.WriteString(strings.Repeat(" ", max(column-length, 0)))
+			text.WriteString(word.Text)
+			length = max(column, length) + len([]rune(word.Text))
+		}
+		text.WriteString("\n")
+	}
+	return text.String()
+}
+
+func verticalCenter(w Word) int {
+	return w.BBox.Top + w.BBox.Height/2
+}
+
+// median devuelve la mediana de values, o 0 si está vacío
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
@@ -1,99 +1,397 @@
 package ocr
 
 import (
+	"context"
 	"fmt"
 	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/metrics"
+	"go_ocr/internal/services/pdf_extractor/doctype"
+	"go_ocr/internal/services/pdf_extractor/ocr/preprocess"
+	"go_ocr/internal/services/tracing"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
 var (
-	log = logger.NewLogger(false)
+	pagesProcessed = metrics.NewCounter("go_ocr_ocr_pages_total",
+		"Páginas de documentos reconocidas con OCR.")
+	// pageDuration incluye el preprocesado y la espera de un hueco para Tesseract, también
+	// de las pasadas de detección de idioma
+	pageDuration = metrics.NewHistogram("go_ocr_ocr_page_duration_seconds",
+		"Duración del reconocimiento de cada imagen con Tesseract.", metrics.DurationBuckets, "outcome")
 )
 
-func ExtractWithOCR(pdfPath string) (string, error) {
+// ExtractWithOCR reconoce cada página de un PDF escaneado o de una imagen (JPEG, PNG, TIFF)
+// y devuelve sus palabras con confianza y posición.
+// Los PDF se rasterizan con pdftoppm; las imágenes van directamente a Tesseract.
+// Los procesos externos se matan si ctx se cancela o vence.
+func (e *Engine) ExtractWithOCR(ctx context.Context, pdfPath string, opts Options) (*Document, error) {
+	ctx, span := tracing.Start(ctx, "ocr")
+	defer span.End()
+
+	startTime := time.Now()
+	doc, err := e.extractWithOCR(ctx, pdfPath, opts)
+	if err == nil {
+		span.SetAttributes(tracing.Int("pages", len(doc.Pages)), tracing.Float64("ocr.confidence", doc.Confidence))
+		pagesProcessed.Add(float64(len(doc.Pages)))
+	}
+	span.RecordError(err)
+	metrics.ObserveStage("ocr", startTime, err)
+	return doc, err
+}
+
+// extractWithOCR hace el reconocimiento de ExtractWithOCR
+func (e *Engine) extractWithOCR(ctx context.Context, pdfPath string, opts Options) (*Document, error) {
+	log := logger.FromContext(ctx)
 	startTime := time.Now()
 	log.Info("Iniciando extracción OCR para archivo: %s", pdfPath)
-	log.Debug("Parámetros de extractWithOCR - pdfPath: %s", pdfPath)
+	log.Debug("Parámetros de extractWithOCR - pdfPath: %s, opciones: %+v", pdfPath, opts)
 
 	// Validar que el archivo existe
 	if _, err := os.Stat(pdfPath); os.IsNotExist(err) {
-		log.Error("El archivo PDF no existe: %s", pdfPath)
-		return "", fmt.Errorf("el archivo PDF no existe: %s", pdfPath)
+		log.Error("El archivo no existe: %s", pdfPath)
+		return nil, fmt.Errorf("el archivo no existe: %s", pdfPath)
+	}
+
+	docType, err := doctype.Detect(pdfPath)
+	if err != nil {
+		log.Error("Error al detectar formato: %v", err)
+		return nil, fmt.Errorf("error al detectar formato: %v", err)
+	}
+
+	tempDir, cleanup, err := createTempDir(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer cleanup()
 
-	// Crear directorio temporal
+	// Cada página se rasteriza y reconoce por separado; el resultado queda en el orden de las páginas
+	var pages []Page
+	switch {
+	case docType == doctype.PDF:
+		var count int
+		count, err = e.pageCount(ctx, pdfPath)
+		if err == nil {
+			opts = e.detectPDFLanguage(ctx, pdfPath, tempDir, 1, opts)
+			log.Info("Procesando %d páginas del PDF con Tesseract OCR...", count)
+			pages, err = e.recognizePDFPages(ctx, pdfPath, tempDir, pageRange(count), opts)
+		}
+	case docType == doctype.TIFF:
+		pages, err = e.recognizeTIFF(ctx, pdfPath, tempDir, opts)
+	case docType.IsImage():
+		opts = resolveLanguages(ctx, opts, func(sample Options) (Page, error) {
+			return e.recognize(ctx, pdfPath, tempDir, 1, sample, 0)
+		})
+		log.Info("Procesando imagen con Tesseract OCR...")
+		var page Page
+		page, err = e.recognize(ctx, pdfPath, tempDir, 1, opts, 0)
+		pages = []Page{page}
+	default:
+		err = fmt.Errorf("formato no soportado para OCR")
+	}
+	if err != nil {
+		log.Error("Error en extracción OCR: %v", err)
+		return nil, err
+	}
+
+	doc := NewDocument(pages)
+	totalWords := 0
+	for _, page := range pages {
+		totalWords += len(page.Words)
+	}
+
+	if totalWords == 0 {
+		log.Error("No se pudo extraer texto con OCR. Páginas procesadas: %d", len(pages))
+		return nil, fmt.Errorf("no se pudo extraer texto con OCR")
+	}
+
+	log.With("stage", "ocr", "pages", len(pages), "duration_ms", time.Since(startTime).Milliseconds()).
+		Info("Extracción OCR completada. Páginas procesadas: %d, confianza media: %.1f. Tiempo total: %v",
+			len(pages), doc.Confidence, time.Since(startTime))
+
+	return doc, nil
+}
+
+// ExtractPages aplica OCR solo a las páginas indicadas de un PDF (empezando en 1)
+// y devuelve un documento con esas páginas en el mismo orden
+func (e *Engine) ExtractPages(ctx context.Context, pdfPath string, pages []int, opts Options) (*Document, error) {
+	ctx, span := tracing.Start(ctx, "ocr", tracing.Int("pages", len(pages)))
+	defer span.End()
+	log := logger.FromContext(ctx)
+	startTime := time.Now()
+	log.Info("Iniciando extracción OCR de %d páginas de %s: %v", len(pages), pdfPath, pages)
+
+	tempDir, cleanup, err := createTempDir(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer cleanup()
+
+	if len(pages) > 0 {
+		opts = e.detectPDFLanguage(ctx, pdfPath, tempDir, pages[0], opts)
+	}
+	results, err := e.recognizePDFPages(ctx, pdfPath, tempDir, pages, opts)
+	metrics.ObserveStage("ocr", startTime, err)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	pagesProcessed.Add(float64(len(results)))
+
+	doc := NewDocument(results)
+	span.SetAttributes(tracing.Float64("ocr.confidence", doc.Confidence))
+	log.With("stage", "ocr", "pages", len(pages), "duration_ms", time.Since(startTime).Milliseconds()).
+		Info("Extracción OCR de páginas completada. Confianza media: %.1f. Tiempo total: %v",
+			doc.Confidence, time.Since(startTime))
+	return doc, nil
+}
+
+// createTempDir crea un directorio temporal y devuelve la función que lo elimina
+func createTempDir(ctx context.Context) (string, func(), error) {
+	log := logger.FromContext(ctx)
 	tempDir, err := os.MkdirTemp("", "ocr_")
 	if err != nil {
 		log.Error("Error al crear directorio temporal: %v", err)
-		return "", fmt.Errorf("error al crear directorio temporal: %v", err)
+		return "", nil, fmt.Errorf("error al crear directorio temporal: %v", err)
 	}
-	defer func() {
+	log.Debug("Directorio temporal creado: %s", tempDir)
+
+	cleanup := func() {
 		if err := os.RemoveAll(tempDir); err != nil {
 			log.Error("Error al eliminar directorio temporal %s: %v", tempDir, err)
 		} else {
 			log.Debug("Directorio temporal eliminado: %s", tempDir)
 		}
-	}()
+	}
+	return tempDir, cleanup, nil
+}
+
+// recognizePDFPages rasteriza y reconoce las páginas indicadas (empezando en 1).
+// Cada página se renderiza con un nombre de archivo derivado de su número, así que el
+// orden del resultado no depende del orden del directorio ni del relleno de ceros de pdftoppm.
+func (e *Engine) recognizePDFPages(ctx context.Context, pdfPath, dir string, pages []int, opts Options) ([]Page, error) {
+	return runPages(ctx, e.workers, len(pages), func(i int) (Page, error) {
+		imgPath, err := e.renderPage(ctx, pdfPath, dir, pages[i], opts.DPI)
+		if err != nil {
+			return Page{}, err
+		}
+		return e.recognize(ctx, imgPath, dir, pages[i], opts, opts.DPI)
+	})
+}
+
+// recognizeTIFF reconoce cada página de un TIFF. Un TIFF de una página va directamente a
+// Tesseract; las páginas de un TIFF multipágina se decodifican a PNG en dir cuando se
+// reconocen, así que en disco y en memoria solo están las páginas en curso.
+func (e *Engine) recognizeTIFF(ctx context.Context, path, dir string, opts Options) ([]Page, error) {
+	log := logger.FromContext(ctx)
+	tf, err := openTIFF(path)
+	if err != nil {
+		return nil, err
+	}
+	defer tf.Close()
+
+	var first string // Primera página, ya decodificada si se detectó el idioma con ella
+	image := func(i int) (string, error) {
+		if len(tf.ifds) == 1 {
+			return path, nil
+		}
+		if i == 0 && first != "" {
+			return first, nil
+		}
+		out := filepath.Join(dir, fmt.Sprintf("tiff-%d.png", i+1))
+		return out, tf.writePage(i, out, opts.MaxPixels)
+	}
+	opts = resolveLanguages(ctx, opts, func(sample Options) (Page, error) {
+		imgPath, err := image(0)
+		if err != nil {
+			return Page{}, err
+		}
+		first = imgPath
+		return e.recognize(ctx, imgPath, dir, 1, sample, 0)
+	})
+	log.Info("Procesando %d páginas del TIFF con Tesseract OCR...", len(tf.ifds))
+	return runPages(ctx, e.workers, len(tf.ifds), func(i int) (Page, error) {
+		imgPath, err := image(i)
+		if err != nil {
+			return Page{}, err
+		}
+		return e.recognize(ctx, imgPath, dir, i+1, opts, 0)
+	})
+}
 
-	log.Info("Directorio temporal creado: %s", tempDir)
+// detectPDFLanguage detecta el idioma con una pasada a baja resolución de la página indicada
+func (e *Engine) detectPDFLanguage(ctx context.Context, pdfPath, dir string, page int, opts Options) Options {
+	return resolveLanguages(ctx, opts, func(sample Options) (Page, error) {
+		imgPath, err := e.renderPage(ctx, pdfPath, dir, page, detectionDPI)
+		if err != nil {
+			return Page{}, err
+		}
+		return e.recognize(ctx, imgPath, dir, page, sample, detectionDPI)
+	})
+}
 
-	// 1. Convertir PDF a imágenes (una por página)
-	log.Info("Convirtiendo PDF a imágenes...")
-	cmd := exec.Command("pdftoppm", "-png", "-r", "300", pdfPath, filepath.Join(tempDir, "page"))
-	log.Debug("Ejecutando comando: %v", cmd.Args)
+// resolveLanguages, si la detección automática está activada, reconoce una muestra con
+// todos los idiomas candidatos y sustituye los idiomas de opts por el detectado (más inglés,
+// habitual en términos bancarios). Si la detección falla se mantienen los idiomas configurados.
+func resolveLanguages(ctx context.Context, opts Options, recognizeSample func(Options) (Page, error)) Options {
+	log := logger.FromContext(ctx)
+	if !opts.AutoDetect || len(opts.Candidates) == 0 {
+		return opts
+	}
 
-	output, err := cmd.CombinedOutput()
+	sample := opts
+	sample.Languages = strings.Join(opts.Candidates, "+")
+	page, err := recognizeSample(sample)
 	if err != nil {
-		log.Error("Error al convertir PDF a imágenes: %v\nSalida: %s", err, string(output))
-		return "", fmt.Errorf("error al convertir PDF a imágenes: %v\nSalida: %s", err, string(output))
+		log.Warning("Error en la pasada de detección de idioma, se usa %s: %v", opts.Languages, err)
+		return opts
+	}
+
+	lang := DetectLanguage(page.Text(), opts.Candidates)
+	if lang == "" {
+		log.Warning("No se pudo detectar el idioma, se usa %s", opts.Languages)
+		return opts
+	}
+	if lang != "eng" {
+		lang += "+eng"
 	}
 
-	log.Debug("Conversión PDF a imágenes exitosa. Salida: %s", string(output))
+	log.Info("Idioma detectado para OCR: %s", lang)
+	opts.Languages = lang
+	return opts
+}
+
+// pageRange devuelve los números de página de 1 a n
+func pageRange(n int) []int {
+	pages := make([]int, n)
+	for i := range pages {
+		pages[i] = i + 1
+	}
+	return pages
+}
+
+// pageCount obtiene el número de páginas del PDF con pdfinfo
+func (e *Engine) pageCount(ctx context.Context, pdfPath string) (int, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("Ejecutando comando: pdfinfo %s", pdfPath)
 
-	// 2. Procesar cada imagen con Tesseract
-	log.Info("Procesando imágenes con Tesseract OCR...")
-	var text strings.Builder
-	files, err := os.ReadDir(tempDir)
+	output, stderr, err := e.run(ctx, "pdfinfo", pdfPath)
 	if err != nil {
-		log.Error("Error al leer directorio temporal: %v", err)
-		return "", fmt.Errorf("error al leer directorio temporal: %v", err)
+		log.Error("Error al obtener información del PDF: %v\nSalida: %s", err, stderr)
+		return 0, fmt.Errorf("error al obtener información del PDF: %v\nSalida: %s", err, stderr)
 	}
 
-	processedPages := 0
-	for _, file := range files {
-		if file.IsDir() || !strings.HasSuffix(file.Name(), ".png") {
-			log.Debug("Archivo ignorado (no es imagen PNG): %s", file.Name())
+	return parsePageCount(string(output))
+}
+
+// parsePageCount lee la línea "Pages:" de la salida de pdfinfo
+func parsePageCount(output string) (int, error) {
+	for _, line := range strings.Split(output, "\n") {
+		value, ok := strings.CutPrefix(line, "Pages:")
+		if !ok {
 			continue
 		}
+		count, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || count <= 0 {
+			return 0, fmt.Errorf("número de páginas inválido: %q", strings.TrimSpace(value))
+		}
+		return count, nil
+	}
+	return 0, fmt.Errorf("pdfinfo no informó el número de páginas")
+}
 
-		imgPath := filepath.Join(tempDir, file.Name())
-		log.Debug("Procesando página con OCR: %s", imgPath)
+// recognize preprocesa la imagen, ejecuta Tesseract sobre ella y devuelve las palabras
+// reconocidas como la página number. La imagen preprocesada se escribe en dir.
+// dpi es la resolución con la que se rasterizó la imagen, o 0 si se desconoce.
+func (e *Engine) recognize(ctx context.Context, imgPath, dir string, number int, opts Options, dpi int) (Page, error) {
+	ctx, span := tracing.Start(ctx, "ocr.page", tracing.Int("page", number), tracing.Int("dpi", dpi),
+		tracing.String("ocr.languages", opts.Languages))
+	defer span.End()
+	log := logger.FromContext(ctx)
+	startTime := time.Now()
+	log.Debug("Procesando página %d con OCR: %s", number, imgPath)
 
-		cmd := exec.Command("tesseract", imgPath, "-", "-l", "spa+eng")
-		output, err := cmd.CombinedOutput()
+	var report *preprocess.Report
+	if e.preprocess.Enabled() {
+		processedPath := filepath.Join(dir, strings.TrimSuffix(filepath.Base(imgPath), filepath.Ext(imgPath))+".pre.png")
+		result, err := preprocess.File(imgPath, processedPath, e.preprocess, opts.MaxPixels)
 		if err != nil {
-			log.Error("Error en OCR para %s: %v\nSalida: %s", imgPath, err, string(output))
-			return "", fmt.Errorf("error en OCR para %s: %v\nSalida: %s", imgPath, err, string(output))
+			// Una imagen que no se puede preprocesar se reconoce tal cual
+			log.Warning("Error al preprocesar %s, se usa la imagen original: %v", imgPath, err)
+		} else {
+			log.Debug("Imagen preprocesada %s: pasos %v, rotación %d°, inclinación %.1f°, tiempo %v",
+				imgPath, result.Steps, result.Rotation, result.Skew, result.Duration)
+			report = &result
+			imgPath = processedPath
 		}
+	}
 
-		text.Write(output)
-		text.WriteString("\n")
-		processedPages++
-		log.Debug("Página procesada exitosamente: %s", imgPath)
+	release, err := e.acquireProcess(ctx)
+	if err != nil {
+		span.RecordError(err)
+		pageDuration.Observe(time.Since(startTime).Seconds(), metrics.Outcome(err))
+		return Page{}, err
 	}
+	defer release()
 
-	if text.Len() == 0 {
-		log.Error("No se pudo extraer texto con OCR. Páginas procesadas: %d", processedPages)
-		return "", fmt.Errorf("no se pudo extraer texto con OCR")
+	// La salida TSV incluye la confianza y la caja de cada palabra
+	args := opts.tesseractArgs(imgPath, dpi)
+	log.Debug("Ejecutando comando: tesseract %v", args)
+	output, stderr, err := e.run(ctx, "tesseract", args...)
+	if err != nil {
+		log.Error("Error en OCR para %s: %v\nSalida: %s", imgPath, err, stderr)
+		err = fmt.Errorf("error en OCR para %s: %v\nSalida: %s", imgPath, err, stderr)
+		span.RecordError(err)
+		pageDuration.Observe(time.Since(startTime).Seconds(), metrics.Outcome(err))
+		return Page{}, err
 	}
 
-	log.Info("Extracción OCR completada. Páginas procesadas: %d. Tiempo total: %v",
-		processedPages, time.Since(startTime))
-	log.Debug("Texto extraído (primeros 100 caracteres): %.100q", text.String())
+	words, err := parseTSV(string(output))
+	if err != nil {
+		log.Error("Error al interpretar la salida de Tesseract para %s: %v", imgPath, err)
+		err = fmt.Errorf("error al interpretar la salida de Tesseract para %s: %v", imgPath, err)
+		span.RecordError(err)
+		pageDuration.Observe(time.Since(startTime).Seconds(), metrics.Outcome(err))
+		return Page{}, err
+	}
+
+	page := newPage(number, words)
+	page.Preprocess = report
+	span.SetAttributes(tracing.Int("ocr.words", len(words)), tracing.Float64("ocr.confidence", page.Confidence),
+		tracing.Bool("ocr.preprocessed", report != nil))
+	pageDuration.Observe(time.Since(startTime).Seconds(), metrics.Outcome(nil))
+	log.Debug("Página %d procesada exitosamente: %d palabras, confianza media %.1f", number, len(words), page.Confidence)
+	return page, nil
+}
+
+// renderPage convierte una única página del PDF en PNG dentro de dir con la resolución dpi
+func (e *Engine) renderPage(ctx context.Context, pdfPath, dir string, page, dpi int) (string, error) {
+	ctx, span := tracing.Start(ctx, "ocr.render", tracing.Int("page", page), tracing.Int("dpi", dpi))
+	defer span.End()
+	log := logger.FromContext(ctx)
+	prefix := filepath.Join(dir, fmt.Sprintf("page-%d-%ddpi", page, dpi))
+	args := []string{"-png", "-r", strconv.Itoa(dpi),
+		"-f", strconv.Itoa(page), "-l", strconv.Itoa(page), "-singlefile", pdfPath, prefix}
+	log.Debug("Ejecutando comando: pdftoppm %v", args)
+
+	release, err := e.acquireProcess(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return "", err
+	}
+	defer release()
+
+	_, stderr, err := e.run(ctx, "pdftoppm", args...)
+	if err != nil {
+		log.Error("Error al convertir página %d a imagen: %v\nSalida: %s", page, err, stderr)
+		err = fmt.Errorf("error al convertir página %d a imagen: %v\nSalida: %s", page, err, stderr)
+		span.RecordError(err)
+		return "", err
+	}
 
-	return text.String(), nil
+	return prefix + ".png", nil
 }
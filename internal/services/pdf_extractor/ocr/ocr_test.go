@@ -0,0 +1,149 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tsvHeader es la cabecera de la salida TSV de Tesseract
+const tsvHeader = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n"
+
+// fakeRunner simula pdftoppm y tesseract. pdftoppm escribe en la imagen la página y la
+// resolución pedidas; tesseract las devuelve como única palabra tras una espera aleatoria,
+// para que las páginas terminen desordenadas.
+type fakeRunner struct {
+	mu      sync.Mutex
+	renders [][]string // Argumentos de cada llamada a pdftoppm
+}
+
+func (f *fakeRunner) run(ctx context.Context, name string, args ...string) ([]byte, string, error) {
+	switch name {
+	case "pdftoppm":
+		f.mu.Lock()
+		f.renders = append(f.renders, args)
+		f.mu.Unlock()
+		prefix := args[len(args)-1]
+		word := fmt.Sprintf("pagina-%s-%sdpi", argValue(args, "-f"), argValue(args, "-r"))
+		return nil, "", os.WriteFile(prefix+".png", []byte(word), 0o600)
+	case "tesseract":
+		time.Sleep(time.Duration(rand.Intn(2000)) * time.Microsecond)
+		word, err := os.ReadFile(args[0])
+		if err != nil {
+			return nil, err.Error(), err
+		}
+		return []byte(tsvHeader + "5\t1\t1\t1\t1\t1\t10\t10\t50\t20\t95\t" + string(word) + "\n"), "", nil
+	}
+	return nil, "", fmt.Errorf("comando inesperado: %s", name)
+}
+
+// argValue devuelve el argumento que sigue a flag, o ""
+func argValue(args []string, flag string) string {
+	for i, arg := range args[:len(args)-1] {
+		if arg == flag {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// newFakeEngine devuelve un Engine que ejecuta los comandos con run, procesa varias páginas
+// en paralelo y no preprocesa, porque el preprocesado no sabe leer las imágenes falsas
+func newFakeEngine(run commandFunc) *Engine {
+	return newEngine(run, Config{Workers: 4, MaxProcesses: 4})
+}
+
+func TestRecognizePDFPagesMapsFilesToPages(t *testing.T) {
+	fake := &fakeRunner{}
+	engine := newFakeEngine(fake.run)
+	dir := t.TempDir()
+	// 1 y 10 comparten prefijo: un nombre de archivo ambiguo mezclaría sus imágenes
+	pages := []int{10, 1, 7, 2, 11}
+	opts := DefaultOptions
+
+	results, err := engine.recognizePDFPages(context.Background(), "nomina.pdf", dir, pages, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != len(pages) {
+		t.Fatalf("se esperaban %d páginas, hay %d", len(pages), len(results))
+	}
+	for i, page := range results {
+		want := fmt.Sprintf("pagina-%d-%ddpi", pages[i], opts.DPI)
+		if page.Number != pages[i] || strings.TrimSpace(page.Text()) != want {
+			t.Errorf("resultado %d: página %d con texto %q, se esperaba la página %d con %q",
+				i, page.Number, page.Text(), pages[i], want)
+		}
+	}
+
+	// Cada página se rasteriza sola, con -singlefile, en un archivo con su número y resolución
+	if len(fake.renders) != len(pages) {
+		t.Fatalf("pdftoppm se llamó %d veces, se esperaban %d", len(fake.renders), len(pages))
+	}
+	rendered := make(map[string]bool)
+	for _, args := range fake.renders {
+		page, _ := strconv.Atoi(argValue(args, "-f"))
+		want := filepath.Join(dir, fmt.Sprintf("page-%d-%ddpi", page, opts.DPI))
+		if argValue(args, "-l") != argValue(args, "-f") || argValue(args, "-r") != strconv.Itoa(opts.DPI) ||
+			args[len(args)-1] != want || args[len(args)-2] != "nomina.pdf" || !contains(args, "-singlefile") {
+			t.Errorf("argumentos de pdftoppm inesperados: %v", args)
+		}
+		rendered[args[len(args)-1]] = true
+	}
+	if len(rendered) != len(pages) {
+		t.Errorf("se rasterizaron %d archivos distintos, se esperaban %d", len(rendered), len(pages))
+	}
+}
+
+func TestExtractPagesKeepsDetectionRenderSeparate(t *testing.T) {
+	fake := &fakeRunner{}
+	engine := newFakeEngine(fake.run)
+	opts := DefaultOptions
+	opts.AutoDetect = true
+
+	doc, err := engine.ExtractPages(context.Background(), "nomina.pdf", []int{3, 1}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// La pasada de detección rasteriza la primera página pedida a menos resolución en el
+	// mismo directorio; el resultado final no puede leer esa imagen
+	for i, number := range []int{3, 1} {
+		want := fmt.Sprintf("pagina-%d-%ddpi", number, opts.DPI)
+		if doc.Pages[i].Number != number || strings.TrimSpace(doc.Pages[i].Text()) != want {
+			t.Errorf("resultado %d: página %d con texto %q, se esperaba la página %d con %q",
+				i, doc.Pages[i].Number, doc.Pages[i].Text(), number, want)
+		}
+	}
+	var detection int
+	for _, args := range fake.renders {
+		if argValue(args, "-r") == strconv.Itoa(detectionDPI) {
+			detection++
+			if want := fmt.Sprintf("page-3-%ddpi", detectionDPI); filepath.Base(args[len(args)-1]) != want {
+				t.Errorf("prefijo de la pasada de detección %q, se esperaba %q", filepath.Base(args[len(args)-1]), want)
+			}
+		}
+	}
+	if detection != 1 || len(fake.renders) != 3 {
+		t.Errorf("pdftoppm se llamó %d veces (%d de detección), se esperaban 3 (1 de detección)",
+			len(fake.renders), detection)
+	}
+}
+
+// contains indica si args incluye arg
+func contains(args []string, arg string) bool {
+	for _, a := range args {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}
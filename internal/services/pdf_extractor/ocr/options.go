@@ -0,0 +1,95 @@
+package ocr
+
+import (
+	"fmt"
+	"go_ocr/internal/services/pdf_extractor/ocr/preprocess"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Options configura la rasterización y el reconocimiento de Tesseract
+type Options struct {
+	Languages   string   `json:"languages"`              // Idiomas de Tesseract separados por '+', p. ej. "spa+eng"
+	DPI         int      `json:"dpi"`                    // Resolución de rasterizado de los PDF
+	PSM         int      `json:"psm"`                    // Page segmentation mode (0-13)
+	OEM         int      `json:"oem"`                    // OCR engine mode (0-3)
+	TessdataDir string   `json:"tessdata_dir,omitempty"` // Directorio de modelos; vacío usa el de la instalación
+	Whitelist   string   `json:"whitelist,omitempty"`    // Caracteres permitidos; vacío permite todos
+	AutoDetect  bool     `json:"auto_detect"`            // Detectar el idioma con una primera pasada a baja resolución
+	Candidates  []string `json:"candidates,omitempty"`   // Idiomas entre los que elige la detección automática
+	MaxPixels   int      `json:"max_pixels"`             // Máximo de píxeles de una imagen decodificada en el servidor
+}
+
+// DefaultOptions son las opciones usadas si no se configuran otras
+var DefaultOptions = Options{
+	Languages:  "spa+eng",
+	DPI:        300,
+	PSM:        3,
+	OEM:        3,
+	Candidates: []string{"spa", "cat", "eus", "glg", "por", "eng"},
+	MaxPixels:  preprocess.DefaultMaxPixels,
+}
+
+// Límites de las opciones configurables por petición
+const (
+	minDPI       = 70
+	maxDPI       = 600
+	maxWhitelist = 256
+
+	// detectionDPI es la resolución de la pasada de detección de idioma
+	detectionDPI = 150
+)
+
+var languagesRegex = regexp.MustCompile(`^[a-z][a-z_]{2,}(\+[a-z][a-z_]{2,})*$`)
+
+// Validate comprueba que las opciones son aceptables antes de pasarlas a los comandos
+func (o Options) Validate() error {
+	if !languagesRegex.MatchString(o.Languages) {
+		return fmt.Errorf("idiomas de OCR inválidos: %q", o.Languages)
+	}
+	if o.DPI < minDPI || o.DPI > maxDPI {
+		return fmt.Errorf("DPI de OCR fuera de rango (%d-%d): %d", minDPI, maxDPI, o.DPI)
+	}
+	if o.PSM < 0 || o.PSM > 13 {
+		return fmt.Errorf("PSM de OCR fuera de rango (0-13): %d", o.PSM)
+	}
+	if o.OEM < 0 || o.OEM > 3 {
+		return fmt.Errorf("OEM de OCR fuera de rango (0-3): %d", o.OEM)
+	}
+	if len(o.Whitelist) > maxWhitelist {
+		return fmt.Errorf("lista de caracteres de OCR demasiado larga: %d", len(o.Whitelist))
+	}
+	for _, r := range o.Whitelist {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("lista de caracteres de OCR con caracteres no imprimibles")
+		}
+	}
+	if o.MaxPixels <= 0 {
+		return fmt.Errorf("máximo de píxeles de OCR inválido: %d", o.MaxPixels)
+	}
+	for _, lang := range o.Candidates {
+		if !languagesRegex.MatchString(lang) || strings.Contains(lang, "+") {
+			return fmt.Errorf("idioma candidato inválido: %q", lang)
+		}
+	}
+	return nil
+}
+
+// tesseractArgs construye los argumentos de Tesseract para reconocer imgPath en formato TSV.
+// dpi se indica solo para las imágenes rasterizadas por nosotros (0 si se desconoce).
+func (o Options) tesseractArgs(imgPath string, dpi int) []string {
+	args := []string{imgPath, "-", "-l", o.Languages,
+		"--psm", strconv.Itoa(o.PSM), "--oem", strconv.Itoa(o.OEM)}
+	if dpi > 0 {
+		args = append(args, "--dpi", strconv.Itoa(dpi))
+	}
+	if o.TessdataDir != "" {
+		args = append(args, "--tessdata-dir", o.TessdataDir)
+	}
+	if o.Whitelist != "" {
+		args = append(args, "-c", "tessedit_char_whitelist="+o.Whitelist)
+	}
+	return append(args, "tsv")
+}
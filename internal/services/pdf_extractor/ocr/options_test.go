@@ -0,0 +1,70 @@
+package ocr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Options)
+		valid  bool
+	}{
+		{"por defecto", func(o *Options) {}, true},
+		{"idioma con guion bajo", func(o *Options) { o.Languages = "chi_sim+spa" }, true},
+		{"límites de DPI", func(o *Options) { o.DPI = minDPI }, true},
+		{"PSM y OEM máximos", func(o *Options) { o.PSM, o.OEM = 13, 3 }, true},
+
+		{"DPI bajo", func(o *Options) { o.DPI = minDPI - 1 }, false},
+		{"DPI alto", func(o *Options) { o.DPI = maxDPI + 1 }, false},
+		{"PSM negativo", func(o *Options) { o.PSM = -1 }, false},
+		{"PSM alto", func(o *Options) { o.PSM = 14 }, false},
+		{"OEM negativo", func(o *Options) { o.OEM = -1 }, false},
+		{"OEM alto", func(o *Options) { o.OEM = 4 }, false},
+		{"sin píxeles", func(o *Options) { o.MaxPixels = 0 }, false},
+
+		// Los idiomas van tras -l: no pueden colar otros argumentos de Tesseract
+		{"idiomas vacíos", func(o *Options) { o.Languages = "" }, false},
+		{"idioma con opción", func(o *Options) { o.Languages = "spa --tessdata-dir /tmp" }, false},
+		{"idioma que es una opción", func(o *Options) { o.Languages = "--psm" }, false},
+		{"idioma con -c", func(o *Options) { o.Languages = "spa -c tessedit_write_images=1" }, false},
+		{"idioma con ruta", func(o *Options) { o.Languages = "../../tmp/spa" }, false},
+		{"idioma con salto de línea", func(o *Options) { o.Languages = "spa\neng" }, false},
+		{"idioma con punto y coma", func(o *Options) { o.Languages = "spa;eng" }, false},
+		{"idioma en mayúsculas", func(o *Options) { o.Languages = "SPA" }, false},
+		{"idioma corto", func(o *Options) { o.Languages = "es" }, false},
+		{"signo + final", func(o *Options) { o.Languages = "spa+" }, false},
+		{"candidato compuesto", func(o *Options) { o.Candidates = []string{"spa+eng"} }, false},
+		{"candidato con opción", func(o *Options) { o.Candidates = []string{"spa", "-l"} }, false},
+
+		{"whitelist larga", func(o *Options) { o.Whitelist = strings.Repeat("a", maxWhitelist+1) }, false},
+		{"whitelist con controles", func(o *Options) { o.Whitelist = "0123\n456" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions
+			opts.Candidates = append([]string(nil), DefaultOptions.Candidates...)
+			tt.modify(&opts)
+			if err := opts.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, se esperaba válido: %t", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestTesseractArgs(t *testing.T) {
+	opts := DefaultOptions
+	opts.Whitelist = "0123456789 -c x"
+	args := opts.tesseractArgs("page.png", 300)
+
+	want := []string{"page.png", "-", "-l", "spa+eng", "--psm", "3", "--oem", "3", "--dpi", "300",
+		"-c", "tessedit_char_whitelist=0123456789 -c x", "tsv"}
+	if strings.Join(args, "|") != strings.Join(want, "|") {
+		t.Errorf("argumentos %q, se esperaba %q", args, want)
+	}
+
+	if args := DefaultOptions.tesseractArgs("page.png", 0); contains(args, "--dpi") {
+		t.Errorf("se indicó la resolución de una imagen de origen desconocido: %q", args)
+	}
+}
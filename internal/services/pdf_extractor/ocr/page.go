@@ -0,0 +1,215 @@
+package ocr
+
+import (
+	"fmt"
+	"go_ocr/internal/services/pdf_extractor/ocr/preprocess"
+	"strconv"
+	"strings"
+)
+
+// LowWordConfidence es la confianza (0-100) por debajo de la cual una palabra se
+// considera dudosa
+const LowWordConfidence = 60
+
+// BBox es la caja de una palabra o región en píxeles de la imagen reconocida
+type BBox struct {
+	Left   int `json:"left"`
+	Top    int `json:"top"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// union devuelve la caja mínima que contiene a ambas
+func (b BBox) union(o BBox) BBox {
+	left, top := min(b.Left, o.Left), min(b.Top, o.Top)
+	right := max(b.Left+b.Width, o.Left+o.Width)
+	bottom := max(b.Top+b.Height, o.Top+o.Height)
+	return BBox{Left: left, Top: top, Width: right - left, Height: bottom - top}
+}
+
+// Word es una palabra reconocida por Tesseract
+type Word struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+	BBox       BBox    `json:"bbox"`
+	Block      int     `json:"block"`
+	Paragraph  int     `json:"paragraph"`
+	Line       int     `json:"line"`
+}
+
+// Page es el resultado del OCR de una página
+type Page struct {
+	Number     int                `json:"page"`
+	Confidence float64            `json:"confidence"` // Confianza media de las palabras (0-100)
+	Words      []Word             `json:"-"`
+	Preprocess *preprocess.Report `json:"preprocess,omitempty"`
+}
+
+// Region es un fragmento de línea con palabras de baja confianza
+type Region struct {
+	Page       int     `json:"page"`
+	BBox       BBox    `json:"bbox"`
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Document agrupa las páginas reconocidas de un documento
+type Document struct {
+	Pages      []Page  `json:"pages"`
+	Confidence float64 `json:"confidence"` // Confianza media de todas las palabras (0-100)
+}
+
+// NewDocument crea un documento y calcula su confianza media ponderada por palabras
+func NewDocument(pages []Page) *Document {
+	doc := &Document{Pages: pages}
+	var sum float64
+	words := 0
+	for _, page := range pages {
+		for _, word := range page.Words {
+			sum += word.Confidence
+			words++
+		}
+	}
+	if words > 0 {
+		doc.Confidence = sum / float64(words)
+	}
+	return doc
+}
+
+// Texts devuelve el texto de cada página en orden
+func (d *Document) Texts() []string {
+	texts := make([]string, len(d.Pages))
+	for i, page := range d.Pages {
+		texts[i] = page.Text()
+	}
+	return texts
+}
+
+// LowConfidenceRegions agrupa en regiones las palabras consecutivas de una misma línea
+// con confianza inferior a threshold
+func (d *Document) LowConfidenceRegions(threshold float64) []Region {
+	var regions []Region
+	for _, page := range d.Pages {
+		var current *Region
+		var words int
+		flush := func() {
+			if current != nil {
+				current.Confidence /= float64(words)
+				regions = append(regions, *current)
+				current = nil
+			}
+		}
+
+		for i, word := range page.Words {
+			if word.Confidence >= threshold {
+				flush()
+				continue
+			}
+			if current != nil && !sameLine(page.Words[i-1], word) {
+				flush()
+			}
+			if current == nil {
+				current = &Region{Page: page.Number, BBox: word.BBox, Text: word.Text, Confidence: word.Confidence}
+				words = 1
+				continue
+			}
+			current.BBox = current.BBox.union(word.BBox)
+			current.Text += " " + word.Text
+			current.Confidence += word.Confidence
+			words++
+		}
+		flush()
+	}
+	return regions
+}
+
+// Text reconstruye el texto de la página: palabras separadas por espacios, líneas por
+// saltos de línea y párrafos por una línea en blanco
+func (p Page) Text() string {
+	var text strings.Builder
+	for i, word := range p.Words {
+		if i > 0 {
+			prev := p.Words[i-1]
+			switch {
+			case prev.Block != word.Block || prev.Paragraph != word.Paragraph:
+				text.WriteString("\n\n")
+			case prev.Line != word.Line:
+				text.WriteString("\n")
+			default:
+				text.WriteString(" ")
+			}
+		}
+		text.WriteString(word.Text)
+	}
+	if text.Len() > 0 {
+		text.WriteString("\n")
+	}
+	return text.String()
+}
+
+func sameLine(a, b Word) bool {
+	return a.Block == b.Block && a.Paragraph == b.Paragraph && a.Line == b.Line
+}
+
+// tsvWordLevel es el nivel de las filas de palabras en la salida TSV de Tesseract
+const tsvWordLevel = 5
+
+// parseTSV interpreta la salida TSV de Tesseract y devuelve sus palabras en orden
+func parseTSV(output string) ([]Word, error) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "level") {
+		return nil, fmt.Errorf("salida TSV sin cabecera")
+	}
+
+	var words []Word
+	for n, line := range lines[1:] {
+		fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(fields) < 12 {
+			continue
+		}
+
+		level, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("línea TSV %d inválida: %q", n+2, line)
+		}
+		text := strings.TrimSpace(fields[11])
+		if level != tsvWordLevel || text == "" {
+			continue
+		}
+
+		var ints [8]int
+		for i := range ints {
+			ints[i], err = strconv.Atoi(fields[i+2])
+			if err != nil {
+				return nil, fmt.Errorf("línea TSV %d inválida: %q", n+2, line)
+			}
+		}
+		confidence, err := strconv.ParseFloat(fields[10], 64)
+		if err != nil {
+			return nil, fmt.Errorf("confianza inválida en línea TSV %d: %q", n+2, fields[10])
+		}
+
+		words = append(words, Word{
+			Text:       text,
+			Confidence: max(confidence, 0),
+			Block:      ints[0],
+			Paragraph:  ints[1],
+			Line:       ints[2],
+			BBox:       BBox{Left: ints[4], Top: ints[5], Width: ints[6], Height: ints[7]},
+		})
+	}
+	return words, nil
+}
+
+// newPage crea una página a partir de sus palabras y calcula su confianza media
+func newPage(number int, words []Word) Page {
+	page := Page{Number: number, Words: words}
+	if len(words) > 0 {
+		var sum float64
+		for _, word := range words {
+			sum += word.Confidence
+		}
+		page.Confidence = sum / float64(len(words))
+	}
+	return page
+}
@@ -0,0 +1,132 @@
+package ocr
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tsv construye una salida de Tesseract con la cabecera y las filas indicadas
+func tsv(rows ...string) string {
+	return tsvHeader + strings.Join(rows, "\n") + "\n"
+}
+
+func TestParseTSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    []Word
+		wantErr bool
+	}{
+		{
+			// Las filas de página, bloque, párrafo y línea llevan conf -1 y no tienen texto
+			name: "filas de estructura con conf -1",
+			output: tsv(
+				"1\t1\t0\t0\t0\t0\t0\t0\t2480\t3508\t-1\t",
+				"2\t1\t1\t0\t0\t0\t100\t120\t800\t60\t-1\t",
+				"3\t1\t1\t1\t0\t0\t100\t120\t800\t60\t-1\t",
+				"4\t1\t1\t1\t1\t0\t100\t120\t800\t30\t-1\t",
+				"5\t1\t1\t1\t1\t1\t100\t120\t150\t30\t96.5\tLíquido",
+				"5\t1\t1\t1\t1\t2\t260\t120\t60\t30\t91\ta",
+				"4\t1\t1\t1\t2\t0\t100\t160\t300\t30\t-1\t",
+				"5\t1\t1\t1\t2\t1\t100\t160\t300\t30\t42.25\t1.564,14",
+			),
+			want: []Word{
+				{Text: "Líquido", Confidence: 96.5, Block: 1, Paragraph: 1, Line: 1, BBox: BBox{100, 120, 150, 30}},
+				{Text: "a", Confidence: 91, Block: 1, Paragraph: 1, Line: 1, BBox: BBox{260, 120, 60, 30}},
+				{Text: "1.564,14", Confidence: 42.25, Block: 1, Paragraph: 1, Line: 2, BBox: BBox{100, 160, 300, 30}},
+			},
+		},
+		{
+			name:   "palabra con conf -1",
+			output: tsv("5\t1\t2\t1\t3\t1\t10\t20\t30\t40\t-1\t~"),
+			want:   []Word{{Text: "~", Confidence: 0, Block: 2, Paragraph: 1, Line: 3, BBox: BBox{10, 20, 30, 40}}},
+		},
+		{
+			name: "palabras vacías y filas incompletas",
+			output: tsv(
+				"5\t1\t1\t1\t1\t1\t10\t20\t30\t40\t0\t   ",
+				"5\t1\t1\t1\t1\t2\t10\t20",
+				"",
+				"5\t1\t1\t1\t1\t3\t50\t20\t30\t40\t88\tbase",
+			),
+			want: []Word{{Text: "base", Confidence: 88, Block: 1, Paragraph: 1, Line: 1, BBox: BBox{50, 20, 30, 40}}},
+		},
+		{
+			name:   "fin de línea de Windows",
+			output: strings.ReplaceAll(tsv("5\t1\t1\t1\t1\t1\t10\t20\t30\t40\t75\tIRPF"), "\n", "\r\n"),
+			want:   []Word{{Text: "IRPF", Confidence: 75, Block: 1, Paragraph: 1, Line: 1, BBox: BBox{10, 20, 30, 40}}},
+		},
+		{name: "sin palabras", output: tsvHeader, want: nil},
+
+		{name: "sin cabecera", output: "5\t1\t1\t1\t1\t1\t10\t20\t30\t40\t75\tIRPF\n", wantErr: true},
+		{name: "vacía", output: "", wantErr: true},
+		{name: "nivel no numérico", output: tsv("x\t1\t1\t1\t1\t1\t10\t20\t30\t40\t75\tIRPF"), wantErr: true},
+		{name: "coordenada no numérica", output: tsv("5\t1\t1\t1\t1\t1\t10\tarriba\t30\t40\t75\tIRPF"), wantErr: true},
+		{name: "confianza no numérica", output: tsv("5\t1\t1\t1\t1\t1\t10\t20\t30\t40\talta\tIRPF"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			words, err := parseTSV(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, se esperaba error: %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(words, tt.want) {
+				t.Errorf("palabras %+v, se esperaba %+v", words, tt.want)
+			}
+		})
+	}
+}
+
+// word crea una palabra de la línea line del primer párrafo con la caja en x
+func word(text string, confidence float64, line, x int) Word {
+	return Word{Text: text, Confidence: confidence, Block: 1, Paragraph: 1, Line: line,
+		BBox: BBox{Left: x, Top: line * 40, Width: 50, Height: 30}}
+}
+
+func TestNewPage(t *testing.T) {
+	page := newPage(2, []Word{word("a", 90, 1, 0), word("b", 60, 1, 60), word("c", 30, 2, 0)})
+	if page.Number != 2 || page.Confidence != 60 {
+		t.Errorf("página %d con confianza %.2f, se esperaba la 2 con 60", page.Number, page.Confidence)
+	}
+	if empty := newPage(1, nil); empty.Confidence != 0 {
+		t.Errorf("confianza %.2f en una página sin palabras", empty.Confidence)
+	}
+
+	// La confianza del documento se pondera por palabras, no por páginas
+	doc := NewDocument([]Page{page, newPage(3, []Word{word("d", 100, 1, 0)})})
+	if doc.Confidence != 70 {
+		t.Errorf("confianza del documento %.2f, se esperaba 70", doc.Confidence)
+	}
+}
+
+func TestLowConfidenceRegions(t *testing.T) {
+	doc := NewDocument([]Page{
+		newPage(1, []Word{
+			word("Salario", 95, 1, 0),
+			word("1.52O,OO", 40, 1, 60), // Dos palabras dudosas seguidas forman una región
+			word("€", 20, 1, 120),
+			word("Plus", 90, 1, 180), // Una palabra fiable corta la región
+			word("l2O", 50, 1, 240),
+			word("convenio", 30, 2, 0), // Y también el cambio de línea
+			word("base", 60, 2, 60),    // Una confianza igual al umbral no es dudosa
+		}),
+		newPage(2, []Word{
+			word("IRPF", 10, 1, 0), // Las regiones no cruzan páginas
+		}),
+	})
+
+	want := []Region{
+		{Page: 1, BBox: BBox{Left: 60, Top: 40, Width: 110, Height: 30}, Text: "1.52O,OO €", Confidence: 30},
+		{Page: 1, BBox: BBox{Left: 240, Top: 40, Width: 50, Height: 30}, Text: "l2O", Confidence: 50},
+		{Page: 1, BBox: BBox{Left: 0, Top: 80, Width: 50, Height: 30}, Text: "convenio", Confidence: 30},
+		{Page: 2, BBox: BBox{Left: 0, Top: 40, Width: 50, Height: 30}, Text: "IRPF", Confidence: 10},
+	}
+	if got := doc.LowConfidenceRegions(LowWordConfidence); !reflect.DeepEqual(got, want) {
+		t.Errorf("regiones:\n%+v\nse esperaba:\n%+v", got, want)
+	}
+
+	if got := doc.LowConfidenceRegions(5); len(got) != 0 {
+		t.Errorf("regiones %+v con un umbral por debajo de todas las palabras", got)
+	}
+}
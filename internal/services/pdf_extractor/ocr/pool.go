@@ -0,0 +1,126 @@
+package ocr
+
+import (
+	"context"
+	"go_ocr/internal/services/metrics"
+	"go_ocr/internal/services/pdf_extractor/ocr/preprocess"
+	"go_ocr/internal/services/runner"
+	"runtime"
+	"sync"
+)
+
+// processesInFlight es el número de procesos de pdftoppm/tesseract en marcha
+var processesInFlight = metrics.NewGauge("go_ocr_ocr_processes_in_flight",
+	"Procesos de pdftoppm y tesseract en ejecución en todo el servidor.")
+
+// Config es el paralelismo y el preprocesado del OCR
+type Config struct {
+	Workers      int                // Páginas de un documento en paralelo; <= 0 usa GOMAXPROCS
+	MaxProcesses int                // Procesos de pdftoppm/tesseract simultáneos en total; <= 0 usa GOMAXPROCS
+	Preprocess   preprocess.Options // Pasos de preprocesado aplicados antes de Tesseract
+}
+
+// commandFunc ejecuta un comando externo y devuelve su salida estándar y de errores
+type commandFunc func(ctx context.Context, name string, args ...string) ([]byte, string, error)
+
+// Engine reconoce documentos con pdftoppm y tesseract. El límite de procesos externos es
+// del Engine: todas las peticiones que lo usan lo comparten.
+type Engine struct {
+	run        commandFunc
+	workers    int
+	slots      chan struct{}
+	preprocess preprocess.Options
+}
+
+// New crea un Engine que ejecuta los comandos externos con r
+func New(r *runner.Runner, cfg Config) *Engine {
+	return newEngine(r.Run, cfg)
+}
+
+// newEngine crea un Engine que ejecuta los comandos externos con run. Los tests lo usan
+// con un runner falso.
+func newEngine(run commandFunc, cfg Config) *Engine {
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
+	if cfg.MaxProcesses <= 0 {
+		cfg.MaxProcesses = runtime.GOMAXPROCS(0)
+	}
+	return &Engine{
+		run:        run,
+		workers:    cfg.Workers,
+		slots:      make(chan struct{}, cfg.MaxProcesses),
+		preprocess: cfg.Preprocess,
+	}
+}
+
+// Workers devuelve el número de páginas de un documento que se procesan en paralelo
+func (e *Engine) Workers() int { return e.workers }
+
+// MaxProcesses devuelve el máximo de procesos externos simultáneos
+func (e *Engine) MaxProcesses() int { return cap(e.slots) }
+
+// acquireProcess reserva un hueco del límite de procesos y devuelve la función que lo
+// libera. Si ctx termina mientras espera devuelve su error.
+func (e *Engine) acquireProcess(ctx context.Context) (func(), error) {
+	select {
+	case e.slots <- struct{}{}:
+		processesInFlight.Inc()
+		return func() {
+			processesInFlight.Dec()
+			<-e.slots
+		}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// runPages ejecuta fn para las páginas 0..n-1 con hasta workers goroutines.
+// Los resultados se devuelven en el orden de las páginas, independientemente del orden
+// en que terminen. Tras el primer error, o si ctx termina, no se inician más páginas.
+func runPages[T any](ctx context.Context, workers, n int, fn func(i int) (T, error)) ([]T, error) {
+	results := make([]T, n)
+	jobs := make(chan int)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+
+	poolSize := min(workers, n)
+	for w := 0; w < poolSize; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result, err := fn(i)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				results[i] = result
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := 0; i < n && !failed() && ctx.Err() == nil; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return results, nil
+}
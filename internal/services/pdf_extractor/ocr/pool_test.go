@@ -0,0 +1,62 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestRunPagesKeepsPageOrder(t *testing.T) {
+	for _, n := range []int{12, 120} {
+		t.Run(fmt.Sprintf("%d páginas", n), func(t *testing.T) {
+			pages := pageRange(n)
+
+			// Las páginas terminan en orden aleatorio
+			results, err := runPages(context.Background(), 4, len(pages), func(i int) (string, error) {
+				time.Sleep(time.Duration(rand.Intn(2000)) * time.Microsecond)
+				return fmt.Sprintf("texto de la página %d", pages[i]), nil
+			})
+			if err != nil {
+				t.Fatalf("runPages: %v", err)
+			}
+
+			if len(results) != n {
+				t.Fatalf("se esperaban %d páginas, hay %d", n, len(results))
+			}
+			for i, text := range results {
+				if want := fmt.Sprintf("texto de la página %d", i+1); text != want {
+					t.Errorf("página %d: %q, se esperaba %q", i+1, text, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunPagesReturnsError(t *testing.T) {
+	_, err := runPages(context.Background(), 4, 120, func(i int) (string, error) {
+		if i == 57 {
+			return "", fmt.Errorf("fallo en página %d", i+1)
+		}
+		return "ok", nil
+	})
+	if err == nil {
+		t.Fatal("se esperaba un error")
+	}
+}
+
+func TestParsePageCount(t *testing.T) {
+	output := "Producer:       pdfTeX\nPages:          120\nEncrypted:      no\n"
+	count, err := parsePageCount(output)
+	if err != nil {
+		t.Fatalf("parsePageCount: %v", err)
+	}
+	if count != 120 {
+		t.Errorf("se esperaban 120 páginas, hay %d", count)
+	}
+
+	if _, err := parsePageCount("Encrypted: no\n"); err == nil {
+		t.Error("se esperaba un error sin línea Pages")
+	}
+}
@@ -0,0 +1,170 @@
+package preprocess
+
+import (
+	"image"
+	"sort"
+)
+
+const (
+	black = 0
+	white = 255
+
+	// bradleyThreshold es el porcentaje por debajo de la media local que se considera tinta
+	bradleyThreshold = 0.15
+)
+
+// binarize aplica la binarización adaptativa de Bradley-Roth: un píxel es tinta si es
+// más oscuro que la media de su vecindario menos un porcentaje. Tolera iluminación
+// irregular y sombras de las fotos, a diferencia de un umbral global.
+func binarize(src *image.Gray) *image.Gray {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	integral := integralImage(src)
+	half := max(w, h) / 32
+	if half < 4 {
+		half = 4
+	}
+
+	dst := image.NewGray(src.Rect)
+	for y := 0; y < h; y++ {
+		y0, y1 := max(y-half, 0), min(y+half, h-1)
+		for x := 0; x < w; x++ {
+			x0, x1 := max(x-half, 0), min(x+half, w-1)
+			count := uint32((x1 - x0 + 1) * (y1 - y0 + 1))
+			// La aritmética módulo 2^32 da la suma exacta de la ventana aunque la
+			// imagen integral desborde, porque la suma de la ventana sí cabe en 32 bits
+			stride := w + 1
+			sum := integral[(y1+1)*stride+x1+1] - integral[y0*stride+x1+1] -
+				integral[(y1+1)*stride+x0] + integral[y0*stride+x0]
+
+			value := uint32(src.Pix[y*src.Stride+x])
+			if float64(value)*float64(count) <= float64(sum)*(1-bradleyThreshold) {
+				dst.Pix[y*dst.Stride+x] = black
+			} else {
+				dst.Pix[y*dst.Stride+x] = white
+			}
+		}
+	}
+	return dst
+}
+
+// integralImage calcula la tabla de sumas acumuladas con una fila y columna de ceros
+func integralImage(src *image.Gray) []uint32 {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	stride := w + 1
+	integral := make([]uint32, stride*(h+1))
+	for y := 0; y < h; y++ {
+		var rowSum uint32
+		for x := 0; x < w; x++ {
+			rowSum += uint32(src.Pix[y*src.Stride+x])
+			integral[(y+1)*stride+x+1] = integral[y*stride+x+1] + rowSum
+		}
+	}
+	return integral
+}
+
+// otsuThreshold calcula el umbral global que maximiza la varianza entre clases
+func otsuThreshold(src *image.Gray) uint8 {
+	var histogram [256]int
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	for y := 0; y < h; y++ {
+		for _, v := range src.Pix[y*src.Stride : y*src.Stride+w] {
+			histogram[v]++
+		}
+	}
+
+	total := w * h
+	var sumAll float64
+	for i, count := range histogram {
+		sumAll += float64(i * count)
+	}
+
+	var sumBackground float64
+	weightBackground := 0
+	bestVariance, threshold := 0.0, 127
+	for i, count := range histogram {
+		weightBackground += count
+		if weightBackground == 0 {
+			continue
+		}
+		weightForeground := total - weightBackground
+		if weightForeground == 0 {
+			break
+		}
+		sumBackground += float64(i * count)
+		meanBackground := sumBackground / float64(weightBackground)
+		meanForeground := (sumAll - sumBackground) / float64(weightForeground)
+		variance := float64(weightBackground) * float64(weightForeground) *
+			(meanBackground - meanForeground) * (meanBackground - meanForeground)
+		if variance > bestVariance {
+			bestVariance, threshold = variance, i
+		}
+	}
+	return uint8(threshold)
+}
+
+// inkMask reduce la imagen para que su lado mayor no supere maxSide y marca como tinta
+// los píxeles que no superan el umbral de Otsu. Se usa solo para analizar la imagen.
+func inkMask(src *image.Gray, maxSide int) ([]bool, int, int) {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	step := max((max(w, h)+maxSide-1)/maxSide, 1)
+	mw, mh := w/step, h/step
+	threshold := otsuThreshold(src)
+
+	mask := make([]bool, mw*mh)
+	for y := 0; y < mh; y++ {
+		for x := 0; x < mw; x++ {
+			mask[y*mw+x] = src.Pix[y*step*src.Stride+x*step] <= threshold
+		}
+	}
+	return mask, mw, mh
+}
+
+// despeckle elimina los píxeles de tinta aislados (sin vecinos de tinta) de una imagen binaria
+func despeckle(src *image.Gray) *image.Gray {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewGray(src.Rect)
+	copy(dst.Pix, src.Pix)
+
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			if src.Pix[y*src.Stride+x] != black {
+				continue
+			}
+			neighbors := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if (dx != 0 || dy != 0) && src.Pix[(y+dy)*src.Stride+x+dx] == black {
+						neighbors++
+					}
+				}
+			}
+			if neighbors == 0 {
+				dst.Pix[y*dst.Stride+x] = white
+			}
+		}
+	}
+	return dst
+}
+
+// median3 aplica un filtro de mediana 3x3, que elimina ruido impulsivo conservando bordes
+func median3(src *image.Gray) *image.Gray {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewGray(src.Rect)
+	copy(dst.Pix, src.Pix)
+
+	window := make([]int, 9)
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					window[i] = int(src.Pix[(y+dy)*src.Stride+x+dx])
+					i++
+				}
+			}
+			sort.Ints(window)
+			dst.Pix[y*dst.Stride+x] = uint8(window[4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,277 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+const (
+	// analysisSide es el lado máximo de la imagen reducida usada para analizar la página
+	analysisSide = 1000
+
+	// maxSkew y skewStep definen los ángulos probados al corregir la inclinación
+	maxSkew  = 5.0
+	skewStep = 0.2
+
+	// minVerticalRatio es cuánto más regular debe ser el perfil de columnas que el de filas
+	// para considerar que el texto está girado 90 grados
+	minVerticalRatio = 1.5
+
+	// maxCropFraction limita el recorte de bordes a una fracción de cada lado
+	maxCropFraction = 0.1
+	// darkBorderMean es el valor medio por debajo del cual una fila o columna es borde oscuro
+	darkBorderMean = 100
+)
+
+// detectOrientation devuelve los grados (0, 90, 180, 270) que hay que girar la imagen
+// en sentido horario para que el texto quede derecho
+func detectOrientation(src *image.Gray) int {
+	mask, w, h := inkMask(src, analysisSide)
+
+	// Las líneas de texto horizontales producen un perfil de filas con picos muy marcados
+	rows := projectionVariance(mask, w, h, true)
+	cols := projectionVariance(mask, w, h, false)
+
+	if cols > rows*minVerticalRatio {
+		// Texto vertical: decidir entre 90 y 270 sobre la imagen ya girada
+		rotated := rotateRight(src, 90)
+		rmask, rw, rh := inkMask(rotated, analysisSide)
+		if isUpsideDown(rmask, rw, rh) {
+			return 270
+		}
+		return 90
+	}
+
+	if isUpsideDown(mask, w, h) {
+		return 180
+	}
+	return 0
+}
+
+// projectionVariance calcula la varianza del perfil de proyección de filas o columnas
+func projectionVariance(mask []bool, w, h int, byRows bool) float64 {
+	n := w
+	if byRows {
+		n = h
+	}
+	profile := make([]float64, n)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !mask[y*w+x] {
+				continue
+			}
+			if byRows {
+				profile[y]++
+			} else {
+				profile[x]++
+			}
+		}
+	}
+	return variance(profile)
+}
+
+// isUpsideDown compara la tinta por encima y por debajo de la franja central de cada
+// línea. En texto latino hay más ascendentes (mayúsculas, dígitos, b, d, h, l, t) que
+// descendentes (g, j, p, q, y), así que un texto invertido tiene más tinta por debajo.
+func isUpsideDown(mask []bool, w, h int) bool {
+	profile := make([]int, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if mask[y*w+x] {
+				profile[y]++
+			}
+		}
+	}
+
+	above, below := 0, 0
+	for start := 0; start < h; {
+		if profile[start] == 0 {
+			start++
+			continue
+		}
+		end := start
+		peak := 0
+		for end < h && profile[end] > 0 {
+			peak = max(peak, profile[end])
+			end++
+		}
+
+		// La franja central son las filas con al menos la mitad de la tinta máxima de la línea
+		coreStart, coreEnd := -1, -1
+		for y := start; y < end; y++ {
+			if profile[y]*2 >= peak {
+				if coreStart < 0 {
+					coreStart = y
+				}
+				coreEnd = y
+			}
+		}
+		for y := start; y < coreStart; y++ {
+			above += profile[y]
+		}
+		for y := coreEnd + 1; y < end; y++ {
+			below += profile[y]
+		}
+		start = end
+	}
+
+	return below > above
+}
+
+// detectSkew busca el ángulo (en grados) que maximiza la varianza del perfil de filas,
+// que es el ángulo en el que las líneas de texto quedan horizontales
+func detectSkew(src *image.Gray) float64 {
+	mask, w, h := inkMask(src, analysisSide)
+
+	var xs, ys []float64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if mask[y*w+x] {
+				xs = append(xs, float64(x))
+				ys = append(ys, float64(y))
+			}
+		}
+	}
+	if len(xs) == 0 {
+		return 0
+	}
+
+	diagonal := int(math.Hypot(float64(w), float64(h))) + 1
+	profile := make([]float64, 2*diagonal)
+	bestScore, bestAngle := -1.0, 0.0
+	for angle := -maxSkew; angle <= maxSkew+1e-9; angle += skewStep {
+		sin, cos := math.Sincos(angle * math.Pi / 180)
+		for i := range profile {
+			profile[i] = 0
+		}
+		for i := range xs {
+			// Fila que ocuparía el píxel tras girar la imagen -angle grados
+			row := int(ys[i]*cos-xs[i]*sin) + diagonal
+			if row >= 0 && row < len(profile) {
+				profile[row]++
+			}
+		}
+		if score := variance(profile); score > bestScore {
+			bestScore, bestAngle = score, angle
+		}
+	}
+
+	// Redondear para no girar por errores de precisión en páginas derechas
+	bestAngle = math.Round(bestAngle/skewStep) * skewStep
+	if math.Abs(bestAngle) < skewStep/2 {
+		return 0
+	}
+	return bestAngle
+}
+
+// rotate gira la imagen angle grados (positivo en sentido horario) alrededor de su
+// centro, rellenando con blanco las zonas descubiertas
+func rotate(src *image.Gray, angle float64) *image.Gray {
+	w, h := float64(src.Rect.Dx()), float64(src.Rect.Dy())
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	cx, cy := w/2, h/2
+
+	dst := image.NewGray(src.Rect)
+	for i := range dst.Pix {
+		dst.Pix[i] = white
+	}
+
+	// Transformación afín de coordenadas origen a destino
+	matrix := f64.Aff3{
+		cos, -sin, cx - cos*cx + sin*cy,
+		sin, cos, cy - sin*cx - cos*cy,
+	}
+	xdraw.BiLinear.Transform(dst, matrix, src, src.Rect, xdraw.Over, nil)
+	return dst
+}
+
+// rotateRight gira la imagen 90, 180 o 270 grados en sentido horario
+func rotateRight(src *image.Gray, degrees int) *image.Gray {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	var dst *image.Gray
+	switch degrees {
+	case 90, 270:
+		dst = image.NewGray(image.Rect(0, 0, h, w))
+	case 180:
+		dst = image.NewGray(image.Rect(0, 0, w, h))
+	default:
+		return src
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := src.Pix[y*src.Stride+x]
+			switch degrees {
+			case 90:
+				dst.SetGray(h-1-y, x, color.Gray{Y: v})
+			case 180:
+				dst.SetGray(w-1-x, h-1-y, color.Gray{Y: v})
+			case 270:
+				dst.SetGray(y, w-1-x, color.Gray{Y: v})
+			}
+		}
+	}
+	return dst
+}
+
+// cropBorders elimina las franjas oscuras de los bordes que dejan escáneres y fotocopias
+func cropBorders(src *image.Gray) *image.Gray {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	maxX, maxY := int(float64(w)*maxCropFraction), int(float64(h)*maxCropFraction)
+
+	rowMean := func(y int) int {
+		sum := 0
+		for _, v := range src.Pix[y*src.Stride : y*src.Stride+w] {
+			sum += int(v)
+		}
+		return sum / w
+	}
+	colMean := func(x int) int {
+		sum := 0
+		for y := 0; y < h; y++ {
+			sum += int(src.Pix[y*src.Stride+x])
+		}
+		return sum / h
+	}
+
+	top, bottom, left, right := 0, h, 0, w
+	for top < maxY && rowMean(top) < darkBorderMean {
+		top++
+	}
+	for h-bottom < maxY && rowMean(bottom-1) < darkBorderMean {
+		bottom--
+	}
+	for left < maxX && colMean(left) < darkBorderMean {
+		left++
+	}
+	for w-right < maxX && colMean(right-1) < darkBorderMean {
+		right--
+	}
+
+	if top == 0 && left == 0 && bottom == h && right == w {
+		return src
+	}
+
+	dst := image.NewGray(image.Rect(0, 0, right-left, bottom-top))
+	for y := top; y < bottom; y++ {
+		copy(dst.Pix[(y-top)*dst.Stride:], src.Pix[y*src.Stride+left:y*src.Stride+right])
+	}
+	return dst
+}
+
+func variance(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum, sumSq float64
+	for _, v := range values {
+		sum += v
+		sumSq += v * v
+	}
+	mean := sum / float64(len(values))
+	return sumSq/float64(len(values)) - mean*mean
+}
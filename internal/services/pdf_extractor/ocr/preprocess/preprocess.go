@@ -0,0 +1,211 @@
+package preprocess
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	_ "golang.org/x/image/tiff"
+)
+
+// DefaultMaxPixels es el máximo de píxeles por defecto de una imagen decodificada en el
+// proceso del servidor. Una página A4 a 600 DPI tiene unos 35 millones.
+const DefaultMaxPixels = 50_000_000
+
+// ErrTooLarge indica que una imagen supera el máximo de píxeles que se decodifican
+var ErrTooLarge = errors.New("la imagen supera el máximo de píxeles")
+
+// CheckSize comprueba que una imagen de width x height píxeles no supera maxPixels. Se usa
+// con las dimensiones de la cabecera, antes de decodificarla: una imagen pequeña en disco
+// puede declarar dimensiones que agoten la memoria al decodificarla.
+func CheckSize(width, height, maxPixels int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("dimensiones de imagen inválidas: %dx%d", width, height)
+	}
+	if int64(width)*int64(height) > int64(maxPixels) {
+		return fmt.Errorf("%w: %dx%d, el máximo es %d", ErrTooLarge, width, height, maxPixels)
+	}
+	return nil
+}
+
+// Options activa cada paso del preprocesado. Todos los pasos trabajan sobre la imagen
+// en escala de grises, así que activar cualquiera implica la conversión a grises.
+type Options struct {
+	Grayscale   bool // Convertir a escala de grises
+	Orientation bool // Detectar y corregir rotaciones de 90/180/270 grados
+	Deskew      bool // Corregir la inclinación con perfiles de proyección
+	CropBorders bool // Recortar los bordes oscuros del escaneo
+	Binarize    bool // Binarización adaptativa (Bradley-Roth)
+	Denoise     bool // Eliminar motas aisladas
+}
+
+// AllSteps activa todos los pasos
+var AllSteps = Options{
+	Grayscale:   true,
+	Orientation: true,
+	Deskew:      true,
+	CropBorders: true,
+	Binarize:    true,
+	Denoise:     true,
+}
+
+// Enabled indica si hay algún paso activo
+func (o Options) Enabled() bool {
+	return o.Grayscale || o.Orientation || o.Deskew || o.CropBorders || o.Binarize || o.Denoise
+}
+
+// ParseOptions interpreta una lista separada por comas de pasos
+// (grayscale, orientation, deskew, crop, binarize, denoise), o "all"/"none"
+func ParseOptions(spec string) (Options, error) {
+	var opts Options
+	for _, step := range strings.Split(spec, ",") {
+		switch strings.ToLower(strings.TrimSpace(step)) {
+		case "":
+		case "all":
+			opts = AllSteps
+		case "none":
+			opts = Options{}
+		case "grayscale":
+			opts.Grayscale = true
+		case "orientation":
+			opts.Orientation = true
+		case "deskew":
+			opts.Deskew = true
+		case "crop":
+			opts.CropBorders = true
+		case "binarize":
+			opts.Binarize = true
+		case "denoise":
+			opts.Denoise = true
+		default:
+			return Options{}, fmt.Errorf("paso de preprocesado desconocido: %q", step)
+		}
+	}
+	return opts, nil
+}
+
+// Report describe los pasos aplicados a una imagen
+type Report struct {
+	Steps    []string      `json:"steps"`
+	Rotation int           `json:"rotation"` // Grados de rotación aplicados (0, 90, 180, 270)
+	Skew     float64       `json:"skew"`     // Grados de inclinación corregidos
+	Duration time.Duration `json:"duration"`
+}
+
+// Process aplica los pasos activos en orden: grises, orientación, inclinación, recorte,
+// binarización y eliminación de ruido
+func Process(img image.Image, opts Options) (image.Image, Report) {
+	startTime := time.Now()
+	report := Report{}
+	if !opts.Enabled() {
+		return img, report
+	}
+
+	gray := toGray(img)
+	report.Steps = append(report.Steps, "grayscale")
+
+	if opts.Orientation {
+		report.Rotation = detectOrientation(gray)
+		if report.Rotation != 0 {
+			gray = rotateRight(gray, report.Rotation)
+		}
+		report.Steps = append(report.Steps, "orientation")
+	}
+
+	if opts.Deskew {
+		report.Skew = detectSkew(gray)
+		if report.Skew != 0 {
+			gray = rotate(gray, -report.Skew)
+		}
+		report.Steps = append(report.Steps, "deskew")
+	}
+
+	if opts.CropBorders {
+		gray = cropBorders(gray)
+		report.Steps = append(report.Steps, "crop")
+	}
+
+	if opts.Binarize {
+		gray = binarize(gray)
+		report.Steps = append(report.Steps, "binarize")
+	}
+
+	if opts.Denoise {
+		if opts.Binarize {
+			gray = despeckle(gray)
+		} else {
+			gray = median3(gray)
+		}
+		report.Steps = append(report.Steps, "denoise")
+	}
+
+	report.Duration = time.Since(startTime)
+	return gray, report
+}
+
+// File preprocesa la imagen de in y guarda el resultado en PNG en out. Las imágenes de más
+// de maxPixels píxeles se rechazan con ErrTooLarge sin decodificarlas.
+func File(in, out string, opts Options, maxPixels int) (Report, error) {
+	img, err := decode(in, maxPixels)
+	if err != nil {
+		return Report{}, err
+	}
+
+	processed, report := Process(img, opts)
+
+	dst, err := os.Create(out)
+	if err != nil {
+		return Report{}, fmt.Errorf("error al crear imagen preprocesada: %v", err)
+	}
+	defer dst.Close()
+
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := encoder.Encode(dst, processed); err != nil {
+		return Report{}, fmt.Errorf("error al guardar imagen preprocesada: %v", err)
+	}
+
+	return report, nil
+}
+
+// decode decodifica la imagen de path tras comprobar sus dimensiones con CheckSize
+func decode(path string, maxPixels int) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir imagen: %v", err)
+	}
+	defer f.Close()
+
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("error al decodificar imagen: %v", err)
+	}
+	if err := CheckSize(config.Width, config.Height, maxPixels); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error al leer imagen: %v", err)
+	}
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("error al decodificar imagen: %v", err)
+	}
+	return img, nil
+}
+
+// toGray convierte cualquier imagen a escala de grises con origen en (0, 0)
+func toGray(img image.Image) *image.Gray {
+	if gray, ok := img.(*image.Gray); ok && gray.Rect.Min == (image.Point{}) {
+		return gray
+	}
+	bounds := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(gray, gray.Rect, img, bounds.Min, draw.Src)
+	return gray
+}
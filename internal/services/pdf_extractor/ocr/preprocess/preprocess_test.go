@@ -0,0 +1,331 @@
+package preprocess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// page dibuja una página de texto sintético de w x h: líneas de letras de 5 píxeles con
+// la franja central rellena, y trazos más finos por encima (ascendentes, frecuentes) y por
+// debajo (descendentes, escasos), como en un texto latino
+func page(w, h int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = white
+	}
+	fill := func(x0, y0, x1, y1 int) {
+		for y := max(y0, 0); y < min(y1, h); y++ {
+			for x := max(x0, 0); x < min(x1, w); x++ {
+				img.Pix[y*img.Stride+x] = black
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	margin := w / 10
+	for top := h / 10; top+30 < h-h/10; top += 32 {
+		for x := margin; x < w-margin; {
+			letters := 2 + rng.Intn(7)
+			for i := 0; i < letters && x+5 < w-margin; i++ {
+				fill(x, top+8, x+5, top+20) // Franja central
+				switch r := rng.Float64(); {
+				case r < 0.4:
+					fill(x, top, x+2, top+8) // Ascendente
+				case r < 0.5:
+					fill(x+3, top+20, x+5, top+26) // Descendente
+				}
+				x += 7
+			}
+			x += 8 // Espacio entre palabras
+		}
+	}
+	return img
+}
+
+func TestDetectOrientation(t *testing.T) {
+	upright := page(480, 600)
+	for _, rotation := range []int{0, 90, 180, 270} {
+		t.Run(fmt.Sprintf("%d°", rotation), func(t *testing.T) {
+			img := rotateRight(upright, rotation)
+			want := (360 - rotation) % 360
+			if got := detectOrientation(img); got != want {
+				t.Fatalf("página girada %d°: se detectó %d°, se esperaba %d°", rotation, got, want)
+			}
+
+			processed, report := Process(img, Options{Orientation: true})
+			if report.Rotation != want {
+				t.Errorf("rotación aplicada %d°, se esperaba %d°", report.Rotation, want)
+			}
+			if got := detectOrientation(processed.(*image.Gray)); got != 0 {
+				t.Errorf("tras corregir la página girada %d° se detectan %d°, se esperaba 0°", rotation, got)
+			}
+			if processed.Bounds().Size() != upright.Rect.Size() {
+				t.Errorf("tamaño %v tras corregir, se esperaba %v", processed.Bounds().Size(), upright.Rect.Size())
+			}
+		})
+	}
+}
+
+func TestDetectSkew(t *testing.T) {
+	const tolerance = 0.4
+	upright := page(600, 600)
+	for _, angle := range []float64{-4, -2.5, -1, 0, 1.6, 3} {
+		t.Run(fmt.Sprintf("%.1f°", angle), func(t *testing.T) {
+			img := upright
+			if angle != 0 {
+				img = rotate(upright, angle)
+			}
+			if got := detectSkew(img); math.Abs(got-angle) > tolerance {
+				t.Fatalf("página inclinada %.1f°: se detectó %.1f°", angle, got)
+			}
+
+			processed, report := Process(img, Options{Deskew: true})
+			if math.Abs(report.Skew-angle) > tolerance {
+				t.Errorf("inclinación corregida %.1f°, se esperaba %.1f°", report.Skew, angle)
+			}
+			if got := detectSkew(processed.(*image.Gray)); math.Abs(got) > tolerance {
+				t.Errorf("tras corregir la página inclinada %.1f° queda %.1f°", angle, got)
+			}
+		})
+	}
+}
+
+func TestCropBorders(t *testing.T) {
+	tests := []struct {
+		name         string
+		border       int // Ancho de la franja oscura en cada lado
+		wantW, wantH int
+	}{
+		{"sin bordes", 0, 400, 300},
+		{"bordes estrechos", 12, 376, 276},
+		// El recorte se limita al 10 % de cada lado: 40 y 30 píxeles
+		{"bordes más anchos que el máximo", 50, 320, 240},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := page(400, 300)
+			for y := 0; y < 300; y++ {
+				for x := 0; x < 400; x++ {
+					if x < tt.border || y < tt.border || x >= 400-tt.border || y >= 300-tt.border {
+						img.Pix[y*img.Stride+x] = 20
+					}
+				}
+			}
+			got := cropBorders(img).Rect
+			if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+				t.Errorf("recortada a %dx%d, se esperaba %dx%d", got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestBinarizeToleratesUnevenLighting(t *testing.T) {
+	const w, h = 400, 300
+	text := page(w, h)
+	// Iluminación de izquierda a derecha: el fondo de la izquierda es más oscuro que la
+	// tinta de la derecha, así que ningún umbral global separa tinta y fondo
+	img := image.NewGray(text.Rect)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			light := 120 + 130*x/w
+			if text.Pix[y*text.Stride+x] == black {
+				light -= 70
+			}
+			img.Pix[y*img.Stride+x] = uint8(light)
+		}
+	}
+
+	got := binarize(img)
+	wrong := 0
+	for i, v := range got.Pix {
+		if v != text.Pix[i] {
+			wrong++
+		}
+	}
+	if ratio := float64(wrong) / float64(len(got.Pix)); ratio > 0.01 {
+		t.Errorf("%.1f %% de píxeles mal clasificados", ratio*100)
+	}
+}
+
+func TestDenoise(t *testing.T) {
+	clean := page(300, 200)
+	noisy := image.NewGray(clean.Rect)
+	copy(noisy.Pix, clean.Pix)
+	// Motas aisladas en el margen, donde no hay texto
+	specks := []image.Point{{5, 5}, {12, 40}, {290, 100}, {150, 195}}
+	for _, p := range specks {
+		noisy.Pix[p.Y*noisy.Stride+p.X] = black
+	}
+
+	for name, denoise := range map[string]func(*image.Gray) *image.Gray{"despeckle": despeckle, "median3": median3} {
+		t.Run(name, func(t *testing.T) {
+			got := denoise(noisy)
+			for _, p := range specks {
+				if got.Pix[p.Y*got.Stride+p.X] != white {
+					t.Errorf("la mota de %v sigue en la imagen", p)
+				}
+			}
+			// El texto se conserva: las letras son más anchas que el filtro
+			if name == "despeckle" && !bytes.Equal(got.Pix, clean.Pix) {
+				t.Errorf("despeckle alteró el texto")
+			}
+		})
+	}
+}
+
+func TestProcess(t *testing.T) {
+	img := rotate(rotateRight(page(480, 600), 90), 2)
+
+	processed, report := Process(img, AllSteps)
+	want := []string{"grayscale", "orientation", "deskew", "crop", "binarize", "denoise"}
+	if len(report.Steps) != len(want) {
+		t.Fatalf("pasos %v, se esperaban %v", report.Steps, want)
+	}
+	for i := range want {
+		if report.Steps[i] != want[i] {
+			t.Fatalf("pasos %v, se esperaban %v", report.Steps, want)
+		}
+	}
+	if report.Rotation != 270 {
+		t.Errorf("rotación %d°, se esperaba 270°", report.Rotation)
+	}
+	gray := processed.(*image.Gray)
+	for _, v := range gray.Pix {
+		if v != black && v != white {
+			t.Fatalf("imagen no binaria: valor %d", v)
+		}
+	}
+
+	if _, report := Process(img, Options{}); report.Steps != nil {
+		t.Errorf("sin pasos activos se aplicaron %v", report.Steps)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Options
+		wantErr bool
+	}{
+		{"all", AllSteps, false},
+		{"none", Options{}, false},
+		{"", Options{}, false},
+		{"deskew, Binarize", Options{Deskew: true, Binarize: true}, false},
+		{"all,none,crop", Options{CropBorders: true}, false},
+		{"sharpen", Options{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseOptions(tt.spec)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseOptions(%q) = %+v, %v; se esperaba %+v", tt.spec, got, err, tt.want)
+			}
+		})
+	}
+}
+
+// writePNG guarda img en PNG en un archivo temporal y devuelve su ruta
+func writePNG(t *testing.T, img image.Image) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "pagina.png")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// declareSize cambia las dimensiones que declara la cabecera IHDR del PNG de path sin
+// cambiar sus datos, como haría una bomba de descompresión
+func declareSize(t *testing.T, path string, width, height uint32) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Firma (8), longitud (4), tipo "IHDR" (4) y datos (13) seguidos del CRC de tipo y datos
+	binary.BigEndian.PutUint32(data[16:20], width)
+	binary.BigEndian.PutUint32(data[20:24], height)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFile(t *testing.T) {
+	in := writePNG(t, page(200, 100))
+	out := filepath.Join(t.TempDir(), "pagina.pre.png")
+
+	report, err := File(in, out, Options{Binarize: true}, 200*100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Steps) != 2 {
+		t.Errorf("pasos %v, se esperaban grayscale y binarize", report.Steps)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if config, err := png.DecodeConfig(f); err != nil || config.Width != 200 || config.Height != 100 {
+		t.Errorf("imagen preprocesada %dx%d (%v), se esperaba 200x100", config.Width, config.Height, err)
+	}
+}
+
+func TestFileRejectsImagesAboveMaxPixels(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height uint32
+		maxPixels     int
+	}{
+		{"mayor que el máximo", 200, 100, 200*100 - 1},
+		{"cabecera de 60000x60000", 60000, 60000, DefaultMaxPixels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := writePNG(t, page(200, 100))
+			declareSize(t, in, tt.width, tt.height)
+			out := filepath.Join(t.TempDir(), "pagina.pre.png")
+
+			_, err := File(in, out, AllSteps, tt.maxPixels)
+			if !errors.Is(err, ErrTooLarge) {
+				t.Fatalf("error %v, se esperaba ErrTooLarge", err)
+			}
+			if _, err := os.Stat(out); err == nil {
+				t.Errorf("se escribió la imagen preprocesada")
+			}
+		})
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	tests := []struct {
+		width, height, maxPixels int
+		ok                       bool
+	}{
+		{100, 100, 10000, true},
+		{100, 101, 10000, false},
+		{100000, 100000, DefaultMaxPixels, false},
+		{0, 100, 10000, false},
+		{-1, 100, 10000, false},
+	}
+	for _, tt := range tests {
+		if err := CheckSize(tt.width, tt.height, tt.maxPixels); (err == nil) != tt.ok {
+			t.Errorf("CheckSize(%d, %d, %d) = %v", tt.width, tt.height, tt.maxPixels, err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package ocr
+
+import (
+	"encoding/binary"
+	"fmt"
+	"go_ocr/internal/services/pdf_extractor/ocr/preprocess"
+	"golang.org/x/image/tiff"
+	"image/png"
+	"io"
+	"os"
+)
+
+// maxTIFFPages limita el número de páginas aceptadas en un TIFF multipágina
+const maxTIFFPages = 500
+
+// tiffFile es un TIFF abierto junto con la posición del IFD de cada página. Las páginas se
+// decodifican de una en una desde el archivo original, sin copiarlo por página.
+type tiffFile struct {
+	f     *os.File
+	size  int64
+	order binary.ByteOrder
+	ifds  []uint32
+}
+
+// openTIFF abre el TIFF de path y recorre la cadena de IFDs (uno por página) leyendo solo
+// las cabeceras
+func openTIFF(path string) (*tiffFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer TIFF: %v", err)
+	}
+	t, err := readTIFF(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func readTIFF(f *os.File) (*tiffFile, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error al leer TIFF: %v", err)
+	}
+	t := &tiffFile{f: f, size: info.Size()}
+
+	var header [8]byte
+	if _, err := f.ReadAt(header[:], 0); err != nil {
+		return nil, fmt.Errorf("TIFF truncado")
+	}
+	switch string(header[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("cabecera TIFF inválida")
+	}
+	if t.order.Uint16(header[2:4]) != 42 {
+		return nil, fmt.Errorf("formato TIFF no soportado (BigTIFF)")
+	}
+
+	seen := make(map[uint32]bool)
+	for offset := t.order.Uint32(header[4:8]); offset != 0; {
+		if seen[offset] {
+			return nil, fmt.Errorf("cadena de IFD inválida en offset %d", offset)
+		}
+		if len(t.ifds) >= maxTIFFPages {
+			return nil, fmt.Errorf("el TIFF supera el máximo de %d páginas", maxTIFFPages)
+		}
+		seen[offset] = true
+		t.ifds = append(t.ifds, offset)
+
+		next, err := t.nextIFD(offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = next
+	}
+	if len(t.ifds) == 0 {
+		return nil, fmt.Errorf("el TIFF no tiene páginas")
+	}
+	return t, nil
+}
+
+// nextIFD lee el puntero al siguiente IFD del IFD en offset
+func (t *tiffFile) nextIFD(offset uint32) (uint32, error) {
+	var count [2]byte
+	if _, err := t.f.ReadAt(count[:], int64(offset)); err != nil {
+		return 0, fmt.Errorf("cadena de IFD inválida en offset %d", offset)
+	}
+	var next [4]byte
+	position := int64(offset) + 2 + int64(t.order.Uint16(count[:]))*12
+	if _, err := t.f.ReadAt(next[:], position); err != nil {
+		return 0, fmt.Errorf("IFD truncado en offset %d", offset)
+	}
+	return t.order.Uint32(next[:]), nil
+}
+
+// Close cierra el archivo
+func (t *tiffFile) Close() error {
+	return t.f.Close()
+}
+
+// writePage decodifica la página i (desde 0) y la guarda en PNG en out. Las páginas de más
+// de maxPixels píxeles se rechazan sin decodificarlas.
+func (t *tiffFile) writePage(i int, out string, maxPixels int) error {
+	page := &tiffPage{r: t.f, order: t.order, ifd: t.ifds[i]}
+	config, err := tiff.DecodeConfig(io.NewSectionReader(page, 0, t.size))
+	if err != nil {
+		return fmt.Errorf("error al decodificar página %d del TIFF: %v", i+1, err)
+	}
+	if err := preprocess.CheckSize(config.Width, config.Height, maxPixels); err != nil {
+		return fmt.Errorf("página %d del TIFF: %w", i+1, err)
+	}
+	img, err := tiff.Decode(io.NewSectionReader(page, 0, t.size))
+	if err != nil {
+		return fmt.Errorf("error al decodificar página %d del TIFF: %v", i+1, err)
+	}
+
+	dst, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("error al escribir página %d del TIFF: %v", i+1, err)
+	}
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	err = encoder.Encode(dst, img)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error al escribir página %d del TIFF: %v", i+1, err)
+	}
+	return nil
+}
+
+// tiffPage lee el TIFF original como si la página con el IFD en ifd fuera la primera:
+// la cabecera apunta a ese IFD. El decodificador solo lee el primer IFD.
+type tiffPage struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+	ifd   uint32
+}
+
+func (p *tiffPage) ReadAt(b []byte, off int64) (int, error) {
+	n, err := p.r.ReadAt(b, off)
+	var first [4]byte
+	p.order.PutUint32(first[:], p.ifd)
+	for i, c := range first {
+		if j := 4 + int64(i) - off; j >= 0 && j < int64(n) {
+			b[j] = c
+		}
+	}
+	return n, err
+}
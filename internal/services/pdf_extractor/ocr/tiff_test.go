@@ -0,0 +1,210 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go_ocr/internal/services/pdf_extractor/ocr/preprocess"
+	"image"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// buildTIFF construye un TIFF multipágina sin comprimir con una página gris de 16x8 por
+// cada valor de values, rellena con ese valor
+func buildTIFF(values ...byte) []byte {
+	const width, height = 16, 8
+	order := binary.LittleEndian
+	var buf bytes.Buffer
+	buf.WriteString("II")
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(0)) // Primer IFD, se completa al escribirlo
+
+	previous := 4 // Posición del puntero que debe apuntar al siguiente IFD
+	for _, value := range values {
+		pixels := buf.Len()
+		buf.Write(bytes.Repeat([]byte{value}, width*height))
+		if buf.Len()%2 != 0 {
+			buf.WriteByte(0)
+		}
+
+		ifd := buf.Len()
+		data := buf.Bytes()
+		order.PutUint32(data[previous:previous+4], uint32(ifd))
+		entries := [][3]uint32{ // etiqueta, tipo (3 SHORT, 4 LONG), valor
+			{256, 3, width}, {257, 3, height}, {258, 3, 8}, {259, 3, 1}, {262, 3, 1},
+			{273, 4, uint32(pixels)}, {277, 3, 1}, {278, 3, height}, {279, 4, width * height},
+		}
+		binary.Write(&buf, order, uint16(len(entries)))
+		for _, e := range entries {
+			binary.Write(&buf, order, uint16(e[0]))
+			binary.Write(&buf, order, uint16(e[1]))
+			binary.Write(&buf, order, uint32(1))
+			if e[1] == 3 {
+				binary.Write(&buf, order, uint16(e[2]))
+				binary.Write(&buf, order, uint16(0))
+			} else {
+				binary.Write(&buf, order, e[2])
+			}
+		}
+		previous = buf.Len()
+		binary.Write(&buf, order, uint32(0))
+	}
+	return buf.Bytes()
+}
+
+// writeTIFF escribe data en un archivo temporal y devuelve su ruta
+func writeTIFF(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "nomina.tif")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// pixel devuelve el valor gris del primer píxel de la imagen de path
+func pixel(path string) (byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return 0, err
+	}
+	r, _, _, _ := img.At(0, 0).RGBA()
+	return byte(r >> 8), nil
+}
+
+func TestTIFFWritePage(t *testing.T) {
+	values := []byte{10, 20, 30}
+	tf, err := openTIFF(writeTIFF(t, buildTIFF(values...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tf.Close()
+
+	if len(tf.ifds) != len(values) {
+		t.Fatalf("%d páginas, se esperaban %d", len(tf.ifds), len(values))
+	}
+	dir := t.TempDir()
+	// En orden inverso: cada página se decodifica por separado del mismo archivo
+	for i := len(values) - 1; i >= 0; i-- {
+		out := filepath.Join(dir, fmt.Sprintf("tiff-%d.png", i+1))
+		if err := tf.writePage(i, out, preprocess.DefaultMaxPixels); err != nil {
+			t.Fatal(err)
+		}
+		got, err := pixel(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != values[i] {
+			t.Errorf("página %d con valor %d, se esperaba %d", i+1, got, values[i])
+		}
+		if info, err := os.Stat(out); err == nil && info.Mode().Perm() != 0o600 {
+			t.Errorf("permisos de la página %d: %v", i+1, info.Mode().Perm())
+		}
+	}
+}
+
+func TestTIFFWritePageRejectsPagesAboveMaxPixels(t *testing.T) {
+	data := buildTIFF(10, 20)
+	// La segunda página declara 60000x60000 píxeles en las entradas de ancho y alto, las
+	// dos primeras de su IFD
+	tf, err := openTIFF(writeTIFF(t, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := int(tf.ifds[1])
+	tf.Close()
+	for entry := 0; entry < 2; entry++ {
+		binary.LittleEndian.PutUint16(data[second+2+entry*12+8:], 60000)
+	}
+
+	tf, err = openTIFF(writeTIFF(t, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tf.Close()
+	dir := t.TempDir()
+	if err := tf.writePage(0, filepath.Join(dir, "tiff-1.png"), preprocess.DefaultMaxPixels); err != nil {
+		t.Errorf("página 1: %v", err)
+	}
+	out := filepath.Join(dir, "tiff-2.png")
+	if err := tf.writePage(1, out, preprocess.DefaultMaxPixels); !errors.Is(err, preprocess.ErrTooLarge) {
+		t.Errorf("página 2: error %v, se esperaba ErrTooLarge", err)
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Errorf("se escribió la página 2")
+	}
+	if err := tf.writePage(0, filepath.Join(dir, "tiff-1.png"), 16*8-1); !errors.Is(err, preprocess.ErrTooLarge) {
+		t.Errorf("página 1 con un máximo menor que su tamaño: error %v, se esperaba ErrTooLarge", err)
+	}
+}
+
+func TestOpenTIFFRejectsInvalidFiles(t *testing.T) {
+	loop := buildTIFF(10, 20)
+	// El puntero al siguiente IFD de la segunda página vuelve a la primera
+	first := binary.LittleEndian.Uint32(loop[4:8])
+	binary.LittleEndian.PutUint32(loop[len(loop)-4:], first)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"cadena de IFD circular", loop, "cadena de IFD inválida"},
+		{"demasiadas páginas", buildTIFF(make([]byte, maxTIFFPages+1)...), "máximo"},
+		{"IFD truncado", buildTIFF(10)[:len(buildTIFF(10))-6], "truncado"},
+		{"cabecera inválida", []byte("XX*\x00\x08\x00\x00\x00"), "cabecera"},
+		{"BigTIFF", []byte("II+\x00\x08\x00\x00\x00"), "BigTIFF"},
+		{"sin páginas", []byte("II*\x00\x00\x00\x00\x00"), "no tiene páginas"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf, err := openTIFF(writeTIFF(t, tt.data))
+			if err == nil {
+				tf.Close()
+				t.Fatal("se esperaba un error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q, se esperaba que contuviera %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractWithOCRRecognizesEachTIFFPage(t *testing.T) {
+	// Tesseract devuelve como palabra el valor de la imagen que recibe
+	engine := newFakeEngine(func(ctx context.Context, name string, args ...string) ([]byte, string, error) {
+		value, err := pixel(args[0])
+		if err != nil {
+			return nil, err.Error(), err
+		}
+		return []byte(fmt.Sprintf("%s5\t1\t1\t1\t1\t1\t10\t10\t50\t20\t95\tvalor-%d\n", tsvHeader, value)), "", nil
+	})
+	opts := DefaultOptions
+	opts.AutoDetect = true
+
+	doc, err := engine.ExtractWithOCR(context.Background(), writeTIFF(t, buildTIFF(10, 20, 30, 40)), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Pages) != 4 {
+		t.Fatalf("%d páginas, se esperaban 4", len(doc.Pages))
+	}
+	for i, page := range doc.Pages {
+		want := fmt.Sprintf("valor-%d", (i+1)*10)
+		if page.Number != i+1 || strings.TrimSpace(page.Text()) != want {
+			t.Errorf("resultado %d: página %d con texto %q, se esperaba la página %d con %q",
+				i, page.Number, page.Text(), i+1, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package pdf_extractor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go_ocr/internal/services/pdf_extractor/ocr"
+	"strings"
+)
+
+// Options configura la extracción de texto de un documento
+type Options struct {
+	// Layout conserva la disposición en columnas del texto y reconstruye las filas de la
+	// tabla de conceptos (ver el paquete table)
+	Layout bool        `json:"layout"`
+	OCR    ocr.Options `json:"ocr"`
+
+	// Passwords son las contraseñas candidatas con las que Open abre PDF cifrados. No forman
+	// parte de Key: el texto extraído no depende de cuál de ellas abra el documento.
+	Passwords []string `json:"-"`
+}
+
+// DefaultOptions son las opciones usadas si no se configuran otras
+var DefaultOptions = Options{Layout: true, OCR: ocr.DefaultOptions}
+
+// Validate comprueba que las opciones son aceptables
+func (o Options) Validate() error {
+	return o.OCR.Validate()
+}
+
+// languages devuelve los idiomas en los que se espera el documento, con los que se puntúa
+// el texto extraído: los de OCR y, con la detección automática, también sus candidatos
+func (o Options) languages() []string {
+	languages := strings.Split(o.OCR.Languages, "+")
+	if o.OCR.AutoDetect {
+		languages = append(languages, o.OCR.Candidates...)
+	}
+	return languages
+}
+
+// String describe las opciones sin las contraseñas, para los logs
+func (o Options) String() string {
+	return fmt.Sprintf("{Layout:%t OCR:%v Passwords:%d}", o.Layout, o.OCR, len(o.Passwords))
+}
+
+// Key identifica las opciones que influyen en el texto extraído, para separar en la caché
+// los resultados de un mismo documento extraído con opciones distintas
+func (o Options) Key() string {
+	raw, _ := json.Marshal(o)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:8])
+}
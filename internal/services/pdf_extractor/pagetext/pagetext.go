@@ -0,0 +1,47 @@
+package pagetext
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// markerFormat es el separador que precede al texto de cada página
+const markerFormat = "=== Página %d ==="
+
+var markerRegex = regexp.MustCompile(`(?m)^=== Página (\d+) ===$`)
+
+// Marker devuelve el separador de la página n (empezando en 1)
+func Marker(n int) string {
+	return fmt.Sprintf(markerFormat, n)
+}
+
+// Join concatena el texto de las páginas en orden, precediendo cada una de su separador
+func Join(pages []string) string {
+	var text strings.Builder
+	for i, page := range pages {
+		text.WriteString(Marker(i + 1))
+		text.WriteString("\n")
+		text.WriteString(strings.TrimRight(page, "\n\f "))
+		text.WriteString("\n\n")
+	}
+	return text.String()
+}
+
+// Split separa un texto generado por Join en el texto de cada página
+func Split(text string) []string {
+	locs := markerRegex.FindAllStringIndex(text, -1)
+	if len(locs) == 0 {
+		return []string{text}
+	}
+
+	pages := make([]string, 0, len(locs))
+	for i, loc := range locs {
+		end := len(text)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		pages = append(pages, strings.TrimSpace(text[loc[1]:end]))
+	}
+	return pages
+}
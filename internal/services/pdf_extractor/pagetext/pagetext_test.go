@@ -0,0 +1,47 @@
+package pagetext
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestJoinSplitKeepsPageOrder(t *testing.T) {
+	for _, n := range []int{12, 120} {
+		t.Run(fmt.Sprintf("%d páginas", n), func(t *testing.T) {
+			pages := make([]string, n)
+			for i := range pages {
+				pages[i] = fmt.Sprintf("Líquido a percibir página %d\nTotal devengado", i+1)
+			}
+
+			text := Join(pages)
+
+			// Los separadores aparecen en orden numérico, no lexicográfico
+			last := -1
+			for i := 1; i <= n; i++ {
+				pos := strings.Index(text, Marker(i)+"\n")
+				if pos <= last {
+					t.Fatalf("separador de la página %d fuera de orden", i)
+				}
+				last = pos
+			}
+
+			split := Split(text)
+			if len(split) != n {
+				t.Fatalf("se esperaban %d páginas, hay %d", n, len(split))
+			}
+			for i := range pages {
+				if split[i] != pages[i] {
+					t.Errorf("página %d: %q, se esperaba %q", i+1, split[i], pages[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplitWithoutMarkers(t *testing.T) {
+	split := Split("texto sin separadores")
+	if len(split) != 1 || split[0] != "texto sin separadores" {
+		t.Errorf("resultado inesperado: %q", split)
+	}
+}
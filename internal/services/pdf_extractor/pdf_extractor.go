@@ -1,137 +1,323 @@
 package pdf_extractor
 
 import (
+	"context"
 	"fmt"
 	"github.com/unidoc/unipdf/v3/extractor"
 	"github.com/unidoc/unipdf/v3/model"
 	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/metrics"
+	"go_ocr/internal/services/pdf_extractor/doctype"
 	"go_ocr/internal/services/pdf_extractor/ocr"
+	"go_ocr/internal/services/pdf_extractor/pagetext"
+	"go_ocr/internal/services/pdf_extractor/table"
+	"go_ocr/internal/services/runner"
+	"go_ocr/internal/services/tracing"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 )
 
+// ReviewConfidence es la confianza media de OCR (0-100) por debajo de la cual un
+// documento o alguna de sus páginas requiere revisión manual
+const ReviewConfidence = 75
+
+// Result es el texto extraído junto con la estrategia elegida y su puntuación de calidad
+type Result struct {
+	Text     string  `json:"-"`
+	Strategy string  `json:"strategy"`
+	Score    float64 `json:"score"`
+	Pages    int     `json:"pages"`
+	OCRPages []int   `json:"ocr_pages,omitempty"` // Páginas reconocidas con OCR por no tener una capa de texto válida
+
+	TableRows int `json:"table_rows,omitempty"` // Filas de la tabla de conceptos reconstruidas por columnas
+
+	OCR           *ocr.Document `json:"ocr,omitempty"`                    // Confianza de las páginas reconocidas con OCR
+	LowConfidence []ocr.Region  `json:"low_confidence_regions,omitempty"` // Fragmentos con palabras dudosas
+	NeedsReview   bool          `json:"needs_review"`
+}
+
 var (
-	log = logger.NewLogger(false) // Logger compartido
+	// strategyChosen cuenta la estrategia del resultado devuelto; lleva "+ocr" si se
+	// reconocieron páginas sueltas con OCR
+	strategyChosen = metrics.NewCounter("go_ocr_extraction_strategy_total",
+		"Documentos extraídos por estrategia elegida.", "strategy")
+	// strategyAttempts cuenta cada estrategia probada: success, low_quality (no alcanzó
+	// MinQuality), error, timeout o canceled
+	strategyAttempts = metrics.NewCounter("go_ocr_extraction_attempts_total",
+		"Estrategias de extracción probadas por resultado.", "strategy", "outcome")
 )
 
-func ExtractTextFromPDF(path string) (string, error) {
+// Service extrae el texto de los documentos con las estrategias configuradas
+type Service struct {
+	runner     *runner.Runner
+	ocr        *ocr.Engine
+	extractors []Extractor // Orden en que se prueban, de la más barata a la más cara
+}
+
+// New crea un Service que ejecuta poppler con r y reconoce con engine. Sin licencia de
+// UniPDF (uniPDF false) se omite esa estrategia: su extracción de texto fallaría en todas
+// las páginas.
+func New(r *runner.Runner, engine *ocr.Engine, uniPDF bool) *Service {
+	s := &Service{runner: r, ocr: engine}
+	s.extractors = append(s.extractors, PdfToTextExtractor{Runner: r})
+	if uniPDF {
+		s.extractors = append(s.extractors, UniPDFExtractor{})
+	}
+	s.extractors = append(s.extractors, OCRExtractor{Engine: engine})
+	return s
+}
+
+// setOCR incorpora al resultado la confianza de las páginas reconocidas con OCR
+func (r *Result) setOCR(pages []Page) {
+	var recognized []ocr.Page
+	for _, page := range pages {
+		if page.OCR != nil {
+			recognized = append(recognized, *page.OCR)
+		}
+	}
+	if len(recognized) == 0 {
+		return
+	}
+
+	r.OCR = ocr.NewDocument(recognized)
+	r.LowConfidence = r.OCR.LowConfidenceRegions(ocr.LowWordConfidence)
+	r.NeedsReview = r.OCR.Confidence < ReviewConfidence
+	for _, page := range recognized {
+		if len(page.Words) > 0 && page.Confidence < ReviewConfidence {
+			r.NeedsReview = true
+		}
+	}
+}
+
+// ExtractTextFromPDF prueba las estrategias de extracción en orden y acepta la primera
+// cuyo texto supera MinQuality. Si ninguna lo supera devuelve la de mayor puntuación.
+// Las estrategias basadas en la capa de texto se evalúan página a página y solo las
+// páginas sin texto válido se reconocen con OCR.
+// Las imágenes no tienen capa de texto, así que solo se prueba OCR.
+// opts se valida antes de llamar, normalmente al leer la configuración o la petición.
+// Si ctx se cancela o vence se abandonan las estrategias pendientes y se devuelve su error.
+func (s *Service) ExtractTextFromPDF(ctx context.Context, path string, opts Options) (*Result, error) {
+	ctx, span := tracing.Start(ctx, "extract")
+	defer span.End()
+
+	startTime := time.Now()
+	result, err := s.extractText(ctx, path, opts, span)
+	span.RecordError(err)
+	metrics.ObserveStage("extract", startTime, err)
+	if err == nil {
+		strategyChosen.Inc(result.Strategy)
+	}
+	return result, err
+}
+
+// extractText hace la extracción de ExtractTextFromPDF y anota en span el formato y la
+// estrategia elegida
+func (s *Service) extractText(ctx context.Context, path string, opts Options, span *tracing.Span) (*Result, error) {
+	log := logger.FromContext(ctx)
 	startTime := time.Now()
 	log.Info("Iniciando extracción de texto de PDF: %s", path)
-	log.Debug("Parámetros de ExtractTextFromPDF - path: %s", path)
+	log.Debug("Parámetros de ExtractTextFromPDF - path: %s, opciones: %+v", path, opts)
 
-	text, err := extractWithPdfToText(path)
+	docType, err := doctype.Detect(path)
 	if err != nil {
-		log.Warning("Extracción con pdftotext fallida: %v", err)
-	} else {
-		log.Info("Extracción con pdftotext exitosa. Longitud del texto: %d", len(text))
-		log.Debug("Texto extraído (primeros 100 caracteres): %.100q", text)
-		log.Info("Proceso completado. Tiempo total: %v", time.Since(startTime))
-		return text, nil
+		log.Error("Error al detectar formato: %v", err)
+		return nil, fmt.Errorf("error al detectar formato: %v", err)
 	}
 
-	// Intentar extracción con UniPDF
-	text, err = extractWithUniPDF(path)
-	if err != nil {
-		log.Warning("Extracción con UniPDF fallida: %v", err)
-	} else if len(text) > 50 {
-		log.Info("Extracción con UniPDF exitosa. Longitud del texto: %d", len(text))
-		log.Debug("Texto extraído (primeros 100 caracteres): %.100q", text)
-		log.Info("Proceso completado. Tiempo total: %v", time.Since(startTime))
-		return text, nil
-	} else {
-		log.Warning("Texto extraído con UniPDF demasiado corto (%d caracteres), intentando con OCR", len(text))
-	}
-
-	// Fallback a OCR
-	log.Info("Intentando extracción con OCR...")
-	ocrText, err := ocr.ExtractWithOCR(path)
+	span.SetAttributes(tracing.String("document.type", string(docType)))
+
+	strategies := s.extractors
+	if docType.IsImage() {
+		log.Info("Documento de tipo imagen (%s), extrayendo con OCR", docType)
+		strategies = []Extractor{OCRExtractor{Engine: s.ocr}}
+	}
+
+	hybrid := newPageOCR(s.ocr, path, opts)
+	var best *Result
+	var lastErr error
+	for _, strategy := range strategies {
+		if err := ctx.Err(); err != nil {
+			log.Warning("Extracción interrumpida antes de %s: %v", strategy.Name(), err)
+			return nil, err
+		}
+
+		result, quality, err := runStrategy(ctx, strategy, path, opts, hybrid)
+		if err != nil {
+			strategyAttempts.Inc(strategy.Name(), metrics.Outcome(err))
+			lastErr = err
+			continue
+		}
+		if quality.Score < MinQuality {
+			strategyAttempts.Inc(strategy.Name(), "low_quality")
+		} else {
+			strategyAttempts.Inc(strategy.Name(), "success")
+		}
+
+		if best == nil || result.Score > best.Score {
+			best = result
+		}
+		if quality.Score >= MinQuality {
+			break
+		}
+		log.Warning("Calidad insuficiente con %s (%.2f < %.2f), probando siguiente estrategia",
+			result.Strategy, quality.Score, MinQuality)
+	}
+
+	if best == nil {
+		log.Error("Fallaron todas las estrategias de extracción: %v", lastErr)
+		return nil, fmt.Errorf("fallaron todos los métodos de extracción: %v", lastErr)
+	}
+
+	span.SetAttributes(tracing.String("strategy", best.Strategy), tracing.Int("pages", best.Pages),
+		tracing.Float64("score", best.Score))
+	log.With("stage", "extract", "strategy", best.Strategy, "pages", best.Pages, "duration_ms", time.Since(startTime).Milliseconds()).
+		Info("Proceso completado con %s (puntuación %.2f). Tiempo total: %v",
+			best.Strategy, best.Score, time.Since(startTime))
+	return best, nil
+}
+
+// runStrategy extrae el texto con una estrategia, reconoce con OCR las páginas sin texto
+// válido si la estrategia usa la capa de texto, y puntúa el resultado. Cada estrategia es
+// un span con las páginas, la longitud del texto y la puntuación.
+func runStrategy(ctx context.Context, strategy Extractor, path string, opts Options, hybrid *pageOCR) (*Result, Quality, error) {
+	log := logger.FromContext(ctx)
+	ctx, span := tracing.Start(ctx, "extract.strategy", tracing.String("strategy", strategy.Name()))
+	defer span.End()
+
+	pages, err := strategy.Extract(ctx, path, opts)
 	if err != nil {
-		log.Error("Extracción con OCR fallida: %v", err)
-		return "", fmt.Errorf("fallaron ambos métodos de extracción: %v", err)
+		log.Warning("Extracción con %s fallida: %v", strategy.Name(), err)
+		span.RecordError(err)
+		return nil, Quality{}, err
 	}
 
-	log.Info("Extracción con OCR exitosa. Longitud del texto: %d", len(ocrText))
-	log.Debug("Texto OCR extraído (primeros 100 caracteres): %.100q", ocrText)
-	log.Info("Proceso completado. Tiempo total: %v", time.Since(startTime))
+	result := &Result{Strategy: strategy.Name(), Pages: len(pages)}
+	if _, isOCR := strategy.(OCRExtractor); !isOCR {
+		result.OCRPages, err = hybrid.fill(ctx, pages)
+		if err != nil {
+			log.Warning("OCR de páginas sin texto fallido con %s: %v", strategy.Name(), err)
+			span.RecordError(err)
+			return nil, Quality{}, err
+		}
+		if len(result.OCRPages) > 0 {
+			result.Strategy += "+ocr"
+		}
+	}
+	texts := make([]string, len(pages))
+	for i, page := range pages {
+		texts[i] = page.Text
+		if opts.Layout {
+			var rows int
+			texts[i], rows = table.Reconstruct(page.Text)
+			result.TableRows += rows
+		}
+	}
+	result.Text = pagetext.Join(texts)
+	result.setOCR(pages)
 
-	return ocrText, nil
+	quality := ScoreText(result.Text, opts.languages())
+	result.Score = quality.Score
+	span.SetAttributes(tracing.Int("pages", len(pages)), tracing.Int("ocr_pages", len(result.OCRPages)),
+		tracing.Int("chars", len(result.Text)), tracing.Float64("score", quality.Score))
+	log.Info("Extracción con %s completada. Páginas: %d, longitud: %d, puntuación: %.2f (imprimibles %.2f, palabras %.2f, claves %d)",
+		result.Strategy, len(pages), len(result.Text), quality.Score, quality.PrintableRatio, quality.WordRatio, quality.Keywords)
+	if result.TableRows > 0 {
+		log.Debug("Filas de conceptos reconstruidas con %s: %d", result.Strategy, result.TableRows)
+	}
+	log.Trace("Texto extraído (primeros 100 caracteres): %.100q", result.Text)
+	return result, quality, nil
 }
 
-func extractWithPdfToText(path string) (string, error) {
-	log.Debug("Extrayendo texto de PDF con pdftotext: %s", path)
+func extractWithPdfToText(ctx context.Context, r *runner.Runner, path string, layout bool) ([]string, error) {
+	log := logger.FromContext(ctx)
+	log.Debug("Extrayendo texto de PDF con pdftotext: %s (layout: %t)", path, layout)
 
-	// Ejecutar pdftotext
-	cmd := exec.Command("pdftotext", path, "-")
-	log.Debug("Ejecutando comando: %v", cmd.Args)
+	// Ejecutar pdftotext; separa las páginas con un salto de página (\f)
+	args := []string{path, "-"}
+	if layout {
+		args = append([]string{"-layout"}, args...)
+	}
+	log.Debug("Ejecutando comando: pdftotext %v", args)
 
-	output, err := cmd.CombinedOutput()
+	output, stderr, err := r.Run(ctx, "pdftotext", args...)
 	if err != nil {
-		log.Error("Error al extraer texto con pdftotext: %v\nSalida: %s", err, string(output))
-		return "", fmt.Errorf("error al extraer texto: %v\nSalida: %s", err, string(output))
+		log.Error("Error al extraer texto con pdftotext: %v\nSalida: %s", err, stderr)
+		return nil, fmt.Errorf("error al extraer texto: %v\nSalida: %s", err, stderr)
+	}
+
+	pages := strings.Split(string(output), "\f")
+	if len(pages) > 1 && strings.TrimSpace(pages[len(pages)-1]) == "" {
+		pages = pages[:len(pages)-1]
 	}
 
-	log.Debug("Extracción con pdftotext completada. Longitud del texto: %d", len(output))
-	return string(output), nil
+	log.Debug("Extracción con pdftotext completada. Páginas: %d, longitud del texto: %d", len(pages), len(output))
+	return pages, nil
 }
 
-func extractWithUniPDF(path string) (string, error) {
+func extractWithUniPDF(ctx context.Context, path string) ([]string, error) {
+	log := logger.FromContext(ctx)
 	log.Debug("Abriendo PDF con UniPDF: %s", path)
 
 	// Abrir el archivo PDF
 	f, err := os.Open(path)
 	if err != nil {
 		log.Error("Error al abrir archivo: %v", err)
-		return "", fmt.Errorf("error al abrir archivo: %v", err)
+		return nil, fmt.Errorf("error al abrir archivo: %v", err)
 	}
 	defer f.Close()
 
 	pdfReader, err := model.NewPdfReader(f)
 	if err != nil {
 		log.Error("Error al crear PDF reader: %v", err)
-		return "", fmt.Errorf("error al crear PDF reader: %v", err)
+		return nil, fmt.Errorf("error al crear PDF reader: %v", err)
+	}
+	if err := decryptReader(pdfReader); err != nil {
+		log.Error("Error al descifrar PDF: %v", err)
+		return nil, err
 	}
 
-	var text strings.Builder
 	totalPages, err := pdfReader.GetNumPages()
 	if err != nil {
 		log.Error("Error al obtener número de páginas: %v", err)
-		return "", fmt.Errorf("error al obtener número de páginas: %v", err)
+		return nil, fmt.Errorf("error al obtener número de páginas: %v", err)
 	}
 	log.Info("Procesando PDF con %d páginas", totalPages)
 
 	// Procesar cada página
+	pages := make([]string, 0, totalPages)
+	totalLength := 0
 	for i := 1; i <= totalPages; i++ {
+		// UniPDF no admite cancelación; se comprueba entre páginas
+		if err := ctx.Err(); err != nil {
+			log.Warning("Extracción con UniPDF interrumpida en página %d: %v", i, err)
+			return nil, err
+		}
 		log.Debug("Extrayendo texto de página %d/%d", i, totalPages)
 
 		page, err := pdfReader.GetPage(i)
 		if err != nil {
 			log.Error("Error al obtener página %d: %v", i, err)
-			return "", fmt.Errorf("error en página %d: %v", i, err)
+			return nil, fmt.Errorf("error en página %d: %v", i, err)
 		}
 
 		ex, err := extractor.New(page)
 		if err != nil {
 			log.Error("Error al crear extractor para página %d: %v", i, err)
-			return "", fmt.Errorf("error en página %d: %v", i, err)
+			return nil, fmt.Errorf("error en página %d: %v", i, err)
 		}
 
 		content, err := ex.ExtractText()
 		if err != nil {
 			log.Error("Error al extraer texto de página %d: %v", i, err)
-			return "", fmt.Errorf("error en página %d: %v", i, err)
+			return nil, fmt.Errorf("error en página %d: %v", i, err)
 		}
 
-		text.WriteString(content + "\n")
-		log.Debug("Página %d procesada. Longitud acumulada: %d", i, text.Len())
-	}
-
-	if text.Len() == 0 {
-		log.Warning("No se encontró texto legible en el PDF")
-		return "", fmt.Errorf("no se encontró texto legible")
+		pages = append(pages, content)
+		totalLength += len(content)
+		log.Debug("Página %d procesada. Longitud acumulada: %d", i, totalLength)
 	}
 
-	log.Info("Extracción con UniPDF completada. Longitud total del texto: %d", text.Len())
-	return text.String(), nil
+	log.Info("Extracción con UniPDF completada. Longitud total del texto: %d", totalLength)
+	return pages, nil
 }
@@ -0,0 +1,98 @@
+package pdf_extractor
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MinQuality es la puntuación mínima para aceptar el texto de una estrategia
+const MinQuality = 0.6
+
+// minTextLength es la longitud por debajo de la cual el texto se considera vacío
+const minTextLength = 50
+
+// Quality resume la calidad de un texto extraído
+type Quality struct {
+	Score          float64 // Puntuación combinada entre 0 y 1
+	PrintableRatio float64 // Proporción de caracteres imprimibles
+	WordRatio      float64 // Proporción de palabras presentes en el diccionario
+	Keywords       int     // Número de términos de nómina distintos encontrados
+}
+
+// Pesos de cada métrica en la puntuación final
+const (
+	printableWeight = 0.4
+	wordWeight      = 0.3
+	keywordWeight   = 0.3
+
+	// Proporción de palabras de diccionario a partir de la cual se da la máxima puntuación.
+	// Las nóminas tienen muchos nombres propios, importes y abreviaturas.
+	expectedWordRatio = 0.3
+	// Número de términos de nómina a partir del cual se da la máxima puntuación
+	expectedKeywords = 3
+)
+
+// accentReplacer elimina tildes, diéresis y cedillas para comparar palabras
+var accentReplacer = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "é", "e", "è", "e", "ê", "e", "í", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ú", "u", "ü", "u", "ç", "c", "ñ", "n",
+)
+
+// ScoreText puntúa un texto según sus caracteres imprimibles, palabras reconocibles
+// y presencia de términos de nómina en alguno de los idiomas de languages (códigos de
+// Tesseract; sin ninguno soportado se usa el español). Un texto vacío o casi vacío puntúa 0.
+func ScoreText(text string, languages []string) Quality {
+	trimmed := strings.TrimSpace(text)
+	if len(trimmed) < minTextLength {
+		return Quality{}
+	}
+
+	var q Quality
+
+	// Caracteres imprimibles: el texto de fuentes mal codificadas genera controles y U+FFFD
+	total, printable := 0, 0
+	for _, r := range trimmed {
+		total++
+		if (unicode.IsPrint(r) || unicode.IsSpace(r)) && r != unicode.ReplacementChar && !unicode.Is(unicode.Co, r) {
+			printable++
+		}
+	}
+	q.PrintableRatio = float64(printable) / float64(total)
+
+	// Palabras de diccionario sobre el total de palabras alfabéticas
+	vocabularies := vocabulariesFor(languages)
+	normalized := accentReplacer.Replace(strings.ToLower(trimmed))
+	words, known := 0, 0
+	for _, token := range strings.FieldsFunc(normalized, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if len([]rune(token)) < 2 {
+			continue
+		}
+		words++
+		for _, v := range vocabularies {
+			if v.words[token] {
+				known++
+				break
+			}
+		}
+	}
+	if words > 0 {
+		q.WordRatio = float64(known) / float64(words)
+	}
+
+	// Los términos compartidos entre idiomas, como "empresa", cuentan una vez
+	found := make(map[string]bool)
+	for _, v := range vocabularies {
+		for _, keyword := range v.keywords {
+			if !found[keyword] && strings.Contains(normalized, keyword) {
+				found[keyword] = true
+				q.Keywords++
+			}
+		}
+	}
+
+	q.Score = printableWeight*q.PrintableRatio +
+		wordWeight*min(1, q.WordRatio/expectedWordRatio) +
+		keywordWeight*min(1, float64(q.Keywords)/expectedKeywords)
+
+	return q
+}
@@ -0,0 +1,127 @@
+package pdf_extractor
+
+import (
+	"strings"
+	"testing"
+)
+
+// Nóminas de ejemplo, como las extrae pdftotext, en cada idioma soportado
+var payslips = map[string]string{
+	"spa": `EMPRESA: Talleres Ejemplo S.L.   CIF: B12345678   Domicilio: Calle Mayor 1, Madrid
+TRABAJADOR: García López, Ana   Categoría: Oficial administrativa   Antigüedad: 01/03/2015
+Nº afiliación a la Seguridad Social: 28/12345678/90   Periodo de liquidación: 01/05/2024 a 31/05/2024
+I. DEVENGOS   Salario base 1.520,00   Plus convenio 120,00   Prorrata pagas extra 273,33
+A. TOTAL DEVENGADO 1.913,33
+II. DEDUCCIONES   Contingencias comunes 4,70% 89,93   Desempleo 1,55% 29,66   IRPF 12,00% 229,60
+B. TOTAL A DEDUCIR 349,19
+LÍQUIDO TOTAL A PERCIBIR (A-B) 1.564,14`,
+
+	"cat": `EMPRESA: Tallers Exemple S.L.   CIF: B12345678   Domicili: Carrer Major 1, Barcelona
+TREBALLADOR: Puig Soler, Marta   Categoria: Oficial administrativa   Antiguitat: 01/03/2015
+Núm. afiliació a la Seguretat Social: 08/12345678/90   Període de liquidació: 01/05/2024 a 31/05/2024
+I. MERITACIONS   Salari base 1.520,00   Plus conveni 120,00   Prorrata pagues extraordinàries 273,33
+A. TOTAL MERITAT 1.913,33
+II. DEDUCCIONS   Contingències comunes 4,70% 89,93   Desocupació 1,55% 29,66   IRPF 12,00% 229,60
+B. TOTAL A DEDUIR 349,19
+LÍQUID TOTAL A PERCEBRE (A-B) 1.564,14`,
+
+	"glg": `EMPRESA: Talleres Exemplo S.L.   CIF: B12345678   Enderezo: Rúa Maior 1, Vigo
+TRABALLADOR: Pereira Castro, Xoán   Categoría: Oficial administrativo   Antigüidade: 01/03/2015
+Núm. afiliación á Seguridade Social: 36/12345678/90   Período de liquidación: 01/05/2024 a 31/05/2024
+I. DEVENGOS   Salario base 1.520,00   Plus convenio 120,00   Prorrata pagas extraordinarias 273,33
+A. TOTAL DEVENGADO 1.913,33
+II. DEDUCIÓNS   Continxencias comúns 4,70% 89,93   Desemprego 1,55% 29,66   IRPF 12,00% 229,60
+B. TOTAL A DEDUCIR 349,19
+LÍQUIDO TOTAL A PERCIBIR (A-B) 1.564,14`,
+
+	"por": `RECIBO DE VENCIMENTO   Empresa: Oficinas Exemplo Lda.   NIPC: 501234567   Morada: Rua Direita 1, Porto
+Trabalhador: Sousa Ferreira, João   Categoria: Técnico administrativo   Antiguidade: 01/03/2015
+NIF: 123456789   Segurança Social: 12345678901   Período: 01/05/2024 a 31/05/2024
+REMUNERAÇÕES   Vencimento base 1.520,00   Subsídio de alimentação 132,00   Diuturnidades 60,00
+Total ilíquido 1.712,00
+DESCONTOS   Contribuição Segurança Social 11% 173,80   Retenção IRS 13,1% 207,00
+Total descontos 380,80
+LÍQUIDO A RECEBER 1.331,20   Pagamento por transferência bancária IBAN PT50 0002 0123 1234 5678 9015 4`,
+
+	"eus": `ENPRESA: Adibide Tailerrak S.L.   IFK: B12345678   Helbidea: Kale Nagusia 1, Bilbo
+LANGILEA: Etxeberria Agirre, Miren   Kategoria: Administrari ofiziala   Antzinatasuna: 2015/03/01
+Gizarte Segurantzako afiliazioa: 48/12345678/90   Likidazio aldia: 2024/05/01 - 2024/05/31
+I. SORTZAPENAK   Oinarrizko soldata 1.520,00   Hitzarmenaren plusa 120,00   Aparteko ordainsarien hainbanatzea 273,33
+A. SORTUTAKOA GUZTIRA 1.913,33
+II. KENKARIAK   Kontingentzia arruntak 4,70% 89,93   Langabezia 1,55% 29,66   PFEZ 12,00% 229,60
+B. KENTZEKOA GUZTIRA 349,19
+JASOTZEKO LIKIDOA GUZTIRA (A-B) 1.564,14`,
+}
+
+func TestScoreText(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		languages []string
+		min, max  float64
+	}{
+		{"nómina en español", payslips["spa"], []string{"spa", "eng"}, MinQuality, 1},
+		{"vacío", "", []string{"spa"}, 0, 0},
+		{"solo espacios", "   \n\t\n  ", []string{"spa"}, 0, 0},
+		{"demasiado corto", "Líquido a percibir 1.564,14", []string{"spa"}, 0, 0},
+		{"fuente mal codificada", strings.Repeat("�\x01\x02 ÿþ  ", 20), []string{"spa"}, 0, MinPageQuality},
+		{"letras sin sentido", strings.Repeat("xkq zvbw ptrf gnnh wqlz ", 10), []string{"spa"}, 0, MinPageQuality},
+		{"símbolos y cifras", strings.Repeat("#@ 12/34 ** ;;; 5.67 || ", 10), []string{"spa"}, 0, MinPageQuality},
+		{"sin idiomas usa el español", payslips["spa"], nil, MinQuality, 1},
+		{"idiomas sin vocabulario usan el español", payslips["spa"], []string{"eng", "fra"}, MinQuality, 1},
+		// Sin su idioma configurado, una nómina en euskera parecería basura
+		{"nómina en euskera solo con español", payslips["eus"], []string{"spa"}, 0, MinQuality - 0.01},
+	}
+	for lang, text := range payslips {
+		tests = append(tests, struct {
+			name      string
+			text      string
+			languages []string
+			min, max  float64
+		}{"nómina en " + lang, text, []string{lang}, MinQuality, 1})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := ScoreText(tt.text, tt.languages)
+			if q.Score < tt.min || q.Score > tt.max {
+				t.Errorf("puntuación %.2f (imprimibles %.2f, palabras %.2f, claves %d), se esperaba entre %.2f y %.2f",
+					q.Score, q.PrintableRatio, q.WordRatio, q.Keywords, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+// TestScoreTextFollowsLanguages comprueba que en una nómina en otro idioma se reconocen más
+// palabras y términos de nómina con su idioma configurado que solo con el español
+func TestScoreTextFollowsLanguages(t *testing.T) {
+	for _, lang := range []string{"cat", "glg", "por", "eus"} {
+		t.Run(lang, func(t *testing.T) {
+			spanish := ScoreText(payslips[lang], []string{"spa"})
+			configured := ScoreText(payslips[lang], []string{"spa", lang})
+			if configured.WordRatio <= spanish.WordRatio || configured.Keywords <= spanish.Keywords {
+				t.Errorf("palabras %.2f y %d claves configurando %s, %.2f y %d solo con spa",
+					configured.WordRatio, configured.Keywords, lang, spanish.WordRatio, spanish.Keywords)
+			}
+		})
+	}
+
+	// Un término compartido por varios idiomas cuenta una vez
+	text := strings.Repeat("empresa trabajador treballador ", 5)
+	if q := ScoreText(text, []string{"spa", "cat", "glg"}); q.Keywords != 3 {
+		t.Errorf("%d términos de nómina, se esperaban 3", q.Keywords)
+	}
+}
+
+func TestOptionsLanguages(t *testing.T) {
+	opts := DefaultOptions
+	opts.OCR.Languages = "spa+cat"
+	opts.OCR.Candidates = []string{"eus", "glg"}
+	if got := strings.Join(opts.languages(), "+"); got != "spa+cat" {
+		t.Errorf("idiomas %q sin detección automática, se esperaba spa+cat", got)
+	}
+	opts.OCR.AutoDetect = true
+	if got := strings.Join(opts.languages(), "+"); got != "spa+cat+eus+glg" {
+		t.Errorf("idiomas %q con detección automática, se esperaba spa+cat+eus+glg", got)
+	}
+}
@@ -0,0 +1,273 @@
+// Package signature verifica las firmas digitales (PAdES y PKCS#7) de un PDF: integridad
+// del contenido firmado, cadena de certificados contra un almacén de confianza y si el
+// documento se modificó después de firmarse.
+package signature
+
+import (
+	"context"
+	"fmt"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+	"github.com/unidoc/unipdf/v3/model/sighandler"
+	"go_ocr/internal/services/logger"
+	"go_ocr/internal/services/metrics"
+	"os"
+	"time"
+)
+
+// verified cuenta las firmas verificadas por estado
+var verified = metrics.NewCounter("go_ocr_signatures_total", "Firmas digitales verificadas por estado.", "status")
+
+// Estados de una firma, de peor a mejor
+const (
+	StatusInvalid   = "invalid"   // El contenido firmado no coincide con la firma
+	StatusUntrusted = "untrusted" // Íntegra, pero el certificado no encadena con el almacén de confianza
+	StatusUnknown   = "unknown"   // Íntegra, pero no hay almacén de confianza con el que verificarla
+	StatusModified  = "modified"  // Íntegra y de confianza, pero hay cambios posteriores a la firma
+	StatusValid     = "valid"
+)
+
+// Signature es el resultado de verificar una firma
+type Signature struct {
+	Field          string     `json:"field"`
+	Signer         string     `json:"signer"`         // Titular del certificado firmante
+	Name           string     `json:"name,omitempty"` // /Name declarado en la firma, sin verificar
+	Issuer         string     `json:"issuer,omitempty"`
+	SigningTime    *time.Time `json:"signing_time,omitempty"` // Solo de un sellado de tiempo verificado
+	ClaimedTime    *time.Time `json:"claimed_time,omitempty"` // /M declarado en la firma, sin verificar
+	Timestamped    bool       `json:"timestamped"`            // SigningTime procede de un sellado de tiempo (RFC 3161)
+	SubFilter      string     `json:"sub_filter,omitempty"`
+	Reason         string     `json:"reason,omitempty"`
+	Integrity      bool       `json:"integrity"`       // El contenido firmado no se ha alterado
+	Trusted        bool       `json:"trusted"`         // La cadena del certificado firmante llega al almacén de confianza
+	CoversDocument bool       `json:"covers_document"` // La firma abarca el archivo completo
+	Status         string     `json:"status"`
+	Errors         []string   `json:"errors,omitempty"`
+
+	data         *signedData
+	trustUnknown bool
+}
+
+// Report agrupa las firmas de un documento
+type Report struct {
+	Signed     bool        `json:"signed"`
+	Valid      bool        `json:"valid"` // Hay firmas y todas son válidas
+	Signatures []Signature `json:"signatures"`
+}
+
+// Verify valida las firmas del PDF. password es la contraseña que lo abre si está cifrado.
+// Si el documento no tiene firmas devuelve un informe con Signed a false.
+func Verify(ctx context.Context, path, password string, store *TrustStore) (*Report, error) {
+	log := logger.FromContext(ctx)
+	startTime := time.Now()
+	log.Debug("Verificando firmas de PDF: %s", path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir archivo: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir archivo: %v", err)
+	}
+	defer f.Close()
+
+	pdfReader, err := model.NewPdfReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear PDF reader: %v", err)
+	}
+	if encrypted, err := pdfReader.IsEncrypted(); err == nil && encrypted {
+		if ok, err := pdfReader.Decrypt([]byte(password)); err != nil || !ok {
+			return nil, fmt.Errorf("error al descifrar: %v", err)
+		}
+	}
+
+	fields := signatureFields(pdfReader)
+	report := &Report{Signatures: []Signature{}}
+	if len(fields) == 0 {
+		return report, nil
+	}
+	report.Signed = true
+
+	handlers, err := validationHandlers()
+	if err != nil {
+		return nil, err
+	}
+	results, err := pdfReader.ValidateSignatures(handlers)
+	if err != nil {
+		return nil, fmt.Errorf("error al validar firmas: %v", err)
+	}
+
+	report.Valid = true
+	for i, field := range fields {
+		sig := newSignature(field, info.Size())
+		if result := matchResult(results, field, i); result != nil {
+			sig.applyResult(result)
+		} else {
+			sig.Errors = append(sig.Errors, "sin resultado de validación")
+		}
+		sig.checkTrust(store)
+		sig.Status = sig.status()
+		verified.Inc(sig.Status)
+		report.Valid = report.Valid && sig.Status == StatusValid
+		report.Signatures = append(report.Signatures, sig)
+	}
+
+	log.With("stage", "signatures", "duration_ms", time.Since(startTime).Milliseconds()).
+		Info("Verificación de firmas completada. Firmas: %d, válidas: %t. Tiempo total: %v",
+			len(report.Signatures), report.Valid, time.Since(startTime))
+	return report, nil
+}
+
+// signatureFields devuelve los campos de firma que contienen una firma
+func signatureFields(pdfReader *model.PdfReader) []*model.PdfFieldSignature {
+	if pdfReader.AcroForm == nil {
+		return nil
+	}
+	var fields []*model.PdfFieldSignature
+	for _, field := range pdfReader.AcroForm.AllFields() {
+		if sigField, ok := field.GetContext().(*model.PdfFieldSignature); ok && sigField.V != nil {
+			fields = append(fields, sigField)
+		}
+	}
+	return fields
+}
+
+// validationHandlers son los validadores de los formatos de firma habituales:
+// adbe.x509.rsa_sha1, adbe.pkcs7.detached y ETSI.CAdES.detached (PAdES)
+func validationHandlers() ([]model.SignatureHandler, error) {
+	rsaSHA1, err := sighandler.NewAdobeX509RSASHA1(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear validador de firmas: %v", err)
+	}
+	pkcs7, err := sighandler.NewAdobePKCS7Detached(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear validador de firmas: %v", err)
+	}
+	pades, err := sighandler.NewEtsiPAdESLevelB(nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear validador de firmas: %v", err)
+	}
+	return []model.SignatureHandler{rsaSHA1, pkcs7, pades}, nil
+}
+
+// matchResult busca el resultado de validación del campo. UniPDF devuelve los resultados
+// en el orden de los campos; se usa el campo asociado si lo informa.
+func matchResult(results []model.SignatureValidationResult, field *model.PdfFieldSignature, i int) *model.SignatureValidationResult {
+	for j := range results {
+		for _, f := range results[j].Fields {
+			if f == field.PdfField {
+				return &results[j]
+			}
+		}
+	}
+	if i < len(results) {
+		return &results[i]
+	}
+	return nil
+}
+
+// newSignature lee de la firma los datos que no dependen de la validación de UniPDF:
+// firmante, alcance y certificados. El firmante es el titular del certificado del
+// SignerInfo; /Name lo escribe quien firma y se informa aparte.
+func newSignature(field *model.PdfFieldSignature, fileSize int64) Signature {
+	sig := Signature{}
+	if name, err := field.FullName(); err == nil {
+		sig.Field = name
+	}
+	dict := field.V
+	if dict.SubFilter != nil {
+		sig.SubFilter = dict.SubFilter.String()
+	}
+	if dict.Reason != nil {
+		sig.Reason = dict.Reason.Decoded()
+	}
+	if dict.Name != nil {
+		sig.Name = dict.Name.Decoded()
+	}
+	sig.CoversDocument = coversDocument(dict.ByteRange, fileSize)
+
+	data, err := readSignedData(dict)
+	if err != nil {
+		sig.Errors = append(sig.Errors, fmt.Sprintf("no se pudo leer el certificado firmante: %v", err))
+		return sig
+	}
+	sig.Signer = data.signer.Subject.CommonName
+	if sig.Signer == "" {
+		sig.Signer = data.signer.Subject.String()
+	}
+	sig.Issuer = data.signer.Issuer.CommonName
+	sig.data = data
+	return sig
+}
+
+// applyResult incorpora el resultado de UniPDF: integridad y hora declarada. La hora del
+// sellado que informa UniPDF no se usa porque no verifica la autoridad que lo emite.
+func (s *Signature) applyResult(result *model.SignatureValidationResult) {
+	s.Integrity = result.IsSigned && result.IsVerified
+	s.Errors = append(s.Errors, result.Errors...)
+	if s.Name == "" {
+		s.Name = result.Name
+	}
+	if t := result.Date.ToGoTime(); !t.IsZero() {
+		s.ClaimedTime = &t
+	}
+}
+
+// checkTrust verifica la cadena del firmante contra el almacén. Solo con un sellado de
+// tiempo verificado se comprueba a la hora sellada, así un certificado caducado después de
+// firmar sigue siendo válido; si no, a la hora actual.
+func (s *Signature) checkTrust(store *TrustStore) {
+	if store == nil {
+		s.trustUnknown = true
+		s.Errors = append(s.Errors, "sin almacén de confianza configurado")
+		return
+	}
+	if s.data == nil {
+		return
+	}
+
+	at := time.Now()
+	if s.data.timestamp != nil {
+		if stamped, err := store.verifyTimestamp(s.data.timestamp, s.data.value); err != nil {
+			s.Errors = append(s.Errors, fmt.Sprintf("sellado de tiempo no válido: %v", err))
+		} else {
+			s.SigningTime = &stamped
+			s.Timestamped = true
+			at = stamped
+		}
+	}
+	if err := store.verify(s.data.signer, s.data.intermediates, at); err != nil {
+		s.Errors = append(s.Errors, fmt.Sprintf("certificado no confiable: %v", err))
+		return
+	}
+	s.Trusted = true
+}
+
+// status resume la firma en uno de los estados Status*
+func (s Signature) status() string {
+	switch {
+	case !s.Integrity:
+		return StatusInvalid
+	case s.trustUnknown:
+		return StatusUnknown
+	case !s.Trusted:
+		return StatusUntrusted
+	case !s.CoversDocument:
+		return StatusModified
+	}
+	return StatusValid
+}
+
+// coversDocument indica si el ByteRange de la firma llega hasta el final del archivo.
+// Si no, se añadieron revisiones (actualizaciones incrementales) después de firmar.
+func coversDocument(byteRange *core.PdfObjectArray, fileSize int64) bool {
+	if byteRange == nil || byteRange.Len() != 4 {
+		return false
+	}
+	values, err := byteRange.ToInt64Slice()
+	if err != nil {
+		return false
+	}
+	return values[2]+values[3] == fileSize
+}
@@ -0,0 +1,288 @@
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unidoc/pkcs7"
+	"github.com/unidoc/timestamp"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// testCert es un certificado de prueba con su clave
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+var serial int64
+
+// newCert crea un certificado firmado por parent, o autofirmado si parent es nil
+func newCert(t *testing.T, name string, parent *testCert, isCA bool, usages []x509.ExtKeyUsage, unknown ...asn1.ObjectIdentifier) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial++
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-24 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           usages,
+		UnknownExtKeyUsage:    unknown,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	issuer, signer := template, key
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+func storeOf(certs ...*testCert) *TrustStore {
+	roots := x509.NewCertPool()
+	for _, c := range certs {
+		roots.AddCert(c.cert)
+	}
+	return &TrustStore{roots: roots}
+}
+
+// newToken sella value con la autoridad tsa a la hora at
+func newToken(t *testing.T, tsa *testCert, value []byte, at time.Time) []byte {
+	t.Helper()
+	digest := sha256.Sum256(value)
+	ts := timestamp.Timestamp{
+		HashAlgorithm:     crypto.SHA256,
+		HashedMessage:     digest[:],
+		Time:              at,
+		Policy:            asn1.ObjectIdentifier{1, 2, 3, 4},
+		AddTSACertificate: true,
+	}
+	resp, err := ts.CreateResponse(tsa.cert, tsa.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed struct {
+		Status asn1.RawValue
+		Token  asn1.RawValue
+	}
+	if _, err := asn1.Unmarshal(resp, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Token.FullBytes
+}
+
+// newPKCS7 firma con signer un PKCS#7 separado que incluye además extra. Con tsa se añade
+// un sellado de tiempo sobre la firma.
+func newPKCS7(t *testing.T, signer *testCert, extra []*testCert, tsa *testCert) []byte {
+	t.Helper()
+	sd, err := pkcs7.NewSignedData([]byte("contenido firmado"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Los certificados ajenos van primero para que no se elija al firmante por posición
+	for _, c := range extra {
+		sd.AddCertificate(c.cert)
+	}
+	if err := sd.AddSigner(signer.cert, signer.key, pkcs7.SignerInfoConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if tsa != nil {
+		err := sd.RequestSignerTimestampToken(0, func(value []byte) ([]byte, error) {
+			return newToken(t, tsa, value, time.Now().Add(-time.Hour)), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	sd.Detach()
+	der, err := sd.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// pdfSignature envuelve el PKCS#7 como en /Contents, con el relleno de ceros habitual
+func pdfSignature(der []byte) *model.PdfSignature {
+	padded := append(append([]byte{}, der...), make([]byte, 512)...)
+	return &model.PdfSignature{
+		SubFilter: core.MakeName("adbe.pkcs7.detached"),
+		Contents:  core.MakeHexString(string(padded)),
+	}
+}
+
+func TestReadSignedDataUsesSignerInfo(t *testing.T) {
+	root := newCert(t, "Raíz", nil, true, nil)
+	ca := newCert(t, "CA intermedia", root, true, nil)
+	signer := newCert(t, "Firmante real", ca, false, []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection})
+	// Otro certificado final de la misma CA que no emite a nadie: con la heurística de
+	// "el que no emite a ningún otro" podía pasar por firmante
+	decoy := newCert(t, "Certificado ajeno", ca, false, nil)
+
+	data, err := readSignedData(pdfSignature(newPKCS7(t, signer, []*testCert{decoy, ca}, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.signer.Equal(signer.cert) {
+		t.Fatalf("firmante %q, se esperaba %q", data.signer.Subject.CommonName, signer.cert.Subject.CommonName)
+	}
+	if data.timestamp != nil {
+		t.Errorf("token de tiempo inesperado")
+	}
+	// La CA incluida sirve de intermedia para llegar a la raíz
+	if err := storeOf(root).verify(data.signer, data.intermediates, time.Now()); err != nil {
+		t.Errorf("cadena no verificada: %v", err)
+	}
+}
+
+func TestVerifyExtKeyUsage(t *testing.T) {
+	root := newCert(t, "Raíz", nil, true, nil)
+	store := storeOf(root)
+	documentSigning := asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 36}
+
+	tests := []struct {
+		name    string
+		usages  []x509.ExtKeyUsage
+		unknown []asn1.ObjectIdentifier
+		ok      bool
+	}{
+		{"sin uso extendido", nil, nil, true},
+		{"protección de correo", []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection}, nil, true},
+		{"firma de documentos", nil, []asn1.ObjectIdentifier{documentSigning}, true},
+		{"cualquier uso", []x509.ExtKeyUsage{x509.ExtKeyUsageAny}, nil, true},
+		{"solo servidor web", []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, nil, false},
+		{"servidor y cliente web", []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}, nil, false},
+		{"solo sellado de tiempo", []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaf := newCert(t, tt.name, root, false, tt.usages, tt.unknown...)
+			err := store.verify(leaf.cert, x509.NewCertPool(), time.Now())
+			if (err == nil) != tt.ok {
+				t.Errorf("verify = %v, se esperaba válido %t", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestVerifyTimestamp(t *testing.T) {
+	root := newCert(t, "Raíz", nil, true, nil)
+	tsa := newCert(t, "TSA", root, false, []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping})
+	value := []byte("valor de la firma")
+	at := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	t.Run("válido", func(t *testing.T) {
+		stamped, err := storeOf(root).verifyTimestamp(newToken(t, tsa, value, at), value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stamped.Equal(at) {
+			t.Errorf("hora %v, se esperaba %v", stamped, at)
+		}
+	})
+	t.Run("sella otra firma", func(t *testing.T) {
+		if _, err := storeOf(root).verifyTimestamp(newToken(t, tsa, []byte("otra"), at), value); err == nil {
+			t.Error("se aceptó un token que sella otro valor")
+		}
+	})
+	t.Run("autoridad fuera del almacén", func(t *testing.T) {
+		other := newCert(t, "Otra raíz", nil, true, nil)
+		if _, err := storeOf(other).verifyTimestamp(newToken(t, tsa, value, at), value); err == nil {
+			t.Error("se aceptó una autoridad que no encadena con el almacén")
+		}
+	})
+	t.Run("autoridad sin uso de sellado", func(t *testing.T) {
+		notTSA := newCert(t, "No TSA", root, false, []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection})
+		if _, err := storeOf(root).verifyTimestamp(newToken(t, notTSA, value, at), value); err == nil {
+			t.Error("se aceptó una autoridad sin uso de sellado de tiempo")
+		}
+	})
+}
+
+func TestCheckTrust(t *testing.T) {
+	root := newCert(t, "Raíz", nil, true, nil)
+	signer := newCert(t, "Firmante", root, false, nil)
+	tsa := newCert(t, "TSA", root, false, []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping})
+	claimed := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newSig := func(t *testing.T, tsa *testCert) Signature {
+		data, err := readSignedData(pdfSignature(newPKCS7(t, signer, nil, tsa)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Signature{Integrity: true, CoversDocument: true, ClaimedTime: &claimed, data: data}
+	}
+
+	t.Run("sin almacén", func(t *testing.T) {
+		sig := newSig(t, nil)
+		sig.checkTrust(nil)
+		if sig.Trusted || sig.status() != StatusUnknown {
+			t.Errorf("confiable %t, estado %s; se esperaba desconocido", sig.Trusted, sig.status())
+		}
+	})
+	t.Run("sin sellado usa la hora actual", func(t *testing.T) {
+		sig := newSig(t, nil)
+		sig.checkTrust(storeOf(root))
+		if sig.SigningTime != nil || sig.Timestamped {
+			t.Errorf("hora de firma sin sellado: %v", sig.SigningTime)
+		}
+		// Con la /M de 2020 el certificado no sería válido; a la hora actual sí
+		if sig.status() != StatusValid {
+			t.Errorf("estado %s: %v", sig.status(), sig.Errors)
+		}
+	})
+	t.Run("sellado verificado", func(t *testing.T) {
+		sig := newSig(t, tsa)
+		sig.checkTrust(storeOf(root))
+		if !sig.Timestamped || sig.SigningTime == nil {
+			t.Fatalf("sellado no verificado: %v", sig.Errors)
+		}
+		if sig.status() != StatusValid {
+			t.Errorf("estado %s: %v", sig.status(), sig.Errors)
+		}
+	})
+	t.Run("sellado de autoridad no confiable", func(t *testing.T) {
+		other := newCert(t, "Otra raíz", nil, true, nil)
+		untrustedTSA := newCert(t, "TSA ajena", other, false, []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping})
+		sig := newSig(t, untrustedTSA)
+		sig.checkTrust(storeOf(root))
+		if sig.Timestamped || sig.SigningTime != nil {
+			t.Errorf("se usó la hora de un sellado no verificado")
+		}
+		if !strings.Contains(strings.Join(sig.Errors, "; "), "sellado de tiempo no válido") {
+			t.Errorf("falta el error del sellado: %v", sig.Errors)
+		}
+	})
+}
+
+func TestLoadTrustStoreWithoutPath(t *testing.T) {
+	store, err := LoadTrustStore(context.Background(), "")
+	if err != nil || store != nil {
+		t.Errorf("LoadTrustStore(\"\") = %v, %v; se esperaba nil sin error", store, err)
+	}
+}
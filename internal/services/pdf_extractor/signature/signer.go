@@ -0,0 +1,83 @@
+package signature
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"github.com/unidoc/pkcs7"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// signedData son los datos de /Contents con los que se decide la confianza de una firma
+type signedData struct {
+	signer        *x509.Certificate // Certificado que indica el SignerInfo (emisor y número de serie)
+	intermediates *x509.CertPool    // Resto de certificados incluidos, solo como intermedios
+	value         []byte            // Valor de la firma, que es lo que sella el token de tiempo
+	timestamp     []byte            // Token RFC 3161 de los atributos no firmados, sin verificar
+}
+
+// readSignedData obtiene el certificado firmante de la firma. En adbe.x509.rsa_sha1
+// /Contents es la firma PKCS#1 y el firmante es el primer certificado de /Cert; en el
+// resto es un PKCS#7 y el firmante es el que identifica su único SignerInfo.
+func readSignedData(dict *model.PdfSignature) (*signedData, error) {
+	if dict.Contents == nil {
+		return nil, errors.New("la firma no tiene /Contents")
+	}
+
+	if dict.SubFilter != nil && dict.SubFilter.String() == "adbe.x509.rsa_sha1" {
+		certs, err := dict.GetCerts()
+		if err != nil {
+			return nil, err
+		}
+		if len(certs) == 0 {
+			return nil, errors.New("la firma no incluye certificados")
+		}
+		return &signedData{signer: certs[0], intermediates: poolExcept(certs, certs[0])}, nil
+	}
+
+	p7, err := pkcs7.Parse(trimPadding(dict.Contents.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("PKCS#7 inválido: %v", err)
+	}
+	if len(p7.Signers) != 1 {
+		return nil, fmt.Errorf("se esperaba un firmante y hay %d", len(p7.Signers))
+	}
+	signer := p7.GetOnlySigner()
+	if signer == nil {
+		return nil, errors.New("el PKCS#7 no incluye el certificado del firmante")
+	}
+
+	data := &signedData{
+		signer:        signer,
+		intermediates: poolExcept(p7.Certificates, signer),
+		value:         p7.Signers[0].EncryptedDigest,
+	}
+	for _, attr := range p7.Signers[0].UnauthenticatedAttributes {
+		if attr.Type.Equal(pkcs7.OIDAttributeTimeStampToken) {
+			data.timestamp = attr.Value.Bytes
+		}
+	}
+	return data, nil
+}
+
+// trimPadding quita los ceros de relleno que siguen al PKCS#7 en /Contents. Si no es DER
+// (codificación BER de longitud indefinida) se devuelve tal cual.
+func trimPadding(contents []byte) []byte {
+	var raw asn1.RawValue
+	if _, err := asn1.Unmarshal(contents, &raw); err != nil {
+		return contents
+	}
+	return raw.FullBytes
+}
+
+// poolExcept devuelve un CertPool con los certificados de certs salvo exclude
+func poolExcept(certs []*x509.Certificate, exclude *x509.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		if !cert.Equal(exclude) {
+			pool.AddCert(cert)
+		}
+	}
+	return pool
+}
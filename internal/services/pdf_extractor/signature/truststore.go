@@ -0,0 +1,193 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/unidoc/pkcs7"
+	"github.com/unidoc/timestamp"
+	"go_ocr/internal/services/logger"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// documentSigningUsages son los usos extendidos de firma de documentos que Go no reconoce:
+// id-kp-documentSigning (RFC 9336), Adobe Authentic Documents Trust y Microsoft Document Signing
+var documentSigningUsages = []asn1.ObjectIdentifier{
+	{1, 3, 6, 1, 5, 5, 7, 3, 36},
+	{1, 2, 840, 113583, 1, 1, 5},
+	{1, 3, 6, 1, 4, 1, 311, 10, 3, 12},
+}
+
+// TrustStore es el conjunto de certificados raíz con los que se aceptan las firmas
+type TrustStore struct {
+	roots *x509.CertPool
+}
+
+// LoadTrustStore carga los certificados raíz de path, que puede ser un archivo PEM o DER
+// o un directorio con varios. Con path vacío devuelve nil: las raíces del sistema son de
+// servidores web, no de firma de documentos, así que sin almacén la confianza es desconocida.
+func LoadTrustStore(ctx context.Context, path string) (*TrustStore, error) {
+	log := logger.FromContext(ctx)
+	if path == "" {
+		log.Warning("Sin almacén de confianza: la confianza de las firmas digitales será desconocida")
+		return nil, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir almacén de confianza: %v", err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer almacén de confianza: %v", err)
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	roots := x509.NewCertPool()
+	count := 0
+	for _, file := range files {
+		certs, err := readCertificates(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range certs {
+			roots.AddCert(cert)
+		}
+		count += len(certs)
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("el almacén de confianza %s no contiene certificados", path)
+	}
+
+	log.Info("Almacén de confianza cargado: %d certificados de %s", count, path)
+	return &TrustStore{roots: roots}, nil
+}
+
+// readCertificates lee los certificados de un archivo PEM (uno o varios) o DER
+func readCertificates(ctx context.Context, file string) ([]*x509.Certificate, error) {
+	log := logger.FromContext(ctx)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer certificado %s: %v", file, err)
+	}
+
+	var certs []*x509.Certificate
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error al interpretar certificado %s: %v", file, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) > 0 {
+		return certs, nil
+	}
+
+	// Sin bloques PEM se intenta como DER; los archivos que no son certificados se ignoran
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return []*x509.Certificate{cert}, nil
+	}
+	log.Debug("Archivo sin certificados en el almacén de confianza: %s", file)
+	return nil, nil
+}
+
+// verify comprueba que leaf encadena con alguna raíz del almacén en la fecha at y que su
+// uso extendido permite firmar documentos
+func (t *TrustStore) verify(leaf *x509.Certificate, intermediates *x509.CertPool, at time.Time) error {
+	// Los usos de firma de documentos no son estándar en Go: la cadena se verifica con
+	// cualquier uso y el del firmante se comprueba aparte
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         t.roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return err
+	}
+	if !signsDocuments(leaf) {
+		return errors.New("el certificado no está autorizado para firmar documentos")
+	}
+	return nil
+}
+
+// signsDocuments indica si el uso extendido del certificado permite firmar documentos: sin
+// uso extendido, cualquier uso, protección de correo (S/MIME) o firma de documentos
+func signsDocuments(cert *x509.Certificate) bool {
+	if len(cert.ExtKeyUsage) == 0 && len(cert.UnknownExtKeyUsage) == 0 {
+		return true
+	}
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageAny || usage == x509.ExtKeyUsageEmailProtection {
+			return true
+		}
+	}
+	for _, usage := range cert.UnknownExtKeyUsage {
+		for _, allowed := range documentSigningUsages {
+			if usage.Equal(allowed) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verifyTimestamp comprueba el token RFC 3161 que sella value (el valor de una firma): que
+// la autoridad lo firmó, que sella ese valor y que el certificado de la autoridad encadena
+// con el almacén con uso de sellado de tiempo. Devuelve la hora sellada.
+func (t *TrustStore) verifyTimestamp(token, value []byte) (time.Time, error) {
+	// Parse verifica la firma del token con el certificado que incluye
+	ts, err := timestamp.Parse(token)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("token inválido: %v", err)
+	}
+	p7, err := pkcs7.Parse(token)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("token inválido: %v", err)
+	}
+	tsa := p7.GetOnlySigner()
+	if tsa == nil {
+		return time.Time{}, errors.New("el token no incluye el certificado de la autoridad")
+	}
+
+	if !ts.HashAlgorithm.Available() {
+		return time.Time{}, fmt.Errorf("algoritmo de resumen no soportado: %v", ts.HashAlgorithm)
+	}
+	h := ts.HashAlgorithm.New()
+	h.Write(value)
+	if !bytes.Equal(h.Sum(nil), ts.HashedMessage) {
+		return time.Time{}, errors.New("el token no sella esta firma")
+	}
+
+	if _, err := tsa.Verify(x509.VerifyOptions{
+		Roots:         t.roots,
+		Intermediates: poolExcept(p7.Certificates, tsa),
+		CurrentTime:   ts.Time,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}); err != nil {
+		return time.Time{}, fmt.Errorf("autoridad de sellado no confiable: %v", err)
+	}
+	return ts.Time, nil
+}
@@ -0,0 +1,329 @@
+// Package table reconstruye la tabla de conceptos de una nómina a partir de texto que
+// conserva la disposición en columnas (pdftotext -layout o el OCR colocado por posición),
+// para que el modelo reciba cada concepto con su columna explícita.
+package table
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Header es la cabecera de las filas reconstruidas
+const Header = "concept | units | price | earning | deduction"
+
+// Columnas de la tabla, en el orden de Header
+const (
+	concept = iota
+	units
+	price
+	earning
+	deduction
+	numColumns
+)
+
+// columnLabels son los rótulos de cada columna en las nóminas, en minúsculas y sin tildes
+var columnLabels = [numColumns][]string{
+	concept:   {"concepto", "conceptos", "descripcion"},
+	units:     {"cuantia", "cantidad", "unidades", "uds", "horas", "dias"},
+	price:     {"precio"},
+	earning:   {"devengos", "devengo", "percepciones"},
+	deduction: {"deducciones", "deduccion", "descuentos", "retenciones"},
+}
+
+// endPrefixes son los inicios de línea que cierran la tabla de conceptos
+var endPrefixes = []string{"total", "liquido", "importe liquido", "a deducir"}
+
+// maxBlankLines es el número de líneas en blanco seguidas que cierra la tabla
+const maxBlankLines = 2
+
+// column es la posición, en caracteres, del rótulo de una columna numérica
+type column struct {
+	field      int
+	start, end int
+}
+
+// cell es un fragmento de una línea separado del resto por varios espacios
+type cell struct {
+	text       string
+	start, end int
+}
+
+// Row es una fila de la tabla de conceptos
+type Row [numColumns]string
+
+func (r Row) String() string {
+	return strings.Join(r[:], " | ")
+}
+
+// Reconstruct sustituye en el texto de una página cada tabla de conceptos por sus filas en
+// formato Header. Devuelve el texto resultante y el número de filas reconstruidas; si no
+// encuentra ninguna cabecera devuelve el texto sin cambios.
+func Reconstruct(text string) (string, int) {
+	lines := strings.Split(text, "\n")
+	var out []string
+	total := 0
+
+	for i := 0; i < len(lines); i++ {
+		columns, ok := parseHeader(lines[i])
+		if !ok {
+			out = append(out, lines[i])
+			continue
+		}
+
+		rows, next := parseRows(lines[i+1:], columns)
+		out = append(out, Header)
+		for _, row := range rows {
+			out = append(out, row.String())
+		}
+		total += len(rows)
+		i += next
+	}
+
+	if total == 0 {
+		return text, 0
+	}
+	return strings.Join(out, "\n"), total
+}
+
+// parseHeader reconoce una línea de cabecera: debe tener rótulos de devengos, de
+// deducciones y de cuantía o precio. Devuelve las columnas numéricas ordenadas.
+func parseHeader(line string) ([]column, bool) {
+	normalized := normalize(line)
+	var columns []column
+	found := [numColumns]bool{}
+	for field := units; field < numColumns; field++ {
+		for _, label := range columnLabels[field] {
+			if start := indexWord(normalized, []rune(label)); start >= 0 {
+				columns = append(columns, column{field: field, start: start, end: start + len([]rune(label))})
+				found[field] = true
+				break
+			}
+		}
+	}
+	if !found[earning] || !found[deduction] || (!found[units] && !found[price]) {
+		return nil, false
+	}
+
+	// Ordenar por posición (inserción: como mucho cuatro columnas)
+	for i := 1; i < len(columns); i++ {
+		for j := i; j > 0 && columns[j].start < columns[j-1].start; j-- {
+			columns[j], columns[j-1] = columns[j-1], columns[j]
+		}
+	}
+	return columns, true
+}
+
+// parseRows lee las filas que siguen a la cabecera hasta una línea de totales o varias
+// líneas en blanco. Una línea sin importes es la continuación del concepto de la fila
+// anterior, o de la siguiente si aún no hay ninguna: los conceptos largos ocupan varias
+// líneas con los importes en la primera. Devuelve las filas y el número de líneas consumidas.
+func parseRows(lines []string, columns []column) ([]Row, int) {
+	var rows []Row
+	var pending string // Concepto de las líneas sin importes anteriores a la primera fila
+	consumed, blanks := 0, 0
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			blanks++
+			if blanks >= maxBlankLines {
+				break
+			}
+			continue
+		}
+		blanks = 0
+		if isEnd(trimmed) {
+			break
+		}
+		if _, ok := parseHeader(line); ok {
+			break
+		}
+
+		row := parseRow(line, columns)
+		// Las líneas en blanco entre filas se descartan; las finales se conservan
+		consumed = i + 1
+		switch {
+		case !row.hasAmounts() && len(rows) > 0:
+			last := &rows[len(rows)-1]
+			last[concept] = strings.TrimSpace(last[concept] + " " + row[concept])
+		case !row.hasAmounts():
+			pending = strings.TrimSpace(pending + " " + row[concept])
+		default:
+			row[concept] = strings.TrimSpace(pending + " " + row[concept])
+			pending = ""
+			rows = append(rows, row)
+		}
+	}
+	if pending != "" {
+		rows = append(rows, Row{concept: pending})
+	}
+	return rows, consumed
+}
+
+// hasAmounts indica si la fila tiene algún importe o cantidad
+func (r Row) hasAmounts() bool {
+	for _, value := range r[concept+1:] {
+		if value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRow asigna cada fragmento numérico de la línea a la columna cuyo rótulo queda más
+// cerca de su centro; el texto a la izquierda de la primera columna es el concepto
+func parseRow(line string, columns []column) Row {
+	var row Row
+	conceptEnd := boundary(columns, -1)
+	for _, c := range splitCells(line) {
+		center := (c.start + c.end) / 2
+		if !isNumeric(c.text) || center < conceptEnd {
+			row[concept] = strings.TrimSpace(row[concept] + " " + c.text)
+			continue
+		}
+		field := columns[len(columns)-1].field
+		for i := range columns {
+			if center < boundary(columns, i) {
+				field = columns[i].field
+				break
+			}
+		}
+		row[field] = strings.TrimSpace(row[field] + " " + c.text)
+	}
+	return row
+}
+
+// boundary devuelve el límite derecho de la columna i, a medio camino entre su rótulo y
+// el siguiente. Para i = -1 devuelve el límite izquierdo de la primera columna, dejando
+// a su izquierda el espacio del concepto con un margen de la mitad del rótulo.
+func boundary(columns []column, i int) int {
+	if i < 0 {
+		first := columns[0]
+		return first.start - (first.end-first.start)/2
+	}
+	if i == len(columns)-1 {
+		return int(^uint(0) >> 1)
+	}
+	return (center(columns[i]) + center(columns[i+1])) / 2
+}
+
+func center(c column) int {
+	return (c.start + c.end) / 2
+}
+
+// splitCells divide la línea en fragmentos separados por dos o más espacios. Un fragmento
+// formado solo por números separados por un espacio se divide en cada número.
+func splitCells(line string) []cell {
+	runes := []rune(strings.ReplaceAll(line, "\t", "  "))
+	var cells []cell
+	start, end := -1, -1
+	flush := func() {
+		if start >= 0 {
+			cells = append(cells, splitNumbers(runes[start:end], start)...)
+		}
+	}
+	for i, r := range runes {
+		if r == ' ' {
+			continue
+		}
+		// Un único espacio separa palabras del mismo fragmento
+		if start < 0 || i-end > 1 {
+			flush()
+			start = i
+		}
+		end = i + 1
+	}
+	flush()
+	return cells
+}
+
+// splitNumbers divide un fragmento en palabras si todas son números
+func splitNumbers(runes []rune, offset int) []cell {
+	text := string(runes)
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return []cell{{text: text, start: offset, end: offset + len(runes)}}
+	}
+	for _, field := range fields {
+		if !isNumeric(field) {
+			return []cell{{text: text, start: offset, end: offset + len(runes)}}
+		}
+	}
+
+	cells := make([]cell, 0, len(fields))
+	pos := 0
+	for _, field := range fields {
+		length := len([]rune(field))
+		for runes[pos] == ' ' {
+			pos++
+		}
+		cells = append(cells, cell{text: field, start: offset + pos, end: offset + pos + length})
+		pos += length
+	}
+	return cells
+}
+
+// isNumeric indica si el fragmento es una cantidad: dígitos con separadores y, como mucho,
+// un signo, un porcentaje o el símbolo del euro
+func isNumeric(s string) bool {
+	digits := 0
+	for _, r := range s {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+		case strings.ContainsRune(".,-+%€ ", r):
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
+
+// isEnd indica si la línea cierra la tabla de conceptos
+func isEnd(line string) bool {
+	normalized := string(normalize(line))
+	for _, prefix := range endPrefixes {
+		if strings.HasPrefix(normalized, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize pasa la línea a minúsculas sin tildes conservando una runa por carácter,
+// para que las posiciones coincidan con las de la línea original
+func normalize(line string) []rune {
+	runes := []rune(line)
+	for i, r := range runes {
+		r = unicode.ToLower(r)
+		switch r {
+		case 'á', 'à':
+			r = 'a'
+		case 'é', 'è':
+			r = 'e'
+		case 'í', 'ï':
+			r = 'i'
+		case 'ó', 'ò':
+			r = 'o'
+		case 'ú', 'ü':
+			r = 'u'
+		}
+		runes[i] = r
+	}
+	return runes
+}
+
+// indexWord devuelve la posición de word como palabra completa en line, o -1
+func indexWord(line, word []rune) int {
+	for i := 0; i+len(word) <= len(line); i++ {
+		if i > 0 && unicode.IsLetter(line[i-1]) {
+			continue
+		}
+		if end := i + len(word); end < len(line) && unicode.IsLetter(line[end]) {
+			continue
+		}
+		if string(line[i:i+len(word)]) == string(word) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,138 @@
+package table
+
+import (
+	"go_ocr/internal/services/pdf_extractor/ocr"
+	"strings"
+	"testing"
+)
+
+// placed es una palabra en la posición horizontal x, en píxeles
+type placed struct {
+	text string
+	x    int
+}
+
+// Columnas de las nóminas de prueba, en píxeles, con caracteres de 10 píxeles
+const (
+	xConcept   = 0
+	xUnits     = 300
+	xPrice     = 400
+	xEarning   = 500
+	xDeduction = 650
+)
+
+// header es la cabecera de la tabla de conceptos de las nóminas de prueba
+var header = []placed{{"CONCEPTO", xConcept}, {"CUANTÍA", xUnits}, {"PRECIO", xPrice}, {"DEVENGOS", xEarning}, {"DEDUCCIONES", xDeduction}}
+
+// layout coloca las palabras de cada línea con cajas fijas, una línea cada 40 píxeles, y
+// devuelve el texto con disposición que produce el OCR
+func layout(lines ...[]placed) string {
+	var words []ocr.Word
+	for i, line := range lines {
+		for _, word := range line {
+			words = append(words, ocr.Word{Text: word.text, BBox: ocr.BBox{
+				Left: word.x, Top: 40 * i, Width: 10 * len([]rune(word.text)), Height: 20,
+			}})
+		}
+	}
+	return ocr.Page{Words: words}.LayoutText()
+}
+
+func TestReconstruct(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines [][]placed
+		want  []string
+	}{
+		{
+			name: "columnas alineadas",
+			lines: [][]placed{
+				header,
+				{{"Salario", xConcept}, {"base", 80}, {"30,00", xUnits}, {"50,00", xPrice}, {"1.500,00", xEarning}},
+				{{"Plus", xConcept}, {"convenio", 50}, {"30,00", xUnits}, {"5,00", xPrice}, {"150,00", xEarning}},
+				{{"IRPF", xConcept}, {"12,00", xUnits}, {"198,00", xDeduction}},
+				{{"TOTAL", xConcept}, {"1.650,00", xEarning}, {"198,00", xDeduction}},
+			},
+			want: []string{
+				"Salario base | 30,00 | 50,00 | 1.500,00 | ",
+				"Plus convenio | 30,00 | 5,00 | 150,00 | ",
+				"IRPF | 12,00 |  |  | 198,00",
+			},
+		},
+		{
+			name: "celda vacía",
+			lines: [][]placed{
+				header,
+				// Sin cuantía ni precio: el devengo no se desplaza a la primera columna
+				{{"Mejora", xConcept}, {"voluntaria", 70}, {"200,00", xEarning}},
+				// Sin precio entre cuantía y devengo
+				{{"Horas", xConcept}, {"extra", 60}, {"4", xUnits}, {"80,00", xEarning}},
+				// Solo deducción, con el devengo vacío
+				{{"Anticipo", xConcept}, {"300,00", xDeduction}},
+			},
+			want: []string{
+				"Mejora voluntaria |  |  | 200,00 | ",
+				"Horas extra | 4 |  | 80,00 | ",
+				"Anticipo |  |  |  | 300,00",
+			},
+		},
+		{
+			name: "celda de varias líneas",
+			lines: [][]placed{
+				header,
+				// El concepto continúa antes de que haya ninguna fila con importes
+				{{"Indemnización", xConcept}, {"por", 140}},
+				{{"traslado", xConcept}, {"1,00", xUnits}, {"90,00", xPrice}, {"90,00", xEarning}},
+				// El concepto continúa en la línea siguiente a sus importes
+				{{"Complemento", xConcept}, {"de", 120}, {"150,00", xEarning}},
+				{{"productividad", xConcept}},
+				{{"Cotización", xConcept}, {"contingencias", 110}, {"4,70", xUnits}, {"77,55", xDeduction}},
+				{{"comunes", xConcept}},
+				{{"TOTAL", xConcept}, {"240,00", xEarning}, {"77,55", xDeduction}},
+			},
+			want: []string{
+				"Indemnización por traslado | 1,00 | 90,00 | 90,00 | ",
+				"Complemento de productividad |  |  | 150,00 | ",
+				"Cotización contingencias comunes | 4,70 |  |  | 77,55",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := layout(tt.lines...)
+			got, rows := Reconstruct(text)
+			if rows != len(tt.want) {
+				t.Errorf("%d filas, se esperaban %d", rows, len(tt.want))
+			}
+			want := Header + "\n" + strings.Join(tt.want, "\n")
+			if !strings.HasPrefix(got, want+"\n") {
+				t.Errorf("texto de entrada:\n%s\nresultado:\n%s\nse esperaba que empezara por:\n%s", text, got, want)
+			}
+		})
+	}
+}
+
+func TestReconstructKeepsSurroundingText(t *testing.T) {
+	text := layout(
+		[]placed{{"Empresa", xConcept}, {"Ejemplo", 80}, {"S.L.", 160}},
+		header,
+		[]placed{{"Salario", xConcept}, {"base", 80}, {"30,00", xUnits}, {"50,00", xPrice}, {"1.500,00", xEarning}},
+		[]placed{{"LÍQUIDO", xConcept}, {"A", 80}, {"PERCIBIR", 100}, {"1.302,00", xEarning}},
+	)
+	got, rows := Reconstruct(text)
+	if rows != 1 {
+		t.Fatalf("%d filas, se esperaba 1", rows)
+	}
+	lines := strings.Split(got, "\n")
+	if lines[0] != "Empresa Ejemplo S.L." || lines[1] != Header || !strings.HasPrefix(lines[3], "LÍQUIDO A PERCIBIR") {
+		t.Errorf("texto alrededor de la tabla alterado:\n%s", got)
+	}
+}
+
+func TestReconstructWithoutHeader(t *testing.T) {
+	text := layout([]placed{{"Salario", xConcept}, {"base", 80}, {"1.500,00", xEarning}})
+	got, rows := Reconstruct(text)
+	if rows != 0 || got != text {
+		t.Errorf("sin cabecera se esperaba el texto sin cambios, hay %d filas:\n%s", rows, got)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package runner
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup no hace nada fuera de unix: la cancelación mata solo el proceso principal
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package runner
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup arranca el comando en su propio grupo de procesos y hace que la
+// cancelación mate el grupo entero, no solo el proceso principal
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// El identificador del grupo coincide con el PID de su líder
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
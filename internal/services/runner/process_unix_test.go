@@ -0,0 +1,48 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunKillsProcessGroupOnDeadline(t *testing.T) {
+	r, _ := newTestRunner(t, Limits{})
+	marker := filepath.Join(t.TempDir(), "vivo")
+
+	// sh lanza un sleep en segundo plano que, si sobrevive, crea marker al despertar. El
+	// sleep hereda la salida estándar: si no se matara con el grupo, Run esperaría a que la
+	// cerrara hasta waitDelay.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := r.Run(ctx, "sh", "-c", "(sleep 1 && touch "+marker+") & wait")
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error %v, se esperaba context.DeadlineExceeded", err)
+	}
+	if elapsed > waitDelay/2 {
+		t.Errorf("Run tardó %v en volver: el sleep mantuvo abierta la salida", elapsed)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("el sleep hijo de sh sobrevivió al plazo")
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	r, _ := newTestRunner(t, Limits{})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	if _, _, err := r.Run(ctx, "sleep", "30"); !errors.Is(err, context.Canceled) {
+		t.Errorf("error %v, se esperaba context.Canceled", err)
+	}
+}
@@ -0,0 +1,67 @@
+// Package runner lanza los comandos externos (poppler, tesseract) ligados a un contexto,
+// de forma que la cancelación de la petición o el fin de su plazo terminan el proceso
+// y todos sus descendientes. Los comandos se ejecutan aislados según la configuración
+// de sandbox (límites de recursos, entorno restringido y lanzador opcional).
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// waitDelay es lo que se espera a que se cierren la salida estándar y de errores tras
+// matar el proceso, por si algún descendiente las mantuviera abiertas
+const waitDelay = 5 * time.Second
+
+// Run ejecuta el comando en un directorio de trabajo temporal propio y devuelve su salida
+// estándar y de errores. El comando se mata, junto con su grupo de procesos, cuando ctx se
+// cancela o vence y cuando su salida supera el máximo configurado (ErrOutputLimit).
+// Las rutas de los argumentos deben ser absolutas, porque el comando no se ejecuta en el
+// directorio actual.
+func (r *Runner) Run(ctx context.Context, name string, args ...string) ([]byte, string, error) {
+	cfg := r.config
+
+	workDir, err := os.MkdirTemp(cfg.TempDir, "run_")
+	if err != nil {
+		return nil, "", fmt.Errorf("error al crear directorio de trabajo: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	argv := append(append([]string{}, cfg.Launcher...), name)
+	argv = append(argv, args...)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	if cmd.Err != nil {
+		return nil, "", cmd.Err
+	}
+	cmd.Dir = workDir
+	cmd.Env = cfg.environment(workDir)
+	cmd.WaitDelay = waitDelay
+	setProcessGroup(cmd)
+	applyLimits(cmd, cfg.Limits)
+
+	stdout := &limitedBuffer{limit: cfg.MaxOutput}
+	stderr := &limitedBuffer{limit: maxStderr, truncate: true}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+	switch {
+	case stdout.exceeded:
+		err = fmt.Errorf("%w: más de %d bytes", ErrOutputLimit, cfg.MaxOutput)
+	case err != nil:
+		err = Err(ctx, err)
+	}
+	return stdout.Bytes(), stderr.String(), err
+}
+
+// Err devuelve el error del contexto si el comando terminó por cancelación o plazo vencido,
+// más útil que el "signal: killed" del proceso. En otro caso devuelve err.
+func Err(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestRunner crea un Runner sin límites de recursos que hereda PATH y crea los
+// directorios de trabajo en un directorio temporal del test
+func newTestRunner(t *testing.T, limits Limits) (*Runner, string) {
+	t.Helper()
+	dir := t.TempDir()
+	r, _ := New(Config{Limits: limits, Env: []string{"PATH"}, TempDir: dir})
+	return r, dir
+}
+
+func TestRun(t *testing.T) {
+	r, dir := newTestRunner(t, Limits{})
+
+	stdout, stderr, err := r.Run(context.Background(), "sh", "-c", "echo salida; echo aviso >&2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(stdout) != "salida\n" || stderr != "aviso\n" {
+		t.Errorf("salida %q y errores %q", stdout, stderr)
+	}
+
+	_, stderr, err = r.Run(context.Background(), "sh", "-c", "echo fallo >&2; exit 3")
+	var exitErr interface{ ExitCode() int }
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 || stderr != "fallo\n" {
+		t.Errorf("error %v con salida de errores %q, se esperaba el código 3", err, stderr)
+	}
+
+	// El directorio de trabajo de cada comando se borra al terminar
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("quedaron directorios de trabajo: %v", entries)
+	}
+}
+
+func TestErr(t *testing.T) {
+	killed := errors.New("signal: killed")
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithTimeout(context.Background(), 0)
+	defer cancelExpired()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want error
+	}{
+		{"contexto activo", context.Background(), killed},
+		{"contexto cancelado", canceled, context.Canceled},
+		{"plazo vencido", expired, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Err(tt.ctx, killed); !errors.Is(err, tt.want) {
+				t.Errorf("Err = %v, se esperaba %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunMissingCommand(t *testing.T) {
+	r, _ := newTestRunner(t, Limits{})
+	if _, _, err := r.Run(context.Background(), filepath.Join(t.TempDir(), "no_existe")); err == nil {
+		t.Error("se esperaba un error con un comando inexistente")
+	}
+}
@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"time"
+)
+
+// ErrOutputLimit indica que un comando superó el tamaño máximo de salida
+var ErrOutputLimit = errors.New("salida del comando demasiado grande")
+
+// maxStderr es lo que se conserva de la salida de errores; el resto se descarta
+const maxStderr = 64 << 10
+
+// Limits son los límites de recursos de cada comando. Los valores 0 no limitan.
+type Limits struct {
+	CPUTime   time.Duration // Tiempo de CPU (RLIMIT_CPU)
+	Memory    int64         // Espacio de direcciones en bytes (RLIMIT_AS)
+	MaxOutput int64         // Salida estándar y tamaño de cada archivo escrito (RLIMIT_FSIZE)
+}
+
+// Config es la configuración del aislamiento de los comandos externos
+type Config struct {
+	Limits
+	Env      []string // Variables de entorno que se heredan; el resto no llega al comando
+	TempDir  string   // Directorio donde se crea el directorio de trabajo de cada comando
+	Launcher []string // Comando que envuelve a cada ejecución, p. ej. bwrap con sus opciones
+}
+
+// DefaultConfig es la configuración usada si no se configura otra
+var DefaultConfig = Config{
+	Limits: Limits{
+		CPUTime:   2 * time.Minute,
+		Memory:    2 << 30,
+		MaxOutput: 128 << 20,
+	},
+	Env: []string{"PATH", "LANG", "LC_ALL", "TESSDATA_PREFIX", "OMP_THREAD_LIMIT"},
+}
+
+// Runner ejecuta los comandos externos con la configuración de aislamiento con la que se creó
+type Runner struct {
+	config Config
+}
+
+// New crea un Runner con la configuración de aislamiento cfg. Devuelve también un error si
+// hay límites de recursos pero no se pueden aplicar (plataforma no soportada o Init no
+// llamado al inicio de main); el Runner es válido igualmente y ejecuta los comandos sin
+// esos límites.
+func New(cfg Config) (*Runner, error) {
+	r := &Runner{config: cfg}
+	if cfg.CPUTime > 0 || cfg.Memory > 0 || cfg.MaxOutput > 0 {
+		if err := limitsSupported(); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// environment construye el entorno restringido del comando: solo las variables permitidas
+// y HOME/TMPDIR apuntando a su directorio de trabajo
+func (c Config) environment(workDir string) []string {
+	env := make([]string, 0, len(c.Env)+2)
+	for _, name := range c.Env {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return append(env, "HOME="+workDir, "TMPDIR="+workDir)
+}
+
+// limitedBuffer acumula la salida de un comando hasta limit bytes (0 sin límite).
+// Al superarlo descarta el resto si truncate es true, o falla la escritura para que
+// el comando termine. No incrusta bytes.Buffer para que io.Copy no use su ReadFrom,
+// que se saltaría el límite.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int64
+	truncate bool
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.limit <= 0 || int64(b.buf.Len()+len(p)) <= b.limit {
+		return b.buf.Write(p)
+	}
+	b.exceeded = true
+	if b.truncate {
+		b.buf.Write(p[:max(b.limit-int64(b.buf.Len()), 0)])
+		return len(p), nil
+	}
+	return 0, ErrOutputLimit
+}
+
+func (b *limitedBuffer) Bytes() []byte { return b.buf.Bytes() }
+
+func (b *limitedBuffer) String() string { return b.buf.String() }
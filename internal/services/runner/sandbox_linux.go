@@ -0,0 +1,98 @@
+//go:build linux
+
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// helperEnv indica al binario relanzado que debe aplicar los límites y ejecutar el comando
+const helperEnv = "GO_OCR_SANDBOX_LIMITS"
+
+// prSetNoNewPrivs es PR_SET_NO_NEW_PRIVS de prctl(2)
+const prSetNoNewPrivs = 38
+
+// self es la ruta del ejecutable actual, fijada por Init
+var self string
+
+// Init debe llamarse al principio de main. Si el proceso es el ayudante relanzado por Run,
+// aplica los límites de recursos y no_new_privs y se sustituye por el comando (no vuelve).
+// En otro caso registra el ejecutable para poder relanzarlo y vuelve.
+//
+// Go no permite fijar rlimits solo para un proceso hijo, así que Run relanza el propio
+// binario, que los aplica sobre sí mismo y hace exec del comando, que los hereda.
+func Init() {
+	spec, ok := os.LookupEnv(helperEnv)
+	if !ok {
+		if path, err := os.Executable(); err == nil {
+			self = path
+		}
+		return
+	}
+
+	os.Unsetenv(helperEnv)
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "sandbox: falta el comando")
+		os.Exit(126)
+	}
+	if err := restrictSelf(spec); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		os.Exit(126)
+	}
+	err := syscall.Exec(os.Args[1], os.Args[1:], os.Environ())
+	fmt.Fprintf(os.Stderr, "sandbox: error al ejecutar %s: %v\n", os.Args[1], err)
+	os.Exit(127)
+}
+
+// limitsSupported comprueba que Init registró el ejecutable para relanzarlo
+func limitsSupported() error {
+	if self == "" {
+		return fmt.Errorf("runner.Init no se llamó al arrancar; los límites de recursos no se aplicarán")
+	}
+	return nil
+}
+
+// applyLimits sustituye el comando por el propio binario en modo ayudante, que aplica los
+// límites antes de ejecutarlo. Sin Init se ejecuta el comando sin límites.
+func applyLimits(cmd *exec.Cmd, limits Limits) {
+	if self == "" {
+		return
+	}
+	spec := fmt.Sprintf("%d:%d:%d", int64(limits.CPUTime.Seconds()), limits.Memory, limits.MaxOutput)
+	cmd.Args = append([]string{self, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = self
+	cmd.Env = append(cmd.Env, helperEnv+"="+spec)
+}
+
+// restrictSelf aplica al proceso actual los límites codificados por applyLimits
+// y activa no_new_privs, de forma que ni el comando ni sus hijos puedan ganar privilegios
+func restrictSelf(spec string) error {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 3 {
+		return fmt.Errorf("límites inválidos: %q", spec)
+	}
+	resources := []int{syscall.RLIMIT_CPU, syscall.RLIMIT_AS, syscall.RLIMIT_FSIZE}
+	for i, part := range parts {
+		value, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return fmt.Errorf("límites inválidos: %q", spec)
+		}
+		if value == 0 {
+			continue
+		}
+		limit := &syscall.Rlimit{Cur: value, Max: value}
+		if err := syscall.Setrlimit(resources[i], limit); err != nil {
+			return fmt.Errorf("error al fijar límite de recursos: %v", err)
+		}
+	}
+
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return fmt.Errorf("error al activar no_new_privs: %v", errno)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+//go:build linux
+
+package runner
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain hace del binario de tests el ayudante que aplica los límites, como main con Init
+func TestMain(m *testing.M) {
+	Init()
+	os.Exit(m.Run())
+}
+
+// procLimit devuelve los límites blando y duro de la línea name de /proc/<pid>/limits
+func procLimit(t *testing.T, limits, name string) (string, string) {
+	t.Helper()
+	for _, line := range strings.Split(limits, "\n") {
+		if rest, ok := strings.CutPrefix(line, name); ok {
+			fields := strings.Fields(rest)
+			if len(fields) >= 2 {
+				return fields[0], fields[1]
+			}
+		}
+	}
+	t.Fatalf("no se encontró %q en:\n%s", name, limits)
+	return "", ""
+}
+
+func TestRunAppliesLimits(t *testing.T) {
+	r, err := New(Config{
+		Limits: Limits{CPUTime: time.Minute, Memory: 512 << 20, MaxOutput: 1 << 20},
+		Env:    []string{"PATH"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// cat es el propio comando: lee los límites con los que se ejecuta
+	limits, _, err := r.Run(context.Background(), "cat", "/proc/self/limits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, want string
+	}{
+		{"Max address space", "536870912"},
+		{"Max cpu time", "60"},
+		{"Max file size", "1048576"},
+	}
+	for _, tt := range tests {
+		if soft, hard := procLimit(t, string(limits), tt.name); soft != tt.want || hard != tt.want {
+			t.Errorf("%s: %s/%s, se esperaba %s", tt.name, soft, hard, tt.want)
+		}
+	}
+
+	status, _, err := r.Run(context.Background(), "cat", "/proc/self/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(status), "NoNewPrivs:\t1") {
+		t.Error("el comando se ejecutó sin no_new_privs")
+	}
+}
+
+func TestRunMemoryLimit(t *testing.T) {
+	r, err := New(Config{Limits: Limits{Memory: 64 << 20}, Env: []string{"PATH"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reservar 256 MB con el espacio de direcciones limitado a 64 MB falla
+	_, _, err = r.Run(context.Background(), "sh", "-c", `x=$(head -c 268435456 /dev/zero | tr '\0' a); echo ${#x}`)
+	if err == nil {
+		t.Error("el comando superó el límite de memoria sin fallar")
+	}
+}
+
+func TestNewReportsLimitsWithoutInit(t *testing.T) {
+	saved := self
+	t.Cleanup(func() { self = saved })
+	self = ""
+
+	if _, err := New(Config{Limits: Limits{Memory: 64 << 20}}); err == nil {
+		t.Error("se esperaba un error con límites y sin Init")
+	}
+	if _, err := New(Config{}); err != nil {
+		t.Errorf("sin límites no se esperaba error: %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package runner
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Init debe llamarse al principio de main. Fuera de Linux no hace nada.
+func Init() {}
+
+// limitsSupported informa de que los límites de recursos solo se aplican en Linux
+func limitsSupported() error {
+	return fmt.Errorf("los límites de recursos solo se aplican en Linux")
+}
+
+// applyLimits no hace nada fuera de Linux: el comando se ejecuta sin límites
+func applyLimits(cmd *exec.Cmd, limits Limits) {}
@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLimitedBuffer(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int64
+		truncate bool
+		writes   []string
+		want     string
+		exceeded bool
+		err      error // Error de la última escritura
+	}{
+		{"sin límite", 0, false, []string{"abcdef", "ghij"}, "abcdefghij", false, nil},
+		{"justo en el límite", 10, false, []string{"abcdef", "ghij"}, "abcdefghij", false, nil},
+		{"supera el límite", 8, false, []string{"abcdef", "ghij"}, "abcdef", true, ErrOutputLimit},
+		{"trunca al límite", 8, true, []string{"abcdef", "ghij"}, "abcdefgh", true, nil},
+		{"trunca tras el límite", 4, true, []string{"abcdef", "ghij"}, "abcd", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &limitedBuffer{limit: tt.limit, truncate: tt.truncate}
+			var err error
+			for _, w := range tt.writes {
+				var n int
+				n, err = b.Write([]byte(w))
+				// Si trunca, la escritura se da por buena para que el comando siga
+				if err == nil && n != len(w) {
+					t.Errorf("Write(%q) = %d, se esperaba %d", w, n, len(w))
+				}
+			}
+			if b.String() != tt.want || b.exceeded != tt.exceeded || !errors.Is(err, tt.err) {
+				t.Errorf("contenido %q, superado %t, error %v; se esperaba %q, %t, %v",
+					b.String(), b.exceeded, err, tt.want, tt.exceeded, tt.err)
+			}
+		})
+	}
+}
+
+func TestRunOutputLimit(t *testing.T) {
+	r, _ := newTestRunner(t, Limits{MaxOutput: 1000})
+
+	stdout, _, err := r.Run(context.Background(), "sh", "-c", "yes | head -c 100000")
+	if !errors.Is(err, ErrOutputLimit) {
+		t.Fatalf("error %v, se esperaba ErrOutputLimit", err)
+	}
+	if len(stdout) > 1000 {
+		t.Errorf("se conservaron %d bytes de salida, el máximo es 1000", len(stdout))
+	}
+
+	// La salida de errores se trunca sin que el comando falle
+	_, stderr, err := r.Run(context.Background(), "sh", "-c", "yes | head -c 100000 >&2")
+	if err != nil || len(stderr) != maxStderr {
+		t.Errorf("error %v con %d bytes de errores, se esperaban %d", err, len(stderr), maxStderr)
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin:/bin")
+	t.Setenv("LANG", "es_ES.UTF-8")
+	t.Setenv("DEEPSEEK_API_KEY", "secreto")
+	cfg := Config{Env: []string{"PATH", "LANG", "NO_DEFINIDA"}}
+
+	got := cfg.environment("/tmp/run_1")
+	want := []string{"PATH=/usr/bin:/bin", "LANG=es_ES.UTF-8", "HOME=/tmp/run_1", "TMPDIR=/tmp/run_1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entorno %q, se esperaba %q", got, want)
+	}
+}
+
+func TestRunEnvironment(t *testing.T) {
+	t.Setenv("DEEPSEEK_API_KEY", "secreto")
+	r, dir := newTestRunner(t, Limits{})
+
+	stdout, _, err := r.Run(context.Background(), "sh", "-c", "env; echo PWD=$(pwd -P)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(stdout)), "\n") {
+		name, value, _ := strings.Cut(line, "=")
+		env[name] = value
+	}
+
+	if _, ok := env["DEEPSEEK_API_KEY"]; ok {
+		t.Error("el comando recibió una variable que no está permitida")
+	}
+	// HOME y TMPDIR son el directorio de trabajo propio del comando, dentro de TempDir
+	home, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(env["PWD"]) != home || !strings.HasPrefix(filepath.Base(env["PWD"]), "run_") {
+		t.Errorf("directorio de trabajo %q, se esperaba un run_* dentro de %s", env["PWD"], home)
+	}
+	if env["HOME"] != env["TMPDIR"] || filepath.Base(env["HOME"]) != filepath.Base(env["PWD"]) {
+		t.Errorf("HOME %q y TMPDIR %q, se esperaba el directorio de trabajo %q", env["HOME"], env["TMPDIR"], env["PWD"])
+	}
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryExporter guarda en memoria los últimos spans exportados. Sirve para probar la
+// instrumentación y para consultar las trazas en local sin un colector.
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+	limit int
+}
+
+// NewMemoryExporter crea un exportador que conserva los últimos limit spans (sin límite si es <= 0)
+func NewMemoryExporter(limit int) *MemoryExporter {
+	return &MemoryExporter{limit: limit}
+}
+
+// Export guarda una copia de los spans, descartando los más antiguos si se supera el límite
+func (e *MemoryExporter) Export(_ context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	if e.limit > 0 && len(e.spans) > e.limit {
+		e.spans = append([]SpanData(nil), e.spans[len(e.spans)-e.limit:]...)
+	}
+	return nil
+}
+
+// Shutdown no hace nada; los spans siguen disponibles
+func (e *MemoryExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+// Spans devuelve una copia de los spans guardados, del más antiguo al más reciente
+func (e *MemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Trace devuelve los spans guardados de una traza
+func (e *MemoryExporter) Trace(id TraceID) []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var spans []SpanData
+	for _, span := range e.spans {
+		if span.TraceID == id {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// Reset descarta los spans guardados
+func (e *MemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
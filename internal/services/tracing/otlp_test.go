@@ -0,0 +1,151 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "reescribe los archivos de referencia de testdata")
+
+const goldenRequest = "testdata/otlp_request.json"
+
+// testSpans devuelve una traza fija: la petición HTTP con una llamada al modelo fallida
+func testSpans(t *testing.T) []SpanData {
+	t.Helper()
+	traceID, err := ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	return []SpanData{
+		{
+			TraceID:  traceID,
+			SpanID:   SpanID{0x53, 0x99, 0x5c, 0x3f, 0x42, 0xcd, 0x8a, 0xd8},
+			ParentID: root,
+			Name:     "llm.request",
+			Kind:     KindClient,
+			Start:    start.Add(2 * time.Second),
+			End:      start.Add(3500 * time.Millisecond),
+			Attributes: []Attribute{
+				String("llm.model", "deepseek-reasoner"),
+				Int("llm.attempt", 2),
+				Float64("score", 0.875),
+				Bool("cached", false),
+				{Key: "duration", Value: 1500 * time.Millisecond},
+			},
+			Status:        StatusError,
+			StatusMessage: "el colector respondió 503",
+		},
+		{
+			TraceID:    traceID,
+			SpanID:     root,
+			Name:       "POST /convert",
+			Kind:       KindServer,
+			Start:      start,
+			End:        start.Add(4 * time.Second),
+			Attributes: []Attribute{String("http.method", "POST"), Int64("http.status_code", 200)},
+			Status:     StatusUnset,
+		},
+	}
+}
+
+func TestOTLPRequestGolden(t *testing.T) {
+	exporter, err := NewOTLPExporter("http://localhost:4318", nil, "go_ocr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.MarshalIndent(exporter.request(testSpans(t)), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(goldenRequest), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(goldenRequest, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(goldenRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("petición OTLP distinta de %s (go test -run OTLPRequestGolden -update para regenerarla):\n%s", goldenRequest, got)
+	}
+}
+
+func TestOTLPExporterExport(t *testing.T) {
+	type received struct {
+		path, contentType, auth string
+		body                    []byte
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Authorization"), body}
+	}))
+	defer server.Close()
+
+	exporter, err := NewOTLPExporter(server.URL+"/", map[string]string{"Authorization": "Bearer abc"}, "go_ocr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Export(context.Background(), testSpans(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-requests
+	if req.path != "/v1/traces" || req.contentType != "application/json" || req.auth != "Bearer abc" {
+		t.Errorf("petición a %s con Content-Type %q y Authorization %q", req.path, req.contentType, req.auth)
+	}
+	golden, err := os.ReadFile(goldenRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	if err := json.Compact(&want, golden); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(req.body, want.Bytes()) {
+		t.Errorf("cuerpo enviado distinto de %s:\n%s", goldenRequest, req.body)
+	}
+}
+
+func TestOTLPExporterErrors(t *testing.T) {
+	if _, err := NewOTLPExporter("localhost:4318", nil, "go_ocr"); err == nil {
+		t.Error("se esperaba un error con un endpoint sin esquema")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "cuota superada", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	exporter, err := NewOTLPExporter(server.URL+"/v1/traces", nil, "go_ocr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exporter.Export(context.Background(), testSpans(t))
+	if err == nil || !strings.Contains(err.Error(), "cuota superada") {
+		t.Errorf("error %v, se esperaba el estado y el mensaje del colector", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := exporter.Export(ctx, testSpans(t)); err == nil {
+		t.Error("se esperaba un error con el contexto cancelado")
+	}
+}
@@ -0,0 +1,192 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Exporter envía los spans terminados a su destino. Export no debe conservar el slice
+// después de volver: se reutiliza para el siguiente lote.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// Config es la configuración del envío de spans
+type Config struct {
+	Exporter      Exporter      // nil desactiva el envío
+	BatchSize     int           // Spans por envío
+	QueueSize     int           // Spans en espera; los que no caben se descartan
+	FlushInterval time.Duration // Tiempo máximo que un span espera a enviarse
+	ExportTimeout time.Duration // Plazo de cada envío
+}
+
+// DefaultConfig son los valores por defecto del envío, sin exportador
+var DefaultConfig = Config{
+	BatchSize:     256,
+	QueueSize:     4096,
+	FlushInterval: 5 * time.Second,
+	ExportTimeout: 10 * time.Second,
+}
+
+// current es el procesador de spans activo, o nil si no hay exportador
+var current atomic.Pointer[processor]
+
+// processor agrupa los spans terminados y los envía en segundo plano
+type processor struct {
+	cfg     Config
+	queue   chan SpanData
+	flush   chan chan struct{}
+	done    chan struct{} // Se cierra para detener el envío
+	stopped chan struct{} // Se cierra cuando run ha enviado lo pendiente
+	once    sync.Once
+	dropped atomic.Int64
+}
+
+// Configure establece el exportador de los spans. Los valores <= 0 de cfg usan los de
+// DefaultConfig. El exportador anterior, si lo hay, se vacía y se cierra.
+func Configure(cfg Config) error {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultConfig.BatchSize
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultConfig.QueueSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultConfig.FlushInterval
+	}
+	if cfg.ExportTimeout <= 0 {
+		cfg.ExportTimeout = DefaultConfig.ExportTimeout
+	}
+
+	var p *processor
+	if cfg.Exporter != nil {
+		p = &processor{
+			cfg:     cfg,
+			queue:   make(chan SpanData, cfg.QueueSize),
+			flush:   make(chan chan struct{}),
+			done:    make(chan struct{}),
+			stopped: make(chan struct{}),
+		}
+		go p.run()
+	}
+	if previous := current.Swap(p); previous != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ExportTimeout)
+		defer cancel()
+		return previous.shutdown(ctx)
+	}
+	return nil
+}
+
+// Flush envía los spans pendientes y espera a que terminen o a que venza ctx
+func Flush(ctx context.Context) error {
+	p := current.Load()
+	if p == nil {
+		return nil
+	}
+	ack := make(chan struct{})
+	select {
+	case p.flush <- ack:
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown envía los spans pendientes y cierra el exportador. Los spans posteriores se descartan.
+func Shutdown(ctx context.Context) error {
+	if p := current.Swap(nil); p != nil {
+		return p.shutdown(ctx)
+	}
+	return nil
+}
+
+// enqueue encola un span terminado sin bloquear; si la cola está llena se descarta
+func (p *processor) enqueue(span SpanData) {
+	select {
+	case p.queue <- span:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+// run envía un lote cuando se llena, cuando vence el intervalo o cuando se pide con Flush
+func (p *processor) run() {
+	defer close(p.stopped)
+	ticker := time.NewTicker(p.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, p.cfg.BatchSize)
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= p.cfg.BatchSize {
+				batch = p.export(batch)
+			}
+		case <-ticker.C:
+			batch = p.export(batch)
+		case ack := <-p.flush:
+			batch = p.drain(batch)
+			close(ack)
+		case <-p.done:
+			p.drain(batch)
+			return
+		}
+	}
+}
+
+// drain envía el lote y todo lo que quede en la cola
+func (p *processor) drain(batch []SpanData) []SpanData {
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= p.cfg.BatchSize {
+				batch = p.export(batch)
+			}
+		default:
+			return p.export(batch)
+		}
+	}
+}
+
+// export envía el lote y devuelve el slice vacío para reutilizarlo. Los errores se
+// escriben en stderr: el registro de la aplicación lleva los identificadores de traza y
+// no debe depender del exportador.
+func (p *processor) export(batch []SpanData) []SpanData {
+	if dropped := p.dropped.Swap(0); dropped > 0 {
+		fmt.Fprintf(os.Stderr, "Cola de trazas llena, %d spans descartados\n", dropped)
+	}
+	if len(batch) == 0 {
+		return batch
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.ExportTimeout)
+	defer cancel()
+	if err := p.cfg.Exporter.Export(ctx, batch); err != nil {
+		fmt.Fprintf(os.Stderr, "Error al exportar %d spans: %v\n", len(batch), err)
+	}
+	return batch[:0]
+}
+
+// shutdown detiene el envío tras vaciar la cola y cierra el exportador
+func (p *processor) shutdown(ctx context.Context) error {
+	p.once.Do(func() { close(p.done) })
+	select {
+	case <-p.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return p.cfg.Exporter.Shutdown(ctx)
+}
@@ -0,0 +1,288 @@
+// Package tracing mide con spans las etapas de cada petición (descarga, estrategias de
+// extracción, páginas de OCR, llamadas al modelo) siguiendo el modelo de OpenTelemetry:
+// cada span pertenece a una traza, tiene un padre y lleva atributos. Los spans terminados
+// se envían en lotes al exportador configurado (OTLP o memoria); sin exportador se crean
+// igualmente para que el registro lleve el identificador de la traza.
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"go_ocr/internal/services/logger"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// TraceID identifica una traza: todos los spans de una petición
+type TraceID [16]byte
+
+// SpanID identifica un span dentro de su traza
+type SpanID [8]byte
+
+// String devuelve el identificador en hexadecimal
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// IsValid indica si el identificador no es todo ceros
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// String devuelve el identificador en hexadecimal
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// IsValid indica si el identificador no es todo ceros
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// MarshalText serializa el identificador en hexadecimal, también en JSON
+func (t TraceID) MarshalText() ([]byte, error) { return []byte(t.String()), nil }
+
+// MarshalText serializa el identificador en hexadecimal, también en JSON
+func (s SpanID) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+// Kind es el papel del span en la comunicación, como en OTLP
+type Kind int
+
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+// Status es el resultado del span, como en OTLP
+type Status int
+
+const (
+	StatusUnset Status = 0
+	StatusOK    Status = 1
+	StatusError Status = 2
+)
+
+// Attribute es un atributo clave-valor de un span. Value es string, int64, float64 o bool.
+type Attribute struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// String crea un atributo de texto
+func String(key, value string) Attribute { return Attribute{key, value} }
+
+// Int crea un atributo entero
+func Int(key string, value int) Attribute { return Attribute{key, int64(value)} }
+
+// Int64 crea un atributo entero
+func Int64(key string, value int64) Attribute { return Attribute{key, value} }
+
+// Float64 crea un atributo decimal
+func Float64(key string, value float64) Attribute { return Attribute{key, value} }
+
+// Bool crea un atributo booleano
+func Bool(key string, value bool) Attribute { return Attribute{key, value} }
+
+// SpanData es la copia de un span terminado que reciben los exportadores
+type SpanData struct {
+	TraceID       TraceID     `json:"trace_id"`
+	SpanID        SpanID      `json:"span_id"`
+	ParentID      SpanID      `json:"parent_id"`
+	Name          string      `json:"name"`
+	Kind          Kind        `json:"kind"`
+	Start         time.Time   `json:"start"`
+	End           time.Time   `json:"end"`
+	Attributes    []Attribute `json:"attributes,omitempty"`
+	Status        Status      `json:"status"`
+	StatusMessage string      `json:"status_message,omitempty"`
+}
+
+// Span es una operación medida. Sus métodos admiten un Span nil, así que el código
+// instrumentado no necesita comprobar si hay trazas.
+type Span struct {
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// spanKey es la clave del span actual en el contexto
+type spanKey struct{}
+
+// remoteKey es la clave del padre recibido de otro servicio en el contexto
+type remoteKey struct{}
+
+// remoteParent es un span de otro servicio, leído de la cabecera traceparent
+type remoteParent struct {
+	traceID TraceID
+	spanID  SpanID
+}
+
+// Start crea un span hijo del span de ctx (o del padre remoto, o uno raíz de una traza
+// nueva) y devuelve un contexto que lo lleva. Hay que terminarlo con End.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	span := &Span{data: SpanData{
+		Name:       name,
+		Kind:       KindInternal,
+		Start:      time.Now(),
+		SpanID:     newSpanID(),
+		Attributes: append([]Attribute(nil), attrs...),
+	}}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.data.TraceID = parent.data.TraceID
+		span.data.ParentID = parent.data.SpanID
+	} else if remote, ok := ctx.Value(remoteKey{}).(remoteParent); ok {
+		span.data.TraceID = remote.traceID
+		span.data.ParentID = remote.spanID
+	} else {
+		span.data.TraceID = newTraceID()
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanFromContext devuelve el span actual de ctx, o nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithTraceparent hace que el siguiente span raíz de ctx continúe la traza de la
+// cabecera W3C traceparent de otro servicio. Si la cabecera no es válida devuelve ctx.
+func ContextWithTraceparent(ctx context.Context, header string) context.Context {
+	traceID, spanID, ok := ParseTraceparent(header)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, remoteParent{traceID, spanID})
+}
+
+// ParseTraceID interpreta un identificador de traza en hexadecimal (32 caracteres)
+func ParseTraceID(s string) (TraceID, error) {
+	var id TraceID
+	if len(s) != 2*len(id) {
+		return id, fmt.Errorf("identificador de traza inválido: %q", s)
+	}
+	if _, err := hex.Decode(id[:], []byte(s)); err != nil || !id.IsValid() {
+		return id, fmt.Errorf("identificador de traza inválido: %q", s)
+	}
+	return id, nil
+}
+
+// ParseTraceparent interpreta una cabecera traceparent: 00-<traza>-<span>-<opciones>
+func ParseTraceparent(header string) (TraceID, SpanID, bool) {
+	var spanID SpanID
+	if len(header) != 55 || header[:3] != "00-" || header[35] != '-' || header[52] != '-' {
+		return TraceID{}, spanID, false
+	}
+	traceID, err := ParseTraceID(header[3:35])
+	if err != nil {
+		return traceID, spanID, false
+	}
+	if _, err := hex.Decode(spanID[:], []byte(header[36:52])); err != nil {
+		return traceID, spanID, false
+	}
+	return traceID, spanID, spanID.IsValid()
+}
+
+// Traceparent devuelve la cabecera traceparent que propaga el span a otro servicio
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", s.data.TraceID, s.data.SpanID)
+}
+
+// TraceID devuelve el identificador de la traza del span
+func (s *Span) TraceID() TraceID {
+	if s == nil {
+		return TraceID{}
+	}
+	return s.data.TraceID
+}
+
+// SpanID devuelve el identificador del span
+func (s *Span) SpanID() SpanID {
+	if s == nil {
+		return SpanID{}
+	}
+	return s.data.SpanID
+}
+
+// SetKind cambia el papel del span (por defecto KindInternal)
+func (s *Span) SetKind(kind Kind) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Kind = kind
+}
+
+// SetAttributes añade atributos al span; los de una clave repetida sustituyen a los anteriores
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, attr := range attrs {
+		replaced := false
+		for i := range s.data.Attributes {
+			if s.data.Attributes[i].Key == attr.Key {
+				s.data.Attributes[i] = attr
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.data.Attributes = append(s.data.Attributes, attr)
+		}
+	}
+}
+
+// RecordError marca el span como fallido con el mensaje de err, con los datos personales
+// enmascarados como en el registro. Con err nil no hace nada.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = StatusError
+	s.data.StatusMessage = logger.Redact(err.Error())
+}
+
+// End termina el span y lo entrega al exportador. Las llamadas siguientes no hacen nada.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	data.Attributes = append([]Attribute(nil), s.data.Attributes...)
+	s.mu.Unlock()
+
+	if p := current.Load(); p != nil {
+		p.enqueue(data)
+	}
+}
+
+// newTraceID genera un identificador de traza aleatorio distinto de cero
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+	}
+	return id
+}
+
+// newSpanID genera un identificador de span aleatorio distinto de cero
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+	return id
+}